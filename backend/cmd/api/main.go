@@ -1,13 +1,20 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -18,10 +25,20 @@ import (
 	"github.com/Cloud-2025-2/anb-platform/internal/config"
 	"github.com/Cloud-2025-2/anb-platform/internal/db"
 	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/events"
+	"github.com/Cloud-2025-2/anb-platform/internal/finalists"
+	"github.com/Cloud-2025-2/anb-platform/internal/fraud"
+	"github.com/Cloud-2025-2/anb-platform/internal/health"
 	"github.com/Cloud-2025-2/anb-platform/internal/httpapi"
 	"github.com/Cloud-2025-2/anb-platform/internal/kafka"
+	"github.com/Cloud-2025-2/anb-platform/internal/logging"
+	"github.com/Cloud-2025-2/anb-platform/internal/metrics"
+	"github.com/Cloud-2025-2/anb-platform/internal/moderation"
+	"github.com/Cloud-2025-2/anb-platform/internal/queue"
+	"github.com/Cloud-2025-2/anb-platform/internal/ranking"
 	"github.com/Cloud-2025-2/anb-platform/internal/repo"
 	"github.com/Cloud-2025-2/anb-platform/internal/storage"
+	"github.com/Cloud-2025-2/anb-platform/internal/tracing"
 	videosvc "github.com/Cloud-2025-2/anb-platform/internal/video"
 )
 
@@ -50,49 +67,128 @@ func main() {
 	_ = godotenv.Load()
 
 	cfg := config.Load()
+	if err := logging.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		logging.Fatal("Invalid logging configuration", "error", err)
+	}
+
+	// Cancelled on SIGINT/SIGTERM; stops the background jobs and starts the drain
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Tracing: spans from gin, GORM, Redis and Kafka share one trace per request
+	shutdownTracing, err := tracing.Init(context.Background(), "anb-api", cfg.TracingExporter)
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
 
 	// DB
-	db.Connect()
-	_ = db.DB.Exec(`CREATE EXTENSION IF NOT EXISTS "pgcrypto";`).Error
-	if err := db.DB.AutoMigrate(&domain.User{}, &domain.Video{}, &domain.Vote{}); err != nil {
-		log.Fatal(err)
+	db.Connect(cfg)
+	// El esquema lo aplica `migrate up`; aquí solo se verifica su versión
+	if err := db.RequireSchema(); err != nil {
+		logging.Fatal("Database schema check failed", "error", err)
 	}
 
 	// repos
-	usersRepo := repo.NewUserRepo(db.DB)
-	videosRepo := repo.NewVideoRepo(db.DB)
-	votesRepo := repo.NewVoteRepo(db.DB)
+	usersRepo := repo.NewUserRepo(db.DB, db.Replica)
+	videosRepo := repo.NewVideoRepo(db.DB, db.Replica)
+	votesRepo := repo.NewVoteRepo(db.DB, db.Replica)
+	snapshotsRepo := repo.NewSnapshotRepo(db.DB)
+	tournamentsRepo := repo.NewTournamentRepo(db.DB)
+	finalistsRepo := repo.NewFinalistRepo(db.DB)
+	reportsRepo := repo.NewReportRepo(db.DB)
+	tasksRepo := repo.NewTaskRepo(db.DB)
 
 	// services
 	authSvc := auth.NewService(usersRepo, cfg.JWTSecret, cfg.JWTExpireMinutes)
 
-	// Kafka producer for video processing
-	kafkaProducer, err := kafka.NewProducer(cfg.KafkaBrokers)
-	if err != nil {
-		log.Fatalf("Failed to create Kafka producer: %v", err)
-	}
-	defer kafkaProducer.Close()
-
 	// Redis client for caching
 	redisCli := redis.NewClient(&redis.Options{
 		Addr:     cfg.RedisAddr,
 		Password: cfg.RedisPassword,
 		DB:       0, // Use default DB for caching
 	})
+	if err := redisotel.InstrumentTracing(redisCli); err != nil {
+		slog.Warn("Failed to instrument Redis tracing", "error", err)
+	}
+
+	// Task queue for video processing (QUEUE_BACKEND): Kafka, Redis Streams,
+	// or a queue in the memory of this process that no worker reads
+	var taskPublisher queue.TaskPublisher
+	switch cfg.QueueBackend {
+	case queue.BackendKafka:
+		kafkaProducer, err := kafka.NewProducer(cfg.KafkaBrokers)
+		if err != nil {
+			logging.Fatal("Failed to create Kafka producer", "error", err)
+		}
+		taskPublisher = kafkaProducer
+	case queue.BackendRedis:
+		taskPublisher = queue.NewRedisPublisher(redisCli)
+	case queue.BackendMemory:
+		slog.Warn("QUEUE_BACKEND=memory: uploads are queued in this process and no worker processes them")
+		taskPublisher = queue.NewMemory(queue.MemoryCapacity)
+	default:
+		logging.Fatal("Unsupported QUEUE_BACKEND", "backend", cfg.QueueBackend, "supported", "kafka, redis, memory")
+	}
+	defer taskPublisher.Close()
 
-	// Initialize cache with 3-minute TTL (within the 1-5 minute range requested)
+	// Initialize cache with 3-minute TTL (within the 1-5 minute range requested).
+	// It only backs the database fallback used until the leaderboard is built.
 	rankingsCache := cache.NewRankingsCache(redisCli, 3*time.Minute)
 
+	// Live updates: published to Redis pub/sub and fanned out by each replica's hub
+	eventsPub := events.NewPublisher(redisCli)
+	eventsHub := events.NewHub(redisCli)
+	// The hub outlives ctx until the load balancer stops routing here, so
+	// streams are not closed while their clients would reconnect to us
+	hubCtx, stopHub := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopHub()
+	go eventsHub.Run(hubCtx)
+
+	// Live leaderboard kept in Redis sorted sets, reconciled periodically with Postgres
+	leaderboard := cache.NewLeaderboard(redisCli)
+	rankingSvc := ranking.NewService(votesRepo, usersRepo, snapshotsRepo, tournamentsRepo, leaderboard, rankingsCache, eventsPub)
+	if cfg.LeaderboardReconcileMinutes > 0 {
+		rankingSvc.StartReconciler(ctx, time.Duration(cfg.LeaderboardReconcileMinutes)*time.Minute)
+	}
+	if cfg.RankingSnapshotMinutes > 0 {
+		rankingSvc.StartSnapshots(ctx, time.Duration(cfg.RankingSnapshotMinutes)*time.Minute)
+	}
+
+	// Fraud screening of votes; suspicious ones wait for admin review
+	fraudCfg := fraud.DefaultConfig()
+	fraudCfg.QuarantineScore = cfg.FraudQuarantineScore
+	fraudCfg.MaxVotesPerIP = int64(cfg.FraudMaxVotesPerIP)
+	fraudCfg.MaxVotesPerSubnet = int64(cfg.FraudMaxVotesPerSubnet)
+	fraudScorer := fraud.NewScorer(votesRepo, fraudCfg)
+
+	finalistSvc := finalists.NewService(finalistsRepo, votesRepo, usersRepo, tournamentsRepo, rankingSvc)
+	lifecycle := videosvc.NewLifecycle(videosRepo, votesRepo, rankingSvc, eventsPub)
+	moderationSvc := moderation.NewService(videosRepo, reportsRepo, lifecycle, cfg.ModerationReportThreshold)
+
 	store := storage.NewLocal("./storage")
-	videoSvc := videosvc.NewService(videosRepo, store, kafkaProducer)
+	videoSvc := videosvc.NewService(videosRepo, tasksRepo, tournamentsRepo, store, taskPublisher, lifecycle, cfg.QueueFairShare)
 
 	// handlers
-	authH := httpapi.NewAuthHandlers(authSvc)
+	authH := httpapi.NewAuthHandlers(authSvc, rankingSvc)
 	videoH := httpapi.NewVideoHandlers(usersRepo, videosRepo, videoSvc)
-	publicH := httpapi.NewPublicHandlers(videosRepo, votesRepo, usersRepo, rankingsCache)
+	publicH := httpapi.NewPublicHandlers(videosRepo, votesRepo, usersRepo, tournamentsRepo, rankingSvc, fraudScorer,
+		repo.VoteQuota{PerCity: cfg.VoteQuotaPerCity})
+	eventsH := httpapi.NewEventHandlers(eventsHub, authSvc)
+	adminH := httpapi.NewAdminHandlers(rankingSvc)
+	fraudH := httpapi.NewFraudHandlers(votesRepo, videosRepo, rankingSvc)
+	tournamentH := httpapi.NewTournamentHandlers(tournamentsRepo)
+	finalistH := httpapi.NewFinalistHandlers(finalistSvc, cfg.FinalistSlotsPerCity)
+	moderationH := httpapi.NewModerationHandlers(moderationSvc)
 
-	// router
-	r := gin.Default()
+	// router: gin's default logger is replaced by AccessLog, which logs through slog
+	r := gin.New()
+	r.Use(gin.Recovery(), httpapi.RequestID())
+
+	// Without trusted proxies X-Forwarded-For is ignored and ClientIP is the peer address
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logging.Fatal("Invalid TRUSTED_PROXIES", "error", err)
+	}
 
 	// CORS
 	r.Use(cors.New(cors.Config{
@@ -102,24 +198,43 @@ func main() {
 			"http://localhost:3000",
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Device-Fingerprint", httpapi.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "X-Next-Cursor", httpapi.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// One server span per request; the context reaches the repos via c.Request.Context()
+	r.Use(otelgin.Middleware("anb-api"), httpapi.AccessLog())
+
+	// Prometheus: latency per route and the metrics shared with the worker.
+	// /metrics is served on its own internal listener, not on this router
+	r.Use(metrics.Gin())
+
 	// Swagger documentation
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Health godoc
 	// @Summary Health check
-	// @Description Check if the API is running
+	// @Description Check if the API is running (kept for existing clients; probes should use /livez and /readyz)
 	// @Tags Health
 	// @Produce plain
 	// @Success 200 {string} string "ok"
 	// @Router /health [get]
 	r.GET("/api/health", func(c *gin.Context) { c.String(200, "ok") })
 
+	// Liveness only proves the process serves HTTP; readiness pings every
+	// dependency and answers 503 with the failing ones
+	checks := health.New(time.Duration(cfg.HealthCheckTimeoutMs)*time.Millisecond).
+		Add("postgres", func(ctx context.Context) error { return db.Ping(ctx, db.DB) }).
+		Add("redis", func(ctx context.Context) error { return redisCli.Ping(ctx).Err() }).
+		Add(cfg.QueueBackend, taskPublisher.Ping)
+	if cfg.PostgresReplicaURL != "" {
+		checks.Add("postgres_replica", func(ctx context.Context) error { return db.Ping(ctx, db.Replica) })
+	}
+	r.GET("/livez", gin.WrapH(health.Live()))
+	r.GET("/readyz", gin.WrapH(checks.Ready()))
+
 	// Auth
 	r.POST("/api/auth/signup", authH.SignUp)
 	r.POST("/api/auth/login", authH.Login)
@@ -132,19 +247,111 @@ func main() {
 		api.GET("/videos", videoH.MyVideos)
 		api.GET("/videos/:id", videoH.Detail)
 		api.DELETE("/videos/:id", videoH.Delete)
+		api.PATCH("/videos/:id", videoH.Update)
+		api.POST("/videos/:id/retry", videoH.Retry)
+		api.POST("/videos/:id/reprocess", videoH.Reprocess)
+		api.POST("/videos/:id/withdraw", videoH.Withdraw)
+		api.POST("/videos/:id/archive", videoH.Archive)
+		api.GET("/tasks/:id", videoH.Task)
 
 		// votar requiere JWT (aunque sea /public)
 		api.POST("/public/videos/:id/vote", publicH.Vote)
+		api.DELETE("/public/videos/:id/vote", publicH.Unvote)
+		api.GET("/votes", publicH.MyVotes)
+		api.POST("/public/videos/:id/report", moderationH.Report)
+
+		// Ticket para abrir /api/events con EventSource
+		api.POST("/events/ticket", eventsH.Ticket)
 
 		// Eliminar usuario para uso en pruebas de Postman
 		api.DELETE("/auth", authH.DeleteUser)
 	}
 
+	// Administración (JWT + rol admin)
+	admin := r.Group("/api/admin")
+	admin.Use(httpapi.JWT(cfg.JWTSecret), httpapi.RequireRole(usersRepo, domain.RoleAdmin))
+	{
+		admin.POST("/rankings/snapshots", adminH.TakeSnapshot)
+		admin.POST("/tournaments", tournamentH.Create)
+		admin.PUT("/tournaments/:id", tournamentH.Update)
+		admin.POST("/finalists/compute", finalistH.Compute)
+		admin.POST("/finalists/:id/freeze", finalistH.Freeze)
+		admin.GET("/votes/quarantined", fraudH.ListQuarantined)
+		admin.POST("/votes/:id/approve", fraudH.ApproveVote)
+		admin.POST("/votes/:id/reject", fraudH.RejectVote)
+	}
+
+	// Jurado (JWT + rol jury o admin)
+	jury := r.Group("/api/jury")
+	jury.Use(httpapi.JWT(cfg.JWTSecret), httpapi.RequireRole(usersRepo, domain.RoleAdmin, domain.RoleJury))
+	{
+		jury.GET("/finalists/:id", finalistH.Get)
+		jury.POST("/finalists/:id/decisions", finalistH.Decide)
+		jury.GET("/finalists/:id/roster", finalistH.Roster)
+
+		jury.GET("/moderation/queue", moderationH.Queue)
+		jury.GET("/moderation/reports", moderationH.Reports)
+		jury.POST("/moderation/videos/:id/approve", moderationH.Approve)
+		jury.POST("/moderation/videos/:id/reject", moderationH.Reject)
+		jury.POST("/moderation/videos/:id/request-reupload", moderationH.RequestReupload)
+	}
+
 	// Público sin auth
-	r.GET("/api/public/videos", publicH.ListVideos)
+	r.GET("/api/public/videos", httpapi.OptionalJWT(cfg.JWTSecret), publicH.ListVideos)
 	r.GET("/api/public/rankings", publicH.Rankings)
+	r.GET("/api/public/rankings/history", publicH.RankingHistory)
+	r.GET("/api/public/rankings/final", publicH.FinalRankings)
 	r.GET("/api/public/cities", publicH.GetCities)
+	r.GET("/api/public/finalists", finalistH.Results)
+	r.GET("/api/public/tournaments", tournamentH.List)
+	r.GET("/api/public/tournaments/:id", tournamentH.Get)
+
+	// Live updates (SSE); a token or stream ticket is optional and unlocks the
+	// user's own video events
+	r.GET("/api/events", httpapi.StreamAuth(cfg.JWTSecret), eventsH.Stream)
+
+	srv := &http.Server{Addr: ":" + cfg.AppPort, Handler: r}
+	go func() {
+		slog.Info("API listening", "port", cfg.AppPort)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("HTTP server error", "error", err)
+		}
+	}()
+
+	var metricsSrv *http.Server
+	if cfg.APIMetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		metricsSrv = &http.Server{Addr: cfg.APIMetricsAddr, Handler: mux}
+		go func() {
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Metrics server error", "error", err)
+			}
+		}()
+		slog.Info("Metrics server listening", "addr", cfg.APIMetricsAddr)
+	}
 
-	log.Printf("API listening on :%s", cfg.AppPort)
-	_ = r.Run(":" + cfg.AppPort)
+	<-ctx.Done()
+	stop()
+	slog.Info("Shutting down, draining in-flight requests", "timeout_seconds", cfg.ShutdownTimeoutSeconds)
+
+	// /readyz turns 503 so the load balancer stops routing here. The listener
+	// stays open until the load balancer has seen it, then SSE streams end
+	// with the hub and every other request gets the timeout to finish
+	checks.Drain()
+	if delay := time.Duration(cfg.ShutdownDrainDelaySeconds) * time.Second; delay > 0 {
+		slog.Info("Waiting for the load balancer to stop routing here", "delay_seconds", cfg.ShutdownDrainDelaySeconds)
+		time.Sleep(delay)
+	}
+	stopHub()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Drain timed out, closing remaining connections", "error", err)
+		_ = srv.Close()
+	}
+	if metricsSrv != nil {
+		_ = metricsSrv.Shutdown(shutdownCtx)
+	}
+	slog.Info("API shutdown complete")
 }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/cache"
+	"github.com/Cloud-2025-2/anb-platform/internal/config"
+	"github.com/Cloud-2025-2/anb-platform/internal/db"
+	"github.com/Cloud-2025-2/anb-platform/internal/ranking"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+)
+
+// Rebuilds the Redis leaderboard from the votes stored in Postgres.
+// Use it to recover after a Redis flush or data loss:
+//
+//	go run ./cmd/leaderboard
+func main() {
+	_ = godotenv.Load()
+
+	cfg := config.Load()
+
+	db.Connect(cfg)
+	votesRepo := repo.NewVoteRepo(db.DB, db.Replica)
+	usersRepo := repo.NewUserRepo(db.DB, db.Replica)
+	snapshotsRepo := repo.NewSnapshotRepo(db.DB)
+	tournamentsRepo := repo.NewTournamentRepo(db.DB)
+
+	redisCli := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       0,
+	})
+	defer redisCli.Close()
+
+	rankingSvc := ranking.NewService(votesRepo, usersRepo, snapshotsRepo, tournamentsRepo, cache.NewLeaderboard(redisCli), cache.NewRankingsCache(redisCli, 0), nil)
+	if err := rankingSvc.Rebuild(context.Background()); err != nil {
+		log.Fatalf("Failed to rebuild leaderboard: %v", err)
+	}
+
+	log.Println("Leaderboard rebuilt successfully")
+}
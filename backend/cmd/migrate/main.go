@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/joho/godotenv"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/config"
+	"github.com/Cloud-2025-2/anb-platform/internal/db"
+	"github.com/Cloud-2025-2/anb-platform/internal/logging"
+	"github.com/Cloud-2025-2/anb-platform/internal/migrations"
+)
+
+// Applies or reverts the versioned SQL migrations in internal/migrations/sql:
+//
+//	go run ./cmd/migrate up         # apply every pending migration
+//	go run ./cmd/migrate down [n]   # revert the last n migrations (default 1)
+//	go run ./cmd/migrate status     # show applied and pending versions
+func main() {
+	_ = godotenv.Load()
+
+	cmd := "up"
+	if len(os.Args) > 1 {
+		cmd = os.Args[1]
+	}
+
+	// Migrations can run for longer than a request and never use the replica
+	cfg := config.Load()
+	if err := logging.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		logging.Fatal("Invalid logging configuration", "error", err)
+	}
+	cfg.DBStatementTimeoutMs = 0
+	cfg.PostgresReplicaURL = ""
+	db.Connect(cfg)
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		logging.Fatal("Failed to get database handle", "error", err)
+	}
+	defer sqlDB.Close()
+	ctx := context.Background()
+
+	switch cmd {
+	case "up":
+		applied, err := migrations.Up(ctx, sqlDB)
+		for _, v := range applied {
+			slog.Info("Applied migration", "version", v)
+		}
+		if err != nil {
+			logging.Fatal("Migration failed", "error", err)
+		}
+		if len(applied) == 0 {
+			slog.Info("Database is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			if steps, err = strconv.Atoi(os.Args[2]); err != nil || steps <= 0 {
+				logging.Fatal("down: steps must be a positive integer", "steps", os.Args[2])
+			}
+		}
+		reverted, err := migrations.Down(ctx, sqlDB, steps)
+		for _, v := range reverted {
+			slog.Info("Reverted migration", "version", v)
+		}
+		if err != nil {
+			logging.Fatal("Revert failed", "error", err)
+		}
+	case "status":
+		all, err := migrations.All()
+		if err != nil {
+			logging.Fatal("Failed to read migrations", "error", err)
+		}
+		current, err := migrations.Current(ctx, sqlDB)
+		if err != nil {
+			logging.Fatal("Failed to read schema version", "error", err)
+		}
+		for _, m := range all {
+			state := "pending"
+			if m.Version <= current {
+				state = "applied"
+			}
+			fmt.Printf("%04d_%s\t%s\n", m.Version, m.Name, state)
+		}
+	default:
+		logging.Fatal("Unknown command", "command", cmd, "supported", "up, down, status")
+	}
+}
@@ -2,21 +2,32 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/extra/redisotel/v9"
+	"github.com/redis/go-redis/v9"
 
 	"github.com/Cloud-2025-2/anb-platform/internal/config"
 	"github.com/Cloud-2025-2/anb-platform/internal/db"
-	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/events"
+	"github.com/Cloud-2025-2/anb-platform/internal/health"
 	"github.com/Cloud-2025-2/anb-platform/internal/kafka"
+	"github.com/Cloud-2025-2/anb-platform/internal/logging"
+	"github.com/Cloud-2025-2/anb-platform/internal/metrics"
 	"github.com/Cloud-2025-2/anb-platform/internal/processing"
+	"github.com/Cloud-2025-2/anb-platform/internal/queue"
 	"github.com/Cloud-2025-2/anb-platform/internal/repo"
 	"github.com/Cloud-2025-2/anb-platform/internal/storage"
+	"github.com/Cloud-2025-2/anb-platform/internal/tracing"
+	videosvc "github.com/Cloud-2025-2/anb-platform/internal/video"
 )
 
 func main() {
@@ -24,66 +35,169 @@ func main() {
 	_ = godotenv.Load()
 
 	cfg := config.Load()
+	if err := logging.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		logging.Fatal("Invalid logging configuration", "error", err)
+	}
+
+	// Tracing: consumed tasks continue the trace of the upload that enqueued them
+	shutdownTracing, err := tracing.Init(context.Background(), "anb-worker", cfg.TracingExporter)
+	if err != nil {
+		logging.Fatal("Failed to set up tracing", "error", err)
+	}
+	defer shutdownTracing(context.Background())
 
 	// Database connection
-	db.Connect()
-	if err := db.DB.AutoMigrate(&domain.User{}, &domain.Video{}, &domain.Vote{}); err != nil {
-		log.Fatal(err)
+	db.Connect(cfg)
+	if err := db.RequireSchema(); err != nil {
+		logging.Fatal("Database schema check failed", "error", err)
 	}
 
 	// Repositories
-	videosRepo := repo.NewVideoRepo(db.DB)
+	videosRepo := repo.NewVideoRepo(db.DB, db.Replica)
+	tasksRepo := repo.NewTaskRepo(db.DB)
 
 	// Storage service
 	store := storage.NewLocal("./storage")
 
 	// Video processor
-	processor := processing.NewVideoProcessor("./temp", "./assets", "./storage")
-
-	// Kafka producer for retry/DLQ
-	producer, err := kafka.NewProducer(cfg.KafkaBrokers)
-	if err != nil {
-		log.Fatalf("Failed to create Kafka producer: %v", err)
+	// Each ffmpeg step is killed after FFMPEG_STEP_TIMEOUT_SECONDS
+	processor := processing.NewVideoProcessor("./temp", "./assets", "./storage",
+		time.Duration(cfg.FFmpegStepTimeoutSeconds)*time.Second)
+
+	// Redis client to push video status changes to the API replicas
+	redisCli := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       0,
+	})
+	defer redisCli.Close()
+	if err := redisotel.InstrumentTracing(redisCli); err != nil {
+		slog.Warn("Failed to instrument Redis tracing", "error", err)
 	}
-	defer producer.Close()
 
 	// Create worker service
-	worker := NewWorkerService(videosRepo, store, processor)
+	// Votes never enter or leave the rankings from the worker, so the
+	// lifecycle runs without a vote tally
+	lifecycle := videosvc.NewLifecycle(videosRepo, nil, nil, events.NewPublisher(redisCli))
+	worker := NewWorkerService(videosRepo, tasksRepo, store, processor, lifecycle)
 
-	// Kafka consumer
+	// Consumer group: every worker shares it, so each task runs once
 	groupID := os.Getenv("KAFKA_GROUP_ID")
 	if groupID == "" {
 		groupID = "video-processors"
 	}
 
-	consumer, err := kafka.NewConsumer(cfg.KafkaBrokers, groupID, producer, worker)
-	if err != nil {
-		log.Fatalf("Failed to create Kafka consumer: %v", err)
+	// Up to WORKER_CONCURRENCY videos run at once. On shutdown the tasks in
+	// progress get WORKER_DRAIN_SECONDS to finish before their ffmpeg is
+	// killed and they are left for redelivery
+	drain := time.Duration(cfg.WorkerDrainSeconds) * time.Second
+
+	// Task queue (QUEUE_BACKEND): Kafka topics, Redis Streams, or a queue in
+	// the memory of this process (only its own retries reach it)
+	var consumer queue.TaskConsumer
+	var kafkaConsumer *kafka.Consumer
+	var brokerCheck health.CheckFunc
+	switch cfg.QueueBackend {
+	case queue.BackendKafka:
+		// Kafka producer for retry/DLQ
+		producer, err := kafka.NewProducer(cfg.KafkaBrokers)
+		if err != nil {
+			logging.Fatal("Failed to create Kafka producer", "error", err)
+		}
+		defer producer.Close()
+
+		kafkaConsumer, err = kafka.NewConsumer(cfg.KafkaBrokers, groupID, producer, worker.HandleTask, cfg.WorkerConcurrency, drain)
+		if err != nil {
+			logging.Fatal("Failed to create Kafka consumer", "error", err)
+		}
+		consumer, brokerCheck = kafkaConsumer, producer.Ping
+	case queue.BackendRedis:
+		// Pending tasks of a worker that died are claimed after QUEUE_CLAIM_IDLE_SECONDS
+		consumer = queue.NewRedisConsumer(redisCli, groupID, worker.workerID, worker.HandleTask, cfg.WorkerConcurrency, drain,
+			time.Duration(cfg.QueueClaimIdleSeconds)*time.Second)
+	case queue.BackendMemory:
+		slog.Warn("QUEUE_BACKEND=memory: the worker only sees tasks queued in its own process")
+		consumer = queue.NewMemoryConsumer(queue.NewMemory(queue.MemoryCapacity), worker.HandleTask, cfg.WorkerConcurrency, drain)
+	default:
+		logging.Fatal("Unsupported QUEUE_BACKEND", "backend", cfg.QueueBackend, "supported", "kafka, redis, memory")
 	}
-	defer consumer.Close()
-
-	// Context for graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// Handle graceful shutdown
-	sigterm := make(chan os.Signal, 1)
-	signal.Notify(sigterm, syscall.SIGINT, syscall.SIGTERM)
 
+	// Cancelled on SIGINT/SIGTERM: stops fetching new tasks
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 	go func() {
-		<-sigterm
-		log.Println("Received termination signal, shutting down gracefully...")
-		cancel()
+		<-ctx.Done()
+		slog.Info("Received termination signal, draining current tasks", "drain_seconds", cfg.WorkerDrainSeconds)
 	}()
 
-	log.Println("Starting video processing worker...")
-	log.Printf("Kafka brokers: %s", strings.Join(cfg.KafkaBrokers, ","))
-	log.Printf("Consumer group: %s", groupID)
+	// ffmpeg is looked up once; a worker without it can't process anything
+	ffmpegVersion, ffmpegErr := processing.FFmpegVersion(context.Background())
+	if ffmpegErr != nil {
+		slog.Error("ffmpeg not available", "error", ffmpegErr)
+	}
+
+	// HTTP server: Prometheus metrics (ffmpeg steps, Kafka latency and lag,
+	// retries and DB pool), liveness and readiness. It stays up while the
+	// current tasks drain.
+	var srv *http.Server
+	if cfg.WorkerMetricsAddr != "" {
+		checks := health.New(time.Duration(cfg.HealthCheckTimeoutMs)*time.Millisecond).
+			Add("postgres", func(ctx context.Context) error { return db.Ping(ctx, db.DB) }).
+			Add("redis", func(ctx context.Context) error { return redisCli.Ping(ctx).Err() }).
+			Add("consumer_group", consumer.Ready).
+			Add("ffmpeg", func(context.Context) error { return ffmpegErr }).
+			Info("queue_backend", func() any { return cfg.QueueBackend }).
+			Info("consumer_group", func() any {
+				if kafkaConsumer != nil {
+					return kafkaConsumer.Membership()
+				}
+				return groupID
+			}).
+			Info("concurrency", func() any { return cfg.WorkerConcurrency }).
+			Info("current_jobs", func() any { return worker.CurrentJobs() }).
+			Info("ffmpeg_version", func() any { return ffmpegVersion })
+		if brokerCheck != nil {
+			checks.Add(cfg.QueueBackend, brokerCheck)
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		mux.Handle("/livez", health.Live())
+		mux.Handle("/readyz", checks.Ready())
+		srv = &http.Server{Addr: cfg.WorkerMetricsAddr, Handler: mux}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Worker HTTP server error", "error", err)
+			}
+		}()
+		go func() {
+			<-ctx.Done()
+			checks.Drain()
+		}()
+		slog.Info("Worker HTTP server listening", "addr", cfg.WorkerMetricsAddr, "paths", "/metrics /livez /readyz")
+	}
+
+	slog.Info("Starting video processing worker",
+		"queue_backend", cfg.QueueBackend,
+		"kafka_brokers", strings.Join(cfg.KafkaBrokers, ","),
+		"consumer_group", groupID,
+		"concurrency", cfg.WorkerConcurrency)
+
+	// Start consuming; returns once the tasks in progress finished or were aborted
+	if err := consumer.Start(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		slog.Error("Consumer error", "error", err)
+	}
 
-	// Start consuming
-	if err := consumer.Start(ctx); err != nil {
-		log.Printf("Consumer error: %v", err)
+	// Leaving the Kafka group commits the marked offsets and hands the
+	// partitions to the remaining workers right away
+	if err := consumer.Close(); err != nil {
+		slog.Error("Failed to close queue consumer", "error", err)
+	}
+	if srv != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
 	}
 
-	log.Println("Worker shutdown complete")
+	slog.Info("Worker shutdown complete")
 }
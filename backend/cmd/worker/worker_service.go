@@ -1,59 +1,162 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/Cloud-2025-2/anb-platform/internal/domain"
 	"github.com/Cloud-2025-2/anb-platform/internal/processing"
+	"github.com/Cloud-2025-2/anb-platform/internal/queue"
 	"github.com/Cloud-2025-2/anb-platform/internal/repo"
 	"github.com/Cloud-2025-2/anb-platform/internal/storage"
+	"github.com/Cloud-2025-2/anb-platform/internal/tracing"
+	videosvc "github.com/Cloud-2025-2/anb-platform/internal/video"
 )
 
+// progressInterval is the least time between two progress writes to the
+// task record; step changes are always written.
+const progressInterval = 2 * time.Second
+
 type WorkerService struct {
 	videos    repo.VideoRepository
+	tasks     repo.TaskRepository
 	store     storage.Storage
 	processor *processing.VideoProcessor
+	lifecycle *videosvc.Lifecycle
+	workerID  string
+
+	// ProcessVideoWithID runs concurrently, one call per task in the pool
+	mu   sync.Mutex
+	jobs map[*Job]struct{}
 }
 
-func NewWorkerService(videos repo.VideoRepository, store storage.Storage, processor *processing.VideoProcessor) *WorkerService {
+// Job is a video the worker is processing, reported on /readyz.
+type Job struct {
+	TaskID    uuid.UUID `json:"task_id"`
+	VideoID   uuid.UUID `json:"video_id"`
+	Profile   string    `json:"profile,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	Step      string    `json:"step,omitempty"`
+	Progress  int       `json:"progress"`
+}
+
+func NewWorkerService(videos repo.VideoRepository, tasks repo.TaskRepository, store storage.Storage, processor *processing.VideoProcessor, lifecycle *videosvc.Lifecycle) *WorkerService {
+	workerID, _ := os.Hostname()
 	return &WorkerService{
 		videos:    videos,
+		tasks:     tasks,
 		store:     store,
 		processor: processor,
+		lifecycle: lifecycle,
+		workerID:  workerID,
+		jobs:      make(map[*Job]struct{}),
 	}
 }
 
-// ProcessVideoWithID processes a video using the provided video ID
-func (w *WorkerService) ProcessVideoWithID(videoID uuid.UUID, inputPath, outputPath string) error {
-	log.Printf("Worker processing video: %s -> %s (VideoID: %s)", inputPath, outputPath, videoID)
+// HandleTask is the queue.Handler of the worker: it processes the video of
+// task next to its original, as <video_id>_processed.mp4.
+func (w *WorkerService) HandleTask(ctx context.Context, task queue.Task) error {
+	videoID, err := uuid.Parse(task.VideoID)
+	if err != nil {
+		return fmt.Errorf("invalid video ID format: %w", err)
+	}
+	outputPath := filepath.Join(filepath.Dir(task.FilePath), w.generateProcessedFileName(videoID))
+
+	// Tasks published before task records existed carry no ID
+	taskID, _ := uuid.Parse(task.TaskID)
+
+	// A duplicate delivery (same idempotency key) of a task that already
+	// succeeded is dropped
+	if taskID != uuid.Nil {
+		if rec, err := w.tasks.FindByID(ctx, taskID); err == nil && rec.Status == domain.TaskSucceeded {
+			slog.InfoContext(ctx, "worker: skipping duplicate task", "idempotency_key", task.IdempotencyKey())
+			return nil
+		}
+	}
+
+	// queue.Dispatch retries a failure until the task used up its retries
+	retryable := task.RetryCount < queue.MaxRetries
+	return w.ProcessVideoWithID(ctx, taskID, videoID, task.FilePath, outputPath, task.Profile, retryable)
+}
+
+// ProcessVideoWithID processes a video using the provided video ID and
+// processing profile ("" for the default one). The outcome and progress are
+// recorded on the task record taskID, if not uuid.Nil; retryable tells
+// whether a failure will be retried.
+func (w *WorkerService) ProcessVideoWithID(ctx context.Context, taskID, videoID uuid.UUID, inputPath, outputPath, profileName string, retryable bool) (err error) {
+	ctx, span := tracing.Tracer.Start(ctx, "worker.process_video",
+		trace.WithAttributes(attribute.String("video.id", videoID.String()), attribute.String("video.profile", profileName)))
+	defer func() { tracing.End(span, err) }()
+
+	job := &Job{TaskID: taskID, VideoID: videoID, Profile: profileName, StartedAt: time.Now()}
+	w.addJob(job)
+	defer w.removeJob(job)
+
+	// skipped is set when the task is dropped without an error (no retry)
+	var skipped error
+	if taskID != uuid.Nil {
+		if err := w.tasks.Start(ctx, taskID, w.workerID); err != nil {
+			slog.WarnContext(ctx, "worker: failed to record task start", "error", err)
+		}
+		defer func() { w.finishTask(ctx, taskID, err, skipped, retryable) }()
+	}
+
+	slog.InfoContext(ctx, "worker: processing video", "input", inputPath, "output", outputPath)
+
+	profile, err := processing.ParseProfile(profileName)
+	if err != nil {
+		// A bad profile will never succeed; don't send it to retry
+		slog.WarnContext(ctx, "worker: skipping video", "reason", err)
+		skipped = err
+		return nil
+	}
 
 	absInputPath, _ := filepath.Abs(inputPath)
 
 	// Get video from database using the provided ID
-	video, err := w.videos.FindByID(videoID)
+	video, err := w.videos.FindByID(ctx, videoID)
 	if err != nil {
 		return fmt.Errorf("failed to find video in database: %w", err)
 	}
 
-	// Update status to processing
-	video.Status = domain.VideoProcessing
-	if err := w.videos.Update(video); err != nil {
-		log.Printf("Warning: failed to update video status to processing: %v", err)
+	// Update status to processing. A video already in processing is a
+	// redelivered task (e.g. after a worker crash) and is processed again.
+	if video.Status != domain.VideoProcessing {
+		if err := w.lifecycle.Transition(ctx, video, domain.VideoProcessing, nil, ""); err != nil {
+			var illegal domain.ErrIllegalTransition
+			if errors.As(err, &illegal) {
+				// Archived or already processed: nothing to do, don't retry
+				slog.WarnContext(ctx, "worker: skipping video", "reason", err)
+				skipped = err
+				return nil
+			}
+			return fmt.Errorf("failed to update video status to processing: %w", err)
+		}
 	}
 
-	// Use the exact outputPath provided - don't generate a new one
-	log.Printf("Processing video to output path: %s", outputPath)
-
 	// Process the video using FFmpeg directly to the specified output path
-	if err := w.processor.ProcessVideo(absInputPath, outputPath); err != nil {
+	if err := w.processor.ProcessVideoWithProfile(ctx, absInputPath, outputPath, profile, w.progress(ctx, job)); err != nil {
+		if ctx.Err() != nil {
+			// Aborted by a shutdown: the video stays in processing and the
+			// redelivered task processes it again
+			slog.WarnContext(ctx, "worker: processing aborted", "error", err)
+			return err
+		}
 		// Update status to failed
-		video.Status = domain.VideoFailed
-		w.videos.Update(video)
+		if terr := w.lifecycle.Transition(ctx, video, domain.VideoFailed, nil, err.Error()); terr != nil {
+			slog.ErrorContext(ctx, "worker: failed to mark video as failed", "error", terr)
+		}
 		return fmt.Errorf("video processing failed: %w", err)
 	}
 
@@ -62,30 +165,112 @@ func (w *WorkerService) ProcessVideoWithID(videoID uuid.UUID, inputPath, outputP
 	processedURL := fmt.Sprintf("/storage/%s", filepath.Base(outputPath))
 
 	// Update video record with processed information
-	now := time.Now()
-	video.Status = domain.VideoPublished
 	video.ProcessedURL = &processedURL
-	video.ProcessedAt = &now
-	video.PublishedAt = &now
-	video.IsPublicForVote = true
 	video.Watermark = true
 
-	video.WidthProc = &[]int{1280}[0]
-	video.HeightProc = &[]int{720}[0]
+	width, height := profile.Size()
+	video.Profile = string(profile)
+	video.WidthProc = &width
+	video.HeightProc = &height
 	video.AspectProc = &[]string{"16:9"}[0]
 	video.HasAudioOrig = &[]bool{false}[0]
 
-	if err := w.videos.Update(video); err != nil {
+	if err := w.lifecycle.Transition(ctx, video, domain.VideoProcessed, nil, ""); err != nil {
 		return fmt.Errorf("failed to update video record: %w", err)
 	}
+	// The video only becomes votable once a moderator approves it
+	if err := w.lifecycle.Transition(ctx, video, domain.VideoPendingReview, nil, ""); err != nil {
+		return fmt.Errorf("failed to queue video for review: %w", err)
+	}
 
-	log.Printf("Successfully processed video %s to %s", video.ID, outputPath)
+	slog.InfoContext(ctx, "worker: video processed", "output", outputPath)
 	return nil
 }
 
+// progress returns the ProgressFunc that keeps job and, at most every
+// progressInterval, its task record up to date.
+func (w *WorkerService) progress(ctx context.Context, job *Job) processing.ProgressFunc {
+	taskID := job.TaskID
+	var lastStep string
+	var lastWrite time.Time
+	lastPercent := -1
+	return func(step string, percent float64) {
+		pct := int(percent)
+		w.mu.Lock()
+		job.Step, job.Progress = step, pct
+		w.mu.Unlock()
+
+		if taskID == uuid.Nil || (pct == lastPercent && step == lastStep) {
+			return
+		}
+		if step == lastStep && time.Since(lastWrite) < progressInterval {
+			return
+		}
+		if err := w.tasks.UpdateProgress(ctx, taskID, step, pct); err != nil {
+			slog.DebugContext(ctx, "worker: failed to record progress", "error", err)
+			return
+		}
+		lastStep, lastPercent, lastWrite = step, pct, time.Now()
+	}
+}
+
+// finishTask records the outcome of the attempt. An attempt aborted by a
+// shutdown puts the task back to queued, since it will be redelivered, and a
+// failed one that will be retried is retrying; it is only failed once it goes
+// to the DLQ.
+func (w *WorkerService) finishTask(ctx context.Context, taskID uuid.UUID, err, skipped error, retryable bool) {
+	aborted := err != nil && ctx.Err() != nil
+	ctx = context.WithoutCancel(ctx)
+
+	status := domain.TaskSucceeded
+	var lastError *string
+	switch {
+	case aborted:
+		status = domain.TaskQueued
+		lastError = ptr("aborted: " + err.Error())
+	case err != nil && retryable:
+		status = domain.TaskRetrying
+		lastError = ptr(err.Error())
+	case err != nil:
+		status = domain.TaskFailed
+		lastError = ptr(err.Error())
+	case skipped != nil:
+		status = domain.TaskFailed
+		lastError = ptr("skipped: " + skipped.Error())
+	}
+	if ferr := w.tasks.Finish(ctx, taskID, status, lastError); ferr != nil {
+		slog.WarnContext(ctx, "worker: failed to record task outcome", "status", status, "error", ferr)
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+// CurrentJobs returns a copy of the jobs in progress, oldest first.
+func (w *WorkerService) CurrentJobs() []Job {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	jobs := make([]Job, 0, len(w.jobs))
+	for job := range w.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].StartedAt.Before(jobs[j].StartedAt) })
+	return jobs
+}
+
+func (w *WorkerService) addJob(job *Job) {
+	w.mu.Lock()
+	w.jobs[job] = struct{}{}
+	w.mu.Unlock()
+}
+
+func (w *WorkerService) removeJob(job *Job) {
+	w.mu.Lock()
+	delete(w.jobs, job)
+	w.mu.Unlock()
+}
+
 // ProcessVideo processes a video by extracting ID from path (legacy method)
 func (w *WorkerService) ProcessVideo(inputPath, outputPath string) error {
-	log.Printf("Worker processing video: %s -> %s", inputPath, outputPath)
 
 	// Extract video ID from the path (assuming it's in the filename)
 	videoID, err := w.extractVideoIDFromPath(inputPath)
@@ -93,7 +278,7 @@ func (w *WorkerService) ProcessVideo(inputPath, outputPath string) error {
 		return fmt.Errorf("failed to extract video ID: %w", err)
 	}
 
-	return w.ProcessVideoWithID(videoID, inputPath, outputPath)
+	return w.ProcessVideoWithID(context.Background(), uuid.Nil, videoID, inputPath, outputPath, "", false)
 }
 
 func (w *WorkerService) extractVideoIDFromPath(path string) (uuid.UUID, error) {
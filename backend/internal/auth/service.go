@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"errors"
 	"time"
 
@@ -21,14 +22,14 @@ func NewService(users repo.UserRepository, secret string, expiresMinutes int) *S
 	return &Service{users: users, secret: secret, expires: time.Duration(expiresMinutes) * time.Minute}
 }
 
-func (s *Service) SignUp(in domain.User, password1, password2 string) error {
+func (s *Service) SignUp(ctx context.Context, in domain.User, password1, password2 string) error {
 	if password1 != password2 {
 		return errors.New("passwords do not match")
 	}
 	hash, _ := bcrypt.GenerateFromPassword([]byte(password1), bcrypt.DefaultCost)
 	in.PasswordHash = string(hash)
 	in.Role = "player"
-	return s.users.Create(&in)
+	return s.users.Create(ctx, &in)
 }
 
 type LoginResult struct {
@@ -37,8 +38,8 @@ type LoginResult struct {
 	ExpiresIn int    `json:"expires_in"`
 }
 
-func (s *Service) Login(email, password string) (*LoginResult, error) {
-	u, err := s.users.FindByEmail(email)
+func (s *Service) Login(ctx context.Context, email, password string) (*LoginResult, error) {
+	u, err := s.users.FindByEmail(ctx, email)
 	if err != nil {
 		return nil, errors.New("invalid credentials")
 	}
@@ -62,6 +63,35 @@ func (s *Service) Login(email, password string) (*LoginResult, error) {
 	}, nil
 }
 
-func (s *Service) DeleteUser(userID uuid.UUID) error {
-	return s.users.DeleteByID(userID)
+// StreamAudience is the audience of stream tickets. Tickets are only accepted
+// by the live updates stream, and access tokens are not accepted there in the
+// query string.
+const StreamAudience = "events"
+
+// StreamTicketTTL is how long a stream ticket can be used to open a stream.
+const StreamTicketTTL = time.Minute
+
+type StreamTicketResult struct {
+	Ticket    string `json:"ticket"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// StreamTicket issues a short-lived token that lets EventSource, which cannot
+// send headers, open the live updates stream as userID. It goes in the query
+// string, where proxies log it, so it can't be used anywhere else.
+func (s *Service) StreamTicket(userID string) (*StreamTicketResult, error) {
+	claims := jwt.MapClaims{
+		"sub": userID,
+		"aud": StreamAudience,
+		"exp": time.Now().Add(StreamTicketTTL).Unix(),
+	}
+	tokenStr, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.secret))
+	if err != nil {
+		return nil, err
+	}
+	return &StreamTicketResult{Ticket: tokenStr, ExpiresIn: int(StreamTicketTTL.Seconds())}, nil
+}
+
+func (s *Service) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	return s.users.DeleteByID(ctx, userID)
 }
@@ -0,0 +1,520 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+)
+
+const (
+	leaderboardPrefix             = "leaderboard:"
+	leaderboardKeysKey            = "leaderboard:keys" // every key written, so Rebuild can drop stale boards
+	leaderboardPlayersKey         = "leaderboard:players"
+	leaderboardVideosKey          = "leaderboard:videos"
+	leaderboardPlayerVideosPrefix = "leaderboard:player_videos:"
+	leaderboardBuiltKey           = "leaderboard:built"
+	leaderboardRebuildingKey      = "leaderboard:rebuilding" // set while a rebuild reads Postgres
+	leaderboardJournalKey         = "leaderboard:journal"    // increments made meanwhile, replayed by Rebuild
+)
+
+// leaderboardRebuildTTL bounds how long a rebuild that died keeps votes
+// journaled.
+const leaderboardRebuildTTL = 10 * time.Minute
+
+// leaderboardRebuildRetries is how many times Rebuild retries when votes keep
+// landing while it writes the boards.
+const leaderboardRebuildRetries = 5
+
+// leaderboardIncrRetries is how many times Incr retries when a rebuild starts
+// or ends while it is being applied.
+const leaderboardIncrRetries = 5
+
+// Leaderboard keeps vote tallies in Redis sorted sets, updated incrementally
+// on each vote. There is one board per scope (player or video) and filter
+// (all, city, country, country+city), both across the whole platform and per
+// tournament. Members are player or video IDs, so
+// ZREVRANGE orders ties by ID descending, the same order TopByCity uses.
+type Leaderboard struct {
+	client *redis.Client
+}
+
+// LeaderboardVideo identifies a voted video and its owner.
+type LeaderboardVideo struct {
+	VideoID      uuid.UUID  `json:"video_id"`
+	Title        string     `json:"title"`
+	TournamentID *uuid.UUID `json:"tournament_id,omitempty"`
+	UserID       uuid.UUID  `json:"user_id"`
+	Username     string     `json:"username"`
+	City         string     `json:"city"`
+	Country      string     `json:"country"`
+}
+
+// LeaderboardEntry is a video together with its total votes, used to rebuild the boards.
+type LeaderboardEntry struct {
+	Video LeaderboardVideo
+	Votes int64
+}
+
+// leaderboardJournalEntry is an increment made while a rebuild was running.
+type leaderboardJournalEntry struct {
+	Video LeaderboardVideo `json:"video"`
+	Delta int64            `json:"delta"`
+}
+
+type leaderboardPlayer struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Username string    `json:"username"`
+	City     string    `json:"city"`
+	Country  string    `json:"country"`
+}
+
+func NewLeaderboard(client *redis.Client) *Leaderboard {
+	return &Leaderboard{client: client}
+}
+
+// Incr adds delta votes to the video and to its owner in every board they
+// belong to, and returns the owner's new total. Entries whose score drops to
+// zero are removed so they stop showing up in the ranking. While a rebuild is
+// running the increment is also journaled, so the rebuild can replay it. The
+// rebuild flag is watched, so an increment that races the start or the end of
+// a rebuild is retried rather than lost or counted twice.
+func (l *Leaderboard) Incr(ctx context.Context, v LeaderboardVideo, delta int64) (int64, error) {
+	var total *redis.FloatCmd
+	apply := func(tx *redis.Tx) error {
+		rebuilding, err := tx.Exists(ctx, leaderboardRebuildingKey).Result()
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			total, err = incr(ctx, pipe, v, delta)
+			if err != nil {
+				return err
+			}
+			if rebuilding > 0 {
+				entry, err := json.Marshal(leaderboardJournalEntry{Video: v, Delta: delta})
+				if err != nil {
+					return err
+				}
+				pipe.RPush(ctx, leaderboardJournalKey, entry)
+			}
+			return nil
+		})
+		return err
+	}
+	for range leaderboardIncrRetries {
+		err := l.client.Watch(ctx, apply, leaderboardRebuildingKey)
+		if err == redis.TxFailedErr {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return int64(total.Val()), nil
+	}
+	return 0, redis.TxFailedErr
+}
+
+// incr queues the commands of Incr on pipe and returns the command holding
+// the owner's new total.
+func incr(ctx context.Context, pipe redis.Pipeliner, v LeaderboardVideo, delta int64) (*redis.FloatCmd, error) {
+	player, video, err := encodeMeta(v)
+	if err != nil {
+		return nil, err
+	}
+	playerKeys := boardKeys(repo.RankingByPlayer, v.TournamentID, v.City, v.Country)
+	videoKeys := boardKeys(repo.RankingByVideo, v.TournamentID, v.City, v.Country)
+	playerVideosKey := leaderboardPlayerVideosPrefix + v.UserID.String()
+	boards := append(append([]string{}, playerKeys...), videoKeys...)
+
+	pipe.HSet(ctx, leaderboardPlayersKey, v.UserID.String(), player)
+	pipe.HSet(ctx, leaderboardVideosKey, v.VideoID.String(), video)
+	pipe.SAdd(ctx, playerVideosKey, v.VideoID.String())
+	var total *redis.FloatCmd
+	for _, key := range playerKeys {
+		cmd := pipe.ZIncrBy(ctx, key, float64(delta), v.UserID.String())
+		if total == nil {
+			total = cmd
+		}
+	}
+	for _, key := range videoKeys {
+		pipe.ZIncrBy(ctx, key, float64(delta), v.VideoID.String())
+	}
+	if delta < 0 {
+		for _, key := range boards {
+			pipe.ZRemRangeByScore(ctx, key, "-inf", "0")
+		}
+	}
+	pipe.SAdd(ctx, leaderboardKeysKey, toAny(append(boards, playerVideosKey))...)
+	return total, nil
+}
+
+// Remove drops a player and all of their videos from every board, e.g. when
+// the account is deleted.
+func (l *Leaderboard) Remove(ctx context.Context, userID uuid.UUID) error {
+	member := userID.String()
+	playerVideosKey := leaderboardPlayerVideosPrefix + member
+
+	var p leaderboardPlayer
+	if raw, err := l.client.HGet(ctx, leaderboardPlayersKey, member).Result(); err == nil {
+		_ = json.Unmarshal([]byte(raw), &p)
+	} else if err != redis.Nil {
+		return err
+	}
+	videoIDs, err := l.client.SMembers(ctx, playerVideosKey).Result()
+	if err != nil {
+		return err
+	}
+	// The player also sits on the boards of every tournament they entered
+	tournaments := map[uuid.UUID]bool{}
+	if len(videoIDs) > 0 {
+		metas, err := l.client.HMGet(ctx, leaderboardVideosKey, videoIDs...).Result()
+		if err != nil {
+			return err
+		}
+		for _, m := range metas {
+			var v LeaderboardVideo
+			if raw, ok := m.(string); ok && json.Unmarshal([]byte(raw), &v) == nil && v.TournamentID != nil {
+				tournaments[*v.TournamentID] = true
+			}
+		}
+	}
+
+	pipe := l.client.TxPipeline()
+	for _, key := range filterKeys(repo.RankingByPlayer, nil, p.City, p.Country) {
+		pipe.ZRem(ctx, key, member)
+	}
+	for id := range tournaments {
+		for _, key := range filterKeys(repo.RankingByPlayer, &id, p.City, p.Country) {
+			pipe.ZRem(ctx, key, member)
+		}
+	}
+	if len(videoIDs) > 0 {
+		for _, key := range filterKeys(repo.RankingByVideo, nil, p.City, p.Country) {
+			pipe.ZRem(ctx, key, toAny(videoIDs)...)
+		}
+		for id := range tournaments {
+			for _, key := range filterKeys(repo.RankingByVideo, &id, p.City, p.Country) {
+				pipe.ZRem(ctx, key, toAny(videoIDs)...)
+			}
+		}
+		pipe.HDel(ctx, leaderboardVideosKey, videoIDs...)
+	}
+	pipe.HDel(ctx, leaderboardPlayersKey, member)
+	pipe.Del(ctx, playerVideosKey)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Ready reports whether the boards have been built from Postgres at least once.
+// Until then the sorted sets may be incomplete and must not be served.
+func (l *Leaderboard) Ready(ctx context.Context) bool {
+	n, err := l.client.Exists(ctx, leaderboardBuiltKey).Result()
+	return err == nil && n > 0
+}
+
+// Top returns one page of the board matching q. Positions follow RANK
+// semantics: tied entries share the position of the first of them.
+func (l *Leaderboard) Top(ctx context.Context, q repo.RankingQuery) ([]repo.RankingRow, error) {
+	if q.Limit <= 0 {
+		q.Limit = 50
+	}
+	key := boardKey(q.Scope, q.TournamentID, q.City, q.Country)
+
+	scores, err := l.page(ctx, key, q.After, int64(q.Limit))
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) == 0 {
+		return []repo.RankingRow{}, nil
+	}
+
+	members := make([]string, len(scores))
+	for i, z := range scores {
+		members[i] = z.Member.(string)
+	}
+
+	// Position = 1 + number of entries with a strictly higher score
+	pipe := l.client.Pipeline()
+	above := make(map[float64]*redis.IntCmd)
+	for _, z := range scores {
+		if _, ok := above[z.Score]; !ok {
+			above[z.Score] = pipe.ZCount(ctx, key, "("+formatScore(z.Score), "+inf")
+		}
+	}
+	var meta *redis.SliceCmd
+	videoSets := make([]*redis.StringSliceCmd, len(members))
+	if q.Scope == repo.RankingByVideo {
+		meta = pipe.HMGet(ctx, leaderboardVideosKey, members...)
+	} else {
+		meta = pipe.HMGet(ctx, leaderboardPlayersKey, members...)
+		for i, m := range members {
+			videoSets[i] = pipe.SMembers(ctx, leaderboardPlayerVideosPrefix+m)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	out := make([]repo.RankingRow, 0, len(scores))
+	for i, z := range scores {
+		row := repo.RankingRow{
+			Position: int(above[z.Score].Val()) + 1,
+			Votes:    int64(z.Score),
+		}
+		raw, _ := meta.Val()[i].(string)
+		if q.Scope == repo.RankingByVideo {
+			var v LeaderboardVideo
+			_ = json.Unmarshal([]byte(raw), &v)
+			id := v.VideoID
+			row.VideoID = &id
+			row.Title = v.Title
+			row.UserID, row.Username, row.City, row.Country = v.UserID, v.Username, v.City, v.Country
+			row.VideoIDs = []uuid.UUID{id}
+		} else {
+			var p leaderboardPlayer
+			_ = json.Unmarshal([]byte(raw), &p)
+			row.UserID, row.Username, row.City, row.Country = p.UserID, p.Username, p.City, p.Country
+			row.VideoIDs = parseIDs(videoSets[i].Val())
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+// page returns up to limit entries of key after the keyset cursor, in
+// ZREVRANGE order: votes descending, then member descending. If the cursor
+// entry has moved or left since the previous page was served, the page
+// continues from the (votes, member) position it had.
+func (l *Leaderboard) page(ctx context.Context, key string, after *repo.RankingCursor, limit int64) ([]redis.Z, error) {
+	if after == nil {
+		return l.client.ZRevRangeWithScores(ctx, key, 0, limit-1).Result()
+	}
+	member := after.ID.String()
+	pipe := l.client.TxPipeline()
+	rank := pipe.ZRevRank(ctx, key, member)
+	score := pipe.ZScore(ctx, key, member)
+	_, _ = pipe.Exec(ctx)
+
+	if rank.Err() == nil && score.Err() == nil && int64(score.Val()) == after.Votes {
+		return l.client.ZRevRangeWithScores(ctx, key, rank.Val()+1, rank.Val()+limit).Result()
+	}
+	if err := score.Err(); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	// The entries tied with the cursor that sort after it, then the ones
+	// with fewer votes
+	votes := strconv.FormatInt(after.Votes, 10)
+	tied, err := l.client.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{Min: votes, Max: votes}).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]redis.Z, 0, limit)
+	for _, z := range tied {
+		if z.Member.(string) < member {
+			out = append(out, z)
+			if int64(len(out)) == limit {
+				return out, nil
+			}
+		}
+	}
+	rest, err := l.client.ZRevRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   "(" + votes,
+		Count: limit - int64(len(out)),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	return append(out, rest...), nil
+}
+
+// BeginRebuild starts journaling increments. It must be called before the
+// tallies passed to Rebuild are read, so that votes recorded in between are
+// replayed on top of them.
+//
+// A vote committed right before the tallies are read whose increment lands
+// after BeginRebuild is counted twice until the next rebuild.
+func (l *Leaderboard) BeginRebuild(ctx context.Context) error {
+	pipe := l.client.TxPipeline()
+	pipe.Del(ctx, leaderboardJournalKey)
+	pipe.Set(ctx, leaderboardRebuildingKey, 1, leaderboardRebuildTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Rebuild replaces every board with the given tallies, plus the increments
+// journaled since BeginRebuild, in a single MULTI/EXEC, so readers never
+// observe a half-built leaderboard and no vote is lost. The transaction is
+// retried if a vote is journaled while it is being prepared.
+func (l *Leaderboard) Rebuild(ctx context.Context, entries []LeaderboardEntry) error {
+	for range leaderboardRebuildRetries {
+		err := l.client.Watch(ctx, func(tx *redis.Tx) error {
+			return l.rebuild(ctx, tx, entries)
+		}, leaderboardJournalKey)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return redis.TxFailedErr
+}
+
+func (l *Leaderboard) rebuild(ctx context.Context, tx *redis.Tx, entries []LeaderboardEntry) error {
+	oldKeys, err := tx.SMembers(ctx, leaderboardKeysKey).Result()
+	if err != nil {
+		return err
+	}
+	journal, err := tx.LRange(ctx, leaderboardJournalKey, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+
+	pipe := tx.TxPipeline()
+	pipe.Del(ctx, append(oldKeys, leaderboardKeysKey, leaderboardPlayersKey, leaderboardVideosKey)...)
+
+	// Player totals per tournament; uuid.Nil holds the platform-wide totals
+	type playerBoard struct{ tournament, user uuid.UUID }
+	totals := make(map[playerBoard]int64)
+	owners := make(map[uuid.UUID]LeaderboardVideo)
+	for _, e := range entries {
+		if e.Votes <= 0 {
+			continue
+		}
+		v := e.Video
+		player, video, err := encodeMeta(v)
+		if err != nil {
+			return err
+		}
+		playerVideosKey := leaderboardPlayerVideosPrefix + v.UserID.String()
+		videoKeys := boardKeys(repo.RankingByVideo, v.TournamentID, v.City, v.Country)
+
+		pipe.HSet(ctx, leaderboardPlayersKey, v.UserID.String(), player)
+		pipe.HSet(ctx, leaderboardVideosKey, v.VideoID.String(), video)
+		pipe.SAdd(ctx, playerVideosKey, v.VideoID.String())
+		for _, key := range videoKeys {
+			pipe.ZAdd(ctx, key, redis.Z{Score: float64(e.Votes), Member: v.VideoID.String()})
+		}
+		pipe.SAdd(ctx, leaderboardKeysKey, toAny(append(videoKeys, playerVideosKey))...)
+
+		totals[playerBoard{user: v.UserID}] += e.Votes
+		if v.TournamentID != nil {
+			totals[playerBoard{tournament: *v.TournamentID, user: v.UserID}] += e.Votes
+		}
+		owners[v.UserID] = v
+	}
+	for b, total := range totals {
+		v := owners[b.user]
+		var tournamentID *uuid.UUID
+		if b.tournament != uuid.Nil {
+			tournamentID = &b.tournament
+		}
+		playerKeys := filterKeys(repo.RankingByPlayer, tournamentID, v.City, v.Country)
+		for _, key := range playerKeys {
+			pipe.ZAdd(ctx, key, redis.Z{Score: float64(total), Member: b.user.String()})
+		}
+		pipe.SAdd(ctx, leaderboardKeysKey, toAny(playerKeys)...)
+	}
+
+	// Votes recorded since the tallies were read
+	for _, raw := range journal {
+		var e leaderboardJournalEntry
+		if err := json.Unmarshal([]byte(raw), &e); err != nil {
+			continue
+		}
+		if _, err := incr(ctx, pipe, e.Video, e.Delta); err != nil {
+			return err
+		}
+	}
+	pipe.Del(ctx, leaderboardJournalKey, leaderboardRebuildingKey)
+	pipe.Set(ctx, leaderboardBuiltKey, time.Now().UTC().Format(time.RFC3339), 0)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// TryLock acquires a short-lived lock shared by all API replicas, so that
+// periodic jobs such as reconciliation run on a single instance at a time.
+func (l *Leaderboard) TryLock(ctx context.Context, name string, ttl time.Duration) bool {
+	ok, err := l.client.SetNX(ctx, "lock:"+name, 1, ttl).Result()
+	return err == nil && ok
+}
+
+func boardKey(scope repo.RankingScope, tournamentID *uuid.UUID, city, country string) string {
+	if scope == "" {
+		scope = repo.RankingByPlayer
+	}
+	key := leaderboardPrefix + string(scope)
+	if tournamentID != nil {
+		key += ":tournament:" + tournamentID.String()
+	}
+	if country != "" {
+		key += ":country:" + country
+	}
+	if city != "" {
+		key += ":city:" + city
+	}
+	if country == "" && city == "" {
+		key += ":all"
+	}
+	return key
+}
+
+// boardKeys returns every board an entry from city/country contributes to:
+// the platform-wide ones and, for tournament entries, the tournament's ones.
+func boardKeys(scope repo.RankingScope, tournamentID *uuid.UUID, city, country string) []string {
+	keys := filterKeys(scope, nil, city, country)
+	if tournamentID != nil {
+		keys = append(keys, filterKeys(scope, tournamentID, city, country)...)
+	}
+	return keys
+}
+
+func filterKeys(scope repo.RankingScope, tournamentID *uuid.UUID, city, country string) []string {
+	keys := []string{boardKey(scope, tournamentID, "", "")}
+	if city != "" {
+		keys = append(keys, boardKey(scope, tournamentID, city, ""))
+	}
+	if country != "" {
+		keys = append(keys, boardKey(scope, tournamentID, "", country))
+	}
+	if city != "" && country != "" {
+		keys = append(keys, boardKey(scope, tournamentID, city, country))
+	}
+	return keys
+}
+
+func encodeMeta(v LeaderboardVideo) (player, video []byte, err error) {
+	player, err = json.Marshal(leaderboardPlayer{UserID: v.UserID, Username: v.Username, City: v.City, Country: v.Country})
+	if err != nil {
+		return nil, nil, err
+	}
+	video, err = json.Marshal(v)
+	return player, video, err
+}
+
+func formatScore(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func parseIDs(ss []string) []uuid.UUID {
+	out := make([]uuid.UUID, 0, len(ss))
+	for _, s := range ss {
+		if id, err := uuid.Parse(s); err == nil {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func toAny(ss []string) []any {
+	out := make([]any, len(ss))
+	for i, s := range ss {
+		out[i] = s
+	}
+	return out
+}
@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/redis/go-redis/v9"
-	"github.com/google/uuid"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/metrics"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
 )
 
 type RankingsCache struct {
@@ -15,11 +17,6 @@ type RankingsCache struct {
 	ttl    time.Duration
 }
 
-type RankingResult struct {
-	VideoID uuid.UUID `json:"video_id"`
-	Votes   int64     `json:"votes"`
-}
-
 func NewRankingsCache(client *redis.Client, ttl time.Duration) *RankingsCache {
 	return &RankingsCache{
 		client: client,
@@ -27,24 +24,27 @@ func NewRankingsCache(client *redis.Client, ttl time.Duration) *RankingsCache {
 	}
 }
 
-func (c *RankingsCache) GetRankings(ctx context.Context, limit int, city string) ([]RankingResult, bool) {
-	key := c.buildKey(limit, city)
+func (c *RankingsCache) GetRankings(ctx context.Context, q repo.RankingQuery) ([]repo.RankingRow, bool) {
+	key := c.buildKey(q)
 	
 	val, err := c.client.Get(ctx, key).Result()
 	if err != nil {
+		metrics.CacheRequests.WithLabelValues("rankings", "miss").Inc()
 		return nil, false
 	}
 	
-	var rankings []RankingResult
+	var rankings []repo.RankingRow
 	if err := json.Unmarshal([]byte(val), &rankings); err != nil {
+		metrics.CacheRequests.WithLabelValues("rankings", "miss").Inc()
 		return nil, false
 	}
 	
+	metrics.CacheRequests.WithLabelValues("rankings", "hit").Inc()
 	return rankings, true
 }
 
-func (c *RankingsCache) SetRankings(ctx context.Context, limit int, city string, rankings []RankingResult) error {
-	key := c.buildKey(limit, city)
+func (c *RankingsCache) SetRankings(ctx context.Context, q repo.RankingQuery, rankings []repo.RankingRow) error {
+	key := c.buildKey(q)
 	
 	data, err := json.Marshal(rankings)
 	if err != nil {
@@ -68,9 +68,25 @@ func (c *RankingsCache) InvalidateAll(ctx context.Context) error {
 	return nil
 }
 
-func (c *RankingsCache) buildKey(limit int, city string) string {
-	if city == "" {
-		return fmt.Sprintf("rankings:limit:%d", limit)
+func (c *RankingsCache) buildKey(q repo.RankingQuery) string {
+	key := fmt.Sprintf("rankings:%s:limit:%d", q.Scope, q.Limit)
+	if q.TournamentID != nil {
+		key += ":tournament:" + q.TournamentID.String()
+	}
+	if q.City != "" {
+		key += ":city:" + q.City
+	}
+	if q.Country != "" {
+		key += ":country:" + q.Country
+	}
+	if q.From != nil {
+		key += ":from:" + q.From.UTC().Format(time.RFC3339)
+	}
+	if q.To != nil {
+		key += ":to:" + q.To.UTC().Format(time.RFC3339)
+	}
+	if q.After != nil {
+		key += ":after:" + q.After.Encode()
 	}
-	return fmt.Sprintf("rankings:limit:%d:city:%s", limit, city)
+	return key
 }
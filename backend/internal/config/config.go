@@ -1,9 +1,11 @@
 package config
 
 import (
-	"log"
+	"log/slog"
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -13,11 +15,66 @@ type Config struct {
 
 	// DB
 	PostgresURL string
+	// Réplica de solo lectura para los listados públicos (vacío = usar la primaria)
+	PostgresReplicaURL string
+	// Pool de conexiones: límites y tiempos de vida en minutos
+	DBMaxOpenConns           int
+	DBMaxIdleConns           int
+	DBConnMaxLifetimeMinutes int
+	DBConnMaxIdleMinutes     int
+	// Tiempo máximo por sentencia en milisegundos (0 = sin límite)
+	DBStatementTimeoutMs int
 	// Redis
 	RedisAddr     string
 	RedisPassword string
 	// Kafka
 	KafkaBrokers []string
+	// Cola de tareas de video: "kafka" o "redis" (Redis Streams)
+	QueueBackend string
+	// Redis Streams: inactividad tras la cual otro worker reclama una tarea pendiente (s)
+	QueueClaimIdleSeconds int
+	// Cola: tareas pendientes por usuario en el carril de subidas; las siguientes van al carril bulk
+	QueueFairShare int
+	// Leaderboard: intervalo de reconciliación con Postgres (0 = deshabilitado)
+	LeaderboardReconcileMinutes int
+	// Rankings: intervalo entre snapshots históricos (0 = deshabilitado)
+	RankingSnapshotMinutes int
+	// Fraude: puntaje a partir del cual un voto queda en cuarentena y límites de ráfaga
+	FraudQuarantineScore   int
+	FraudMaxVotesPerIP     int
+	FraudMaxVotesPerSubnet int
+	// Moderación: reportes abiertos que devuelven un video a revisión (0 = nunca)
+	ModerationReportThreshold int
+	// Finalistas: cupos por ciudad por defecto
+	FinalistSlotsPerCity int
+	// Votos: máximo de votos por usuario por ciudad (0 = sin límite)
+	VoteQuotaPerCity int
+	// Logs: nivel (debug, info, warn, error) y formato (json o text)
+	LogLevel  string
+	LogFormat string
+	// Trazas: exportador de OpenTelemetry ("otlp", "stdout" o "none")
+	TracingExporter string
+	// Worker: dirección donde expone /metrics, /livez y /readyz (vacío = deshabilitado)
+	WorkerMetricsAddr string
+	// API: dirección interna donde expone /metrics, fuera del router público
+	// (vacío = deshabilitado)
+	APIMetricsAddr string
+	// Salud: tiempo máximo de cada verificación de /readyz (ms)
+	HealthCheckTimeoutMs int
+	// Apagado: tiempo para drenar las peticiones HTTP en curso (s)
+	ShutdownTimeoutSeconds int
+	// Apagado: tiempo entre que /readyz responde 503 y el cierre del listener,
+	// para que el balanceador deje de enviar conexiones (s)
+	ShutdownDrainDelaySeconds int
+	// Worker: videos procesados en paralelo. Por defecto la mitad de las CPU:
+	// libx264 ya usa varios hilos por video
+	WorkerConcurrency int
+	// Worker: tiempo máximo de cada paso de ffmpeg (s; 0 = sin límite)
+	FFmpegStepTimeoutSeconds int
+	// Worker: tiempo que la tarea en curso puede seguir tras SIGTERM antes de abortarla (s)
+	WorkerDrainSeconds int
+	// Proxies de confianza para resolver la IP real del cliente (separados por coma)
+	TrustedProxies []string
 }
 
 func atoiEnv(k string, def int) int {
@@ -48,7 +105,7 @@ func Load() *Config {
 
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
-		log.Println("⚠️  JWT_SECRET no definido (usa uno seguro en prod)")
+		slog.Warn("JWT_SECRET no definido (usa uno seguro en prod)")
 	}
 
 	brokers := os.Getenv("KAFKA_BROKERS")
@@ -67,7 +124,50 @@ func Load() *Config {
 		RedisAddr:        getenv("REDIS_ADDR", "localhost:6379"),
 		RedisPassword:    os.Getenv("REDIS_PASSWORD"),
 		KafkaBrokers:     kafkaBrokers,
+
+		QueueBackend:          getenv("QUEUE_BACKEND", "kafka"),
+		QueueClaimIdleSeconds: atoiEnv("QUEUE_CLAIM_IDLE_SECONDS", 60),
+		QueueFairShare:        atoiEnv("QUEUE_FAIR_SHARE", 2),
+
+		PostgresReplicaURL:       os.Getenv("DATABASE_REPLICA_URL"),
+		DBMaxOpenConns:           atoiEnv("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:           atoiEnv("DB_MAX_IDLE_CONNS", 10),
+		DBConnMaxLifetimeMinutes: atoiEnv("DB_CONN_MAX_LIFETIME_MINUTES", 30),
+		DBConnMaxIdleMinutes:     atoiEnv("DB_CONN_MAX_IDLE_MINUTES", 5),
+		DBStatementTimeoutMs:     atoiEnv("DB_STATEMENT_TIMEOUT_MS", 5000),
+
+		LeaderboardReconcileMinutes: atoiEnv("LEADERBOARD_RECONCILE_MINUTES", 10),
+		RankingSnapshotMinutes:      atoiEnv("RANKING_SNAPSHOT_MINUTES", 60),
+
+		FraudQuarantineScore:      atoiEnv("FRAUD_QUARANTINE_SCORE", 50),
+		FraudMaxVotesPerIP:        atoiEnv("FRAUD_MAX_VOTES_PER_IP", 5),
+		FraudMaxVotesPerSubnet:    atoiEnv("FRAUD_MAX_VOTES_PER_SUBNET", 20),
+		ModerationReportThreshold: atoiEnv("MODERATION_REPORT_THRESHOLD", 3),
+		FinalistSlotsPerCity:      atoiEnv("FINALIST_SLOTS_PER_CITY", 3),
+		VoteQuotaPerCity:          atoiEnv("VOTE_QUOTA_PER_CITY", 0),
+		TrustedProxies:            splitList(os.Getenv("TRUSTED_PROXIES")),
+		WorkerMetricsAddr:         getenv("WORKER_METRICS_ADDR", ":9091"),
+		APIMetricsAddr:            getenv("API_METRICS_ADDR", ":9090"),
+		HealthCheckTimeoutMs:      atoiEnv("HEALTH_CHECK_TIMEOUT_MS", 2000),
+		ShutdownTimeoutSeconds:    atoiEnv("SHUTDOWN_TIMEOUT_SECONDS", 20),
+		ShutdownDrainDelaySeconds: atoiEnv("SHUTDOWN_DRAIN_DELAY_SECONDS", 5),
+		WorkerDrainSeconds:        atoiEnv("WORKER_DRAIN_SECONDS", 25),
+		FFmpegStepTimeoutSeconds:  atoiEnv("FFMPEG_STEP_TIMEOUT_SECONDS", 300),
+		WorkerConcurrency:         atoiEnv("WORKER_CONCURRENCY", max(1, runtime.NumCPU()/2)),
+		TracingExporter:           getenv("OTEL_TRACES_EXPORTER", "none"),
+		LogLevel:                  getenv("LOG_LEVEL", "info"),
+		LogFormat:                 getenv("LOG_FORMAT", "json"),
+	}
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
 	}
+	return out
 }
 
 func getenv(k, def string) string {
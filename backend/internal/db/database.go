@@ -1,42 +1,109 @@
 package db
 
 import (
-	"fmt"
-	"log"
-	"os"
+	"context"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	otelgorm "gorm.io/plugin/opentelemetry/tracing"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/config"
+	"github.com/Cloud-2025-2/anb-platform/internal/logging"
+	"github.com/Cloud-2025-2/anb-platform/internal/metrics"
+	"github.com/Cloud-2025-2/anb-platform/internal/migrations"
 )
 
-var DB *gorm.DB
+var (
+	DB *gorm.DB
+	// Replica atiende las lecturas pesadas de los endpoints públicos. Sin
+	// DATABASE_REPLICA_URL apunta a la misma conexión que DB.
+	Replica *gorm.DB
+)
 
-func Connect() {
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		host := getEnv("POSTGRES_HOST", "localhost")
-		port := getEnv("POSTGRES_PORT", "5432")
-		user := getEnv("POSTGRES_USER", "proyecto1")
-		pass := getEnv("POSTGRES_PASSWORD", "proyecto1")
-		name := getEnv("POSTGRES_DB", "db")
+// Connect abre la primaria (y la réplica, si está configurada) con los
+// límites de pool y el statement_timeout de cfg, y exporta las estadísticas
+// de cada pool en /metrics.
+func Connect(cfg *config.Config) {
+	var err error
+	DB, err = open(cfg, "primary", cfg.PostgresURL)
+	if err != nil {
+		logging.Fatal("Error al conectar a la base de datos", "error", err)
+	}
+	slog.Info("Conexión exitosa a la Base de Datos")
 
-		dsn = fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-			host, user, pass, name, port)
+	Replica = DB
+	if cfg.PostgresReplicaURL != "" {
+		Replica, err = open(cfg, "replica", cfg.PostgresReplicaURL)
+		if err != nil {
+			logging.Fatal("Error al conectar a la réplica de lectura", "error", err)
+		}
+		slog.Info("Conexión exitosa a la réplica de lectura")
 	}
+}
 
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+func open(cfg *config.Config, name, dsn string) (*gorm.DB, error) {
+	gdb, err := gorm.Open(postgres.Open(withStatementTimeout(dsn, cfg.DBStatementTimeoutMs)), &gorm.Config{})
 	if err != nil {
-		log.Fatal("Error al conectar a la base de datos:", err)
+		return nil, err
 	}
+	// Cada consulta queda como span hijo del contexto que recibe WithContext
+	if err := gdb.Use(otelgorm.NewPlugin(otelgorm.WithoutMetrics(), otelgorm.WithDBName(name))); err != nil {
+		return nil, err
+	}
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	sqlDB.SetConnMaxLifetime(time.Duration(cfg.DBConnMaxLifetimeMinutes) * time.Minute)
+	sqlDB.SetConnMaxIdleTime(time.Duration(cfg.DBConnMaxIdleMinutes) * time.Minute)
+	metrics.RegisterDB(name, sqlDB)
+	return gdb, nil
+}
 
-	log.Println("Conexión exitosa a la Base de Datos")
+// withStatementTimeout agrega statement_timeout como parámetro de arranque,
+// tanto a DSN en formato URL como en formato "clave=valor".
+func withStatementTimeout(dsn string, ms int) string {
+	if ms <= 0 || strings.Contains(dsn, "statement_timeout") {
+		return dsn
+	}
+	timeout := strconv.Itoa(ms)
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		q := u.Query()
+		q.Set("statement_timeout", timeout)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " statement_timeout=" + timeout
 }
 
-func getEnv(key, def string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// RequireSchema verifica que la base de datos tenga aplicadas todas las
+// migraciones; los servicios no migran al arrancar (ver cmd/migrate).
+func RequireSchema() error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
 	}
-	return def
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return migrations.RequireCurrent(ctx, sqlDB)
 }
 
+// Ping verifica que gdb (DB o Replica) acepte conexiones; lo usa /readyz.
+func Ping(ctx context.Context, gdb *gorm.DB) error {
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SelectionStatus string
+
+const (
+	SelectionDraft  SelectionStatus = "draft"  // se puede recalcular y modificar
+	SelectionFrozen SelectionStatus = "frozen" // resultados publicados, inmutables
+)
+
+type FinalistDecisionKind string
+
+const (
+	FinalistSelected  FinalistDecisionKind = "selected"
+	FinalistAlternate FinalistDecisionKind = "alternate"
+	FinalistExcluded  FinalistDecisionKind = "excluded"
+)
+
+type DecisionSource string
+
+const (
+	DecisionFromRanking DecisionSource = "ranking" // calculada a partir de los votos
+	DecisionFromJury    DecisionSource = "jury"    // decisión manual del jurado
+)
+
+// FinalistSelection is the showcase roster chosen from the rankings once
+// voting closes: the top SlotsPerCity players of each city, adjusted by the
+// jury. Once frozen it is published and can no longer change.
+type FinalistSelection struct {
+	ID           uuid.UUID       `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	TournamentID *uuid.UUID      `gorm:"type:uuid;index"` // nil = toda la plataforma
+	SlotsPerCity int             `gorm:"not null"`
+	Status       SelectionStatus `gorm:"type:text;index;not null;default:draft"`
+	ComputedAt   time.Time       `gorm:"not null"`
+	FrozenAt     *time.Time
+	FrozenBy     *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt    time.Time
+
+	Decisions []FinalistDecision `gorm:"foreignKey:SelectionID;constraint:OnDelete:CASCADE"`
+}
+
+// FinalistDecision records whether one player makes the roster, and why.
+// Player details are copied so the published results do not change if the
+// player edits their profile.
+type FinalistDecision struct {
+	ID          uuid.UUID            `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	SelectionID uuid.UUID            `gorm:"type:uuid;uniqueIndex:idx_selection_player;not null"`
+	UserID      uuid.UUID            `gorm:"type:uuid;uniqueIndex:idx_selection_player;not null"`
+	Username    string               `gorm:"not null"`
+	City        string               `gorm:"index;not null"`
+	Country     string               `gorm:"not null"`
+	Position    int                  // puesto en el ranking de su ciudad (0 = fuera del ranking)
+	Votes       int64                `gorm:"not null;default:0"`
+	Decision    FinalistDecisionKind `gorm:"type:text;not null"`
+	Source      DecisionSource       `gorm:"type:text;not null"`
+	Reason      string               `gorm:"not null"`
+	DecidedBy   *uuid.UUID           `gorm:"type:uuid"` // nil para decisiones calculadas
+	DecidedAt   time.Time            `gorm:"not null"`
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RankingSnapshot is a copy of the rankings taken at a point in time, used for
+// position movement and "rank over time" charts. Final snapshots freeze the
+// standings when voting closes.
+type RankingSnapshot struct {
+	ID      uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	TakenAt time.Time `gorm:"index;not null"`
+	Final   bool      `gorm:"default:false;index"`
+
+	Entries []RankingSnapshotEntry `gorm:"foreignKey:SnapshotID;constraint:OnDelete:CASCADE"`
+}
+
+// RankingSnapshotEntry is one row of one board. A board is a scope plus the
+// tournament (nil = whole platform) and city/country filter ("" = no filter)
+// it was computed with. EntryID is the
+// player ID or the video ID depending on Scope.
+type RankingSnapshotEntry struct {
+	ID                uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	SnapshotID        uuid.UUID  `gorm:"type:uuid;index:idx_snapshot_board;not null"`
+	Scope             string     `gorm:"type:text;index:idx_snapshot_board;not null"`
+	BoardTournamentID *uuid.UUID `gorm:"type:uuid;index:idx_snapshot_board"`
+	BoardCity         string     `gorm:"index:idx_snapshot_board"`
+	BoardCountry      string     `gorm:"index:idx_snapshot_board"`
+	EntryID           uuid.UUID  `gorm:"type:uuid;index;not null"`
+	UserID            uuid.UUID  `gorm:"type:uuid;not null"`
+	VideoID           *uuid.UUID `gorm:"type:uuid"`
+	Username          string
+	City              string
+	Country           string
+	Title             string
+	Position          int   `gorm:"not null"`
+	Votes             int64 `gorm:"not null"`
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"
+	ReportUpheld    ReportStatus = "upheld"    // el video fue rechazado
+	ReportDismissed ReportStatus = "dismissed" // el video fue aprobado
+)
+
+// VideoReport is a user's complaint about an inappropriate video. Reports
+// are resolved when a moderator approves or rejects the video.
+type VideoReport struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	VideoID    uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_report_video_reporter;not null"`
+	Video      Video     `gorm:"foreignKey:VideoID;references:ID;constraint:OnDelete:CASCADE"`
+	ReporterID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_report_video_reporter;not null"`
+	Reporter   User      `gorm:"foreignKey:ReporterID;references:ID;constraint:OnDelete:CASCADE"`
+	Reason     string    `gorm:"not null"`
+	Details    string
+	Status     ReportStatus `gorm:"type:text;index;not null;default:open"`
+	CreatedAt  time.Time
+	ResolvedBy *uuid.UUID `gorm:"type:uuid"`
+	ResolvedAt *time.Time
+}
@@ -17,15 +17,54 @@ const (
 	TaskDead      TaskStatus = "dead"
 )
 
+// TaskTypeVideoProcess is the task that runs the ffmpeg pipeline on a video.
+const TaskTypeVideoProcess = "video:process"
+
+// TaskLane is the priority lane of a task. When every worker slot is busy,
+// the next free one goes to the lane that comes first in TaskLanes.
+type TaskLane string
+
+const (
+	// LaneUpload holds uploads of users with few tasks pending
+	LaneUpload TaskLane = "upload"
+	// LaneReprocess holds retries and reprocessing asked by the owner
+	LaneReprocess TaskLane = "reprocess"
+	// LaneBulk holds the uploads of a user who already has their fair share
+	// of tasks pending, so one uploader can't hold back everyone else
+	LaneBulk TaskLane = "bulk"
+)
+
+// TaskLanes lists the lanes from highest to lowest priority.
+var TaskLanes = []TaskLane{LaneUpload, LaneReprocess, LaneBulk}
+
+// Priority is the position of the lane in TaskLanes (0 runs first). Tasks
+// without a lane, published before lanes existed, rank as uploads.
+func (l TaskLane) Priority() int {
+	for i, lane := range TaskLanes {
+		if lane == l {
+			return i
+		}
+	}
+	return 0
+}
+
+// Ahead returns the lanes whose tasks run before those of l.
+func (l TaskLane) Ahead() []TaskLane {
+	return TaskLanes[:l.Priority()]
+}
+
 type ProcessingTask struct {
 	ID          uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
 	VideoID     uuid.UUID  `gorm:"type:uuid;index;not null"`
 	Video       Video      `gorm:"constraint:OnDelete:CASCADE"`
 	TaskType    string     `gorm:"not null"` // p.ej. "video:process"
 	Status      TaskStatus `gorm:"type:text;not null;default:queued"`
+	Lane        TaskLane   `gorm:"type:text;not null;default:upload"`
 	Attempts    int        `gorm:"not null;default:0"`
 	MaxAttempts int        `gorm:"not null;default:5"`
 	LastError   *string
+	Step        string     `gorm:"not null;default:''"` // paso del pipeline en curso
+	Progress    int        `gorm:"not null;default:0"`  // porcentaje completado (0-100)
 	EnqueuedAt  time.Time  `gorm:"autoCreateTime"`
 	StartedAt   *time.Time
 	FinishedAt  *time.Time
@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TournamentPhase string
+
+const (
+	TournamentUpcoming     TournamentPhase = "upcoming"     // aún no abre inscripciones
+	TournamentRegistration TournamentPhase = "registration" // jugadores inscriben videos
+	TournamentVoting       TournamentPhase = "voting"       // el público vota
+	TournamentClosed       TournamentPhase = "closed"       // votación cerrada
+)
+
+// Tournament is a competition videos are submitted to. Its phases follow one
+// another: registration, then voting, then closed. Voting may start before
+// registration ends.
+type Tournament struct {
+	ID                   uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Name                 string    `gorm:"not null"`
+	Description          string
+	RegistrationOpensAt  time.Time  `gorm:"not null"`
+	RegistrationClosesAt time.Time  `gorm:"not null"`
+	VotingOpensAt        time.Time  `gorm:"not null;index"`
+	VotingClosesAt       time.Time  `gorm:"not null;index"`
+	EligibleCities       StringList `gorm:"type:jsonb;not null;default:'[]'"` // vacío = todas
+	EligibleCountries    StringList `gorm:"type:jsonb;not null;default:'[]'"` // vacío = todos
+	MaxEntriesPerPlayer  int        `gorm:"not null;default:1"`
+	VotesPerUser         int        `gorm:"not null;default:0"` // 0 = sin límite
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+// Phase returns the phase the tournament is in at now.
+func (t *Tournament) Phase(now time.Time) TournamentPhase {
+	switch {
+	case !now.Before(t.VotingClosesAt):
+		return TournamentClosed
+	case !now.Before(t.VotingOpensAt):
+		return TournamentVoting
+	case !now.Before(t.RegistrationOpensAt):
+		return TournamentRegistration
+	default:
+		return TournamentUpcoming
+	}
+}
+
+// AcceptsEntries reports whether videos can be submitted at now.
+func (t *Tournament) AcceptsEntries(now time.Time) bool {
+	return !now.Before(t.RegistrationOpensAt) && now.Before(t.RegistrationClosesAt)
+}
+
+// VotingOpen reports whether votes are accepted at now.
+func (t *Tournament) VotingOpen(now time.Time) bool {
+	return t.Phase(now) == TournamentVoting
+}
+
+// Eligible reports whether a player from city/country may take part.
+func (t *Tournament) Eligible(city, country string) bool {
+	return t.EligibleCities.ContainsFold(city) && t.EligibleCountries.ContainsFold(country)
+}
+
+// Validate checks the tournament is well formed.
+func (t *Tournament) Validate() error {
+	switch {
+	case strings.TrimSpace(t.Name) == "":
+		return errors.New("name is required")
+	case !t.RegistrationOpensAt.Before(t.RegistrationClosesAt):
+		return errors.New("registration must open before it closes")
+	case t.VotingOpensAt.Before(t.RegistrationOpensAt):
+		return errors.New("voting cannot open before registration")
+	case !t.VotingOpensAt.Before(t.VotingClosesAt):
+		return errors.New("voting must open before it closes")
+	case t.VotingClosesAt.Before(t.RegistrationClosesAt):
+		return errors.New("voting cannot close before registration")
+	case t.MaxEntriesPerPlayer <= 0:
+		return errors.New("max entries per player must be positive")
+	case t.VotesPerUser < 0:
+		return errors.New("votes per user cannot be negative")
+	}
+	return nil
+}
+
+// StringList is a list of strings stored as a JSON array.
+type StringList []string
+
+// ContainsFold reports whether s is in the list, ignoring case. An empty list
+// contains everything.
+func (l StringList) ContainsFold(s string) bool {
+	if len(l) == 0 {
+		return true
+	}
+	for _, v := range l {
+		if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]string(l))
+	return string(b), err
+}
+
+func (l *StringList) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(l))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(l))
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", src)
+	}
+}
@@ -20,7 +20,7 @@ type User struct {
 	FirstName    string    `gorm:"not null"`
 	LastName     string    `gorm:"not null"`
 	Email        string    `gorm:"uniqueIndex;not null"`
-	PasswordHash string    `gorm:"not null"` // solo 1 hash, password2 no se almacena
+	PasswordHash string    `gorm:"not null" json:"-"` // solo 1 hash, password2 no se almacena
 	City         string    `gorm:"not null"`
 	Country      string    `gorm:"not null"`
 	Role         Role      `gorm:"type:text;not null;default:player"`
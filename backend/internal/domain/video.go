@@ -6,7 +6,6 @@ import (
 	"github.com/google/uuid"
 )
 
-
 type VideoStatus string
 
 const (
@@ -15,15 +14,21 @@ const (
 	VideoProcessed  VideoStatus = "processed"
 	VideoPublished  VideoStatus = "published"
 	VideoFailed     VideoStatus = "failed"
+
+	// Moderación: tras procesarse, el video espera revisión antes de publicarse
+	VideoPendingReview     VideoStatus = "pending_review"
+	VideoRejected          VideoStatus = "rejected"
+	VideoReuploadRequested VideoStatus = "reupload_requested"
 )
 
 type Video struct {
-	ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	ID              uuid.UUID   `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
 	UserID          uuid.UUID   `gorm:"type:uuid;index;not null"`
 	User            User        `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
 	Title           string      `gorm:"not null"`
-	OriginalURL     string      `gorm:"not null"`     // ruta/URL del archivo subido
-	ProcessedURL    *string                              // se llena cuando termina worker
+	Description     string
+	OriginalURL     string      `gorm:"not null"` // ruta/URL del archivo subido
+	ProcessedURL    *string     // se llena cuando termina worker
 	Status          VideoStatus `gorm:"type:text;index;not null;default:uploaded"`
 	UploadedAt      time.Time   `gorm:"autoCreateTime"`
 	ProcessedAt     *time.Time
@@ -36,10 +41,24 @@ type Video struct {
 	HeightProc      *int
 	AspectProc      *string // "16:9"
 	HasAudioOrig    *bool
-	Watermark       bool      `gorm:"default:false"`
-	IsPublicForVote bool      `gorm:"default:false;index"`
-	CitySnapshot    string    // copia de city del usuario para ranking por ciudad
+	Watermark       bool        `gorm:"default:false"`
+	Profile         string      `gorm:"type:text;not null;default:720p"` // perfil de procesamiento (720p, 1080p, 480p)
+	IsPublicForVote bool        `gorm:"default:false;index"`
+	CitySnapshot    string      // copia de city del usuario para ranking por ciudad
+	TournamentID    *uuid.UUID  `gorm:"type:uuid;index"` // torneo al que se inscribió el video
+	Tournament      *Tournament `gorm:"foreignKey:TournamentID;references:ID;constraint:OnDelete:SET NULL"`
 	ChecksumSHA256  *string
 
+	// Última decisión de moderación
+	ModerationReason *string
+	ModeratedBy      *uuid.UUID `gorm:"type:uuid"`
+	ModeratedAt      *time.Time
+
 	Votes []Vote `gorm:"foreignKey:VideoID"`
-}
\ No newline at end of file
+
+	VotedByMe bool `gorm:"-" json:"voted_by_me"` // solo en el listado público con token
+
+	// Calculados en SQL por el listado público; no son columnas
+	VoteCount     int64 `gorm:"->;-:migration" json:"vote_count"`
+	TrendingVotes int64 `gorm:"->;-:migration" json:"trending_votes"`
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	VideoUnpublished VideoStatus = "unpublished" // retirado de la votación por su dueño o un admin
+	VideoArchived    VideoStatus = "archived"    // estado final, solo para historial
+)
+
+// videoTransitions lists the legal status changes of a video.
+var videoTransitions = map[VideoStatus][]VideoStatus{
+	VideoUploaded:          {VideoProcessing, VideoFailed, VideoArchived}, // failed = no se pudo encolar
+	VideoProcessing:        {VideoProcessed, VideoFailed},
+	VideoProcessed:         {VideoPendingReview},
+	VideoPendingReview:     {VideoPublished, VideoRejected, VideoReuploadRequested, VideoUploaded, VideoArchived},
+	VideoPublished:         {VideoPendingReview, VideoUnpublished, VideoUploaded, VideoArchived}, // uploaded = reprocesar
+	VideoUnpublished:       {VideoPendingReview, VideoUploaded, VideoArchived},
+	VideoFailed:            {VideoUploaded, VideoProcessing, VideoArchived}, // reintento manual o automático
+	VideoRejected:          {VideoArchived},
+	VideoReuploadRequested: {VideoUploaded, VideoArchived},
+	VideoArchived:          {},
+}
+
+// ErrIllegalTransition is returned for status changes the lifecycle forbids.
+type ErrIllegalTransition struct {
+	From, To VideoStatus
+}
+
+func (e ErrIllegalTransition) Error() string {
+	return fmt.Sprintf("illegal video status transition from %q to %q", e.From, e.To)
+}
+
+// CanTransition reports whether a video may go from one status to another.
+func CanTransition(from, to VideoStatus) bool {
+	for _, s := range videoTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStatuses returns the statuses a video in status s may move to.
+func NextStatuses(s VideoStatus) []VideoStatus {
+	return append([]VideoStatus(nil), videoTransitions[s]...)
+}
+
+// VideoStatusEvent records one status change of a video. From is empty for
+// the initial upload. ActorID is nil for changes made by the system.
+type VideoStatusEvent struct {
+	ID        uuid.UUID   `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	VideoID   uuid.UUID   `gorm:"type:uuid;index;not null" json:"video_id"`
+	Video     *Video      `gorm:"foreignKey:VideoID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+	From      VideoStatus `gorm:"type:text" json:"from,omitempty"`
+	To        VideoStatus `gorm:"type:text;not null" json:"to"`
+	Reason    string      `json:"reason,omitempty"`
+	ActorID   *uuid.UUID  `gorm:"type:uuid" json:"actor_id,omitempty"`
+	CreatedAt time.Time   `gorm:"index" json:"at"`
+}
@@ -6,12 +6,31 @@ import (
 	"github.com/google/uuid"
 )
 
+type VoteStatus string
+
+const (
+	VoteCounted     VoteStatus = "counted"     // cuenta para el ranking
+	VoteQuarantined VoteStatus = "quarantined" // sospechoso, pendiente de revisión
+	VoteRejected    VoteStatus = "rejected"    // descartado por un admin
+)
+
 type Vote struct {
 	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
 	UserID    uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_user_video;not null"`
 	User      User      `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
 	VideoID   uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_user_video;not null"`
 	Video     Video     `gorm:"foreignKey:VideoID;references:ID;constraint:OnDelete:CASCADE"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
-}
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+
+	// Señales para detección de fraude
+	IP          string `gorm:"index"`
+	Subnet      string `gorm:"index"` // /24 para IPv4, /64 para IPv6
+	UserAgent   string
+	Fingerprint string `gorm:"index"`
 
+	Status       VoteStatus `gorm:"type:text;index;not null;default:counted"`
+	FraudScore   int        `gorm:"not null;default:0"`
+	FraudReasons string     // códigos separados por coma
+	ReviewedBy   *uuid.UUID `gorm:"type:uuid"`
+	ReviewedAt   *time.Time
+}
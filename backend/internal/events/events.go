@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+)
+
+// Redis pub/sub channels shared by the API replicas and the workers.
+const (
+	ChannelRankings = "events:rankings"
+	ChannelVideos   = "events:videos"
+)
+
+// Event types, used as the SSE event name.
+const (
+	TypeRankingUpdated = "ranking.updated"
+	TypeVideoStatus    = "video.status"
+)
+
+// Event is the message broadcast over Redis and streamed to clients.
+// Owner is only set for private events, which are delivered to that user alone.
+type Event struct {
+	Type  string          `json:"type"`
+	Owner string          `json:"owner,omitempty"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// RankingUpdate is sent every time a vote changes a player's score.
+type RankingUpdate struct {
+	UserID  uuid.UUID `json:"user_id"`
+	VideoID uuid.UUID `json:"video_id"`
+	City    string    `json:"city"`
+	Votes   int64     `json:"votes"`
+}
+
+// VideoStatusChange is sent to the owner of a video when its status changes.
+type VideoStatusChange struct {
+	VideoID uuid.UUID          `json:"video_id"`
+	Status  domain.VideoStatus `json:"status"`
+	At      time.Time          `json:"at"`
+}
+
+// Publisher broadcasts events to every API replica through Redis pub/sub.
+// A nil *Publisher is valid and discards every event, for tools that do not stream.
+type Publisher struct {
+	client *redis.Client
+}
+
+func NewPublisher(client *redis.Client) *Publisher {
+	return &Publisher{client: client}
+}
+
+// RankingUpdated notifies every client that a player's score changed.
+func (p *Publisher) RankingUpdated(ctx context.Context, u RankingUpdate) {
+	p.publish(ctx, ChannelRankings, TypeRankingUpdated, "", u)
+}
+
+// VideoStatusChanged notifies the owner of a video that its status changed.
+func (p *Publisher) VideoStatusChanged(ctx context.Context, ownerID uuid.UUID, videoID uuid.UUID, status domain.VideoStatus) {
+	p.publish(ctx, ChannelVideos, TypeVideoStatus, ownerID.String(), VideoStatusChange{
+		VideoID: videoID,
+		Status:  status,
+		At:      time.Now().UTC(),
+	})
+}
+
+// publish never fails the caller: live updates are best effort and clients
+// can always fall back to reloading the resource.
+func (p *Publisher) publish(ctx context.Context, channel, typ, owner string, data any) {
+	if p == nil {
+		return
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		slog.WarnContext(ctx, "events: failed to encode", "type", typ, "error", err)
+		return
+	}
+	msg, err := json.Marshal(Event{Type: typ, Owner: owner, Data: raw})
+	if err != nil {
+		slog.WarnContext(ctx, "events: failed to encode", "type", typ, "error", err)
+		return
+	}
+	if err := p.client.Publish(ctx, channel, msg).Err(); err != nil {
+		slog.WarnContext(ctx, "events: failed to publish", "type", typ, "error", err)
+	}
+}
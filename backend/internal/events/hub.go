@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// subscriberBuffer is how many events a slow client may lag behind before
+// new events are dropped for it.
+const subscriberBuffer = 32
+
+// Hub holds a single Redis subscription per API replica and fans the events
+// out to the clients connected to that replica.
+type Hub struct {
+	client *redis.Client
+
+	mu   sync.RWMutex
+	subs map[*subscriber]struct{}
+
+	done chan struct{}
+}
+
+type subscriber struct {
+	owner string
+	ch    chan Event
+}
+
+func NewHub(client *redis.Client) *Hub {
+	return &Hub{client: client, subs: make(map[*subscriber]struct{}), done: make(chan struct{})}
+}
+
+// Run listens to Redis until ctx is cancelled. When it returns, Done is
+// closed so open streams end and their clients reconnect elsewhere.
+func (h *Hub) Run(ctx context.Context) {
+	defer close(h.done)
+	ps := h.client.Subscribe(ctx, ChannelRankings, ChannelVideos)
+	defer ps.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ps.Channel():
+			if !ok {
+				return
+			}
+			var ev Event
+			if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+				slog.WarnContext(ctx, "events: discarding malformed message", "channel", msg.Channel, "error", err)
+				continue
+			}
+			h.broadcast(ev)
+		}
+	}
+}
+
+// Done is closed once Run has returned.
+func (h *Hub) Done() <-chan struct{} {
+	return h.done
+}
+
+// Subscribe registers a client. Public events are always delivered; private
+// events only when owner (the authenticated user ID, possibly empty) matches.
+// The returned function must be called when the client goes away.
+func (h *Hub) Subscribe(owner string) (<-chan Event, func()) {
+	s := &subscriber{owner: owner, ch: make(chan Event, subscriberBuffer)}
+
+	h.mu.Lock()
+	h.subs[s] = struct{}{}
+	h.mu.Unlock()
+
+	return s.ch, func() {
+		h.mu.Lock()
+		delete(h.subs, s)
+		h.mu.Unlock()
+	}
+}
+
+func (h *Hub) broadcast(ev Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for s := range h.subs {
+		if ev.Owner != "" && ev.Owner != s.owner {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			// Slow client: drop rather than block every other subscriber
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Package finalists picks the showcase roster from the rankings once voting
+// closes: the top players of every city, adjusted by the jury and then frozen.
+package finalists
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/ranking"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+)
+
+var (
+	ErrVotingOpen     = errors.New("voting has not closed yet")
+	ErrInvalidSlots   = errors.New("slots per city must be positive")
+	ErrReasonRequired = errors.New("a reason is required")
+	ErrNotEligible    = errors.New("player is not eligible for this tournament")
+)
+
+type Service struct {
+	selections  repo.FinalistRepository
+	votes       repo.VoteRepository
+	users       repo.UserRepository
+	tournaments repo.TournamentRepository
+	rankings    *ranking.Service
+}
+
+func NewService(selections repo.FinalistRepository, votes repo.VoteRepository, users repo.UserRepository, tournaments repo.TournamentRepository, rankings *ranking.Service) *Service {
+	return &Service{selections: selections, votes: votes, users: users, tournaments: tournaments, rankings: rankings}
+}
+
+// Compute (re)computes the finalists of the tournament (nil = whole platform)
+// from the current rankings: the top slots players of each city are selected
+// and the next slots become alternates. Jury decisions of a draft selection
+// are kept. Frozen selections are never recomputed.
+func (s *Service) Compute(ctx context.Context, tournamentID *uuid.UUID, slots int) (*domain.FinalistSelection, error) {
+	if slots <= 0 {
+		return nil, ErrInvalidSlots
+	}
+	if tournamentID != nil {
+		t, err := s.tournaments.FindByID(ctx, *tournamentID)
+		if err != nil {
+			return nil, err
+		}
+		if t.Phase(time.Now()) != domain.TournamentClosed {
+			return nil, ErrVotingOpen
+		}
+	}
+
+	sel, err := s.selections.Latest(ctx, tournamentID, "")
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		sel = &domain.FinalistSelection{TournamentID: tournamentID, SlotsPerCity: slots, Status: domain.SelectionDraft, ComputedAt: time.Now().UTC()}
+		if err := s.selections.Create(ctx, sel); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	case sel.Status == domain.SelectionFrozen:
+		return nil, repo.ErrSelectionFrozen
+	}
+
+	cities, err := s.users.GetDistinctCities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	var decisions []domain.FinalistDecision
+	for _, city := range cities {
+		rows, err := s.votes.TopByCity(ctx, repo.RankingQuery{
+			Scope:        repo.RankingByPlayer,
+			TournamentID: tournamentID,
+			City:         city,
+			Limit:        2 * slots,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i, row := range rows {
+			d := domain.FinalistDecision{
+				UserID:    row.UserID,
+				Username:  row.Username,
+				City:      row.City,
+				Country:   row.Country,
+				Position:  row.Position,
+				Votes:     row.Votes,
+				Decision:  domain.FinalistSelected,
+				Source:    domain.DecisionFromRanking,
+				Reason:    fmt.Sprintf("Ranked #%d in %s with %d votes", row.Position, city, row.Votes),
+				DecidedAt: now,
+			}
+			if i >= slots {
+				d.Decision = domain.FinalistAlternate
+				if row.Position <= rows[slots-1].Position {
+					d.Reason += "; tied at the cutoff, left out by ID order"
+				}
+			}
+			decisions = append(decisions, d)
+		}
+	}
+
+	if err := s.selections.ReplaceComputed(ctx, sel.ID, slots, decisions); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "finalists: computed selection", "selection_id", sel.ID, "candidates", len(decisions), "cities", len(cities))
+	return s.selections.FindByID(ctx, sel.ID)
+}
+
+// Decide records a jury override for one player of a draft selection. The
+// player does not need to be among the computed candidates.
+func (s *Service) Decide(ctx context.Context, selectionID, playerID uuid.UUID, kind domain.FinalistDecisionKind, reason string, jurorID uuid.UUID) (*domain.FinalistDecision, error) {
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	sel, err := s.selections.FindByID(ctx, selectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	d := domain.FinalistDecision{SelectionID: selectionID, UserID: playerID}
+	found := false
+	for _, existing := range sel.Decisions {
+		if existing.UserID == playerID {
+			d = existing
+			found = true
+			break
+		}
+	}
+	if !found {
+		player, err := s.users.FindByID(ctx, playerID)
+		if err != nil {
+			return nil, err
+		}
+		if sel.TournamentID != nil {
+			t, err := s.tournaments.FindByID(ctx, *sel.TournamentID)
+			if err != nil {
+				return nil, err
+			}
+			if !t.Eligible(player.City, player.Country) {
+				return nil, ErrNotEligible
+			}
+		}
+		d.Username = player.FirstName + " " + player.LastName
+		d.City, d.Country = player.City, player.Country
+	}
+
+	d.Decision = kind
+	d.Source = domain.DecisionFromJury
+	d.Reason = reason
+	d.DecidedBy = &jurorID
+	d.DecidedAt = time.Now().UTC()
+	if err := s.selections.SaveDecision(ctx, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Freeze publishes the selection and freezes the final rankings with it.
+func (s *Service) Freeze(ctx context.Context, selectionID, adminID uuid.UUID) (*domain.FinalistSelection, error) {
+	if err := s.selections.Freeze(ctx, selectionID, adminID); err != nil {
+		return nil, err
+	}
+	if _, err := s.rankings.Snapshot(ctx, true); err != nil {
+		slog.ErrorContext(ctx, "finalists: failed to take final ranking snapshot", "error", err)
+	}
+	return s.selections.FindByID(ctx, selectionID)
+}
+
+// Get returns one selection with its decisions.
+func (s *Service) Get(ctx context.Context, selectionID uuid.UUID) (*domain.FinalistSelection, error) {
+	return s.selections.FindByID(ctx, selectionID)
+}
+
+// Published returns the frozen selection of the tournament (nil = whole platform).
+func (s *Service) Published(ctx context.Context, tournamentID *uuid.UUID) (*domain.FinalistSelection, error) {
+	return s.selections.Latest(ctx, tournamentID, domain.SelectionFrozen)
+}
+
+// Roster returns the selected players of a selection, grouped by city.
+func Roster(sel *domain.FinalistSelection) []domain.FinalistDecision {
+	out := []domain.FinalistDecision{}
+	for _, d := range sel.Decisions {
+		if d.Decision == domain.FinalistSelected {
+			out = append(out, d)
+		}
+	}
+	return out
+}
@@ -0,0 +1,129 @@
+// Package fraud scores votes for signs of ballot stuffing so that suspicious
+// ones can be quarantined until an admin reviews them.
+package fraud
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+)
+
+// Reason codes stored in Vote.FraudReasons.
+const (
+	ReasonIPBurst          = "ip_burst"
+	ReasonSubnetBurst      = "subnet_burst"
+	ReasonFreshAccount     = "fresh_account"
+	ReasonSinglePlayer     = "single_player_voter"
+	ReasonSharedDevice     = "shared_device"
+	ReasonMissingUserAgent = "missing_user_agent"
+)
+
+// Weight of each signal in the fraud score.
+var weights = map[string]int{
+	ReasonIPBurst:          40,
+	ReasonSubnetBurst:      30,
+	ReasonFreshAccount:     20,
+	ReasonSinglePlayer:     25,
+	ReasonSharedDevice:     50,
+	ReasonMissingUserAgent: 10,
+}
+
+type Config struct {
+	Window            time.Duration // window in which bursts are detected
+	MaxVotesPerIP     int64         // votes per IP within the window
+	MaxVotesPerSubnet int64         // votes per subnet within the window
+	FreshAccountAge   time.Duration // accounts younger than this are fresh
+	MinVotesForBias   int64         // prior votes needed before checking for a single-player voter
+	QuarantineScore   int           // votes scoring this much or more are quarantined
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Window:            10 * time.Minute,
+		MaxVotesPerIP:     5,
+		MaxVotesPerSubnet: 20,
+		FreshAccountAge:   24 * time.Hour,
+		MinVotesForBias:   2,
+		QuarantineScore:   50,
+	}
+}
+
+type Scorer struct {
+	votes repo.VoteRepository
+	cfg   Config
+}
+
+func NewScorer(votes repo.VoteRepository, cfg Config) *Scorer {
+	return &Scorer{votes: votes, cfg: cfg}
+}
+
+// Screen scores v, cast by voter for video, and sets its Subnet, FraudScore,
+// FraudReasons and Status. Lookup errors are logged and the affected signal is
+// skipped: a database hiccup must not block legitimate votes.
+func (s *Scorer) Screen(ctx context.Context, voter *domain.User, video *domain.Video, v *domain.Vote) {
+	v.Subnet = Subnet(v.IP)
+	since := time.Now().Add(-s.cfg.Window)
+	var reasons []string
+
+	if v.IP != "" {
+		if n, err := s.votes.CountRecentFromIP(ctx, v.IP, since); err != nil {
+			slog.WarnContext(ctx, "fraud: counting votes from ip", "error", err)
+		} else if n >= s.cfg.MaxVotesPerIP {
+			reasons = append(reasons, ReasonIPBurst)
+		}
+	}
+	if v.Subnet != "" {
+		if n, err := s.votes.CountRecentFromSubnet(ctx, v.Subnet, since); err != nil {
+			slog.WarnContext(ctx, "fraud: counting votes from subnet", "error", err)
+		} else if n >= s.cfg.MaxVotesPerSubnet {
+			reasons = append(reasons, ReasonSubnetBurst)
+		}
+	}
+	if voter != nil && time.Since(voter.CreatedAt) < s.cfg.FreshAccountAge {
+		reasons = append(reasons, ReasonFreshAccount)
+	}
+	if perPlayer, err := s.votes.VotesPerPlayer(ctx, v.UserID); err != nil {
+		slog.WarnContext(ctx, "fraud: counting votes cast by voter", "user_id", v.UserID, "error", err)
+	} else if len(perPlayer) == 1 && perPlayer[video.UserID] >= s.cfg.MinVotesForBias {
+		// Every previous vote went to this same player
+		reasons = append(reasons, ReasonSinglePlayer)
+	}
+	if v.Fingerprint != "" {
+		if n, err := s.votes.CountOtherVotersWithFingerprint(ctx, v.Fingerprint, v.VideoID, v.UserID); err != nil {
+			slog.WarnContext(ctx, "fraud: counting votes from device", "error", err)
+		} else if n > 0 {
+			reasons = append(reasons, ReasonSharedDevice)
+		}
+	}
+	if strings.TrimSpace(v.UserAgent) == "" {
+		reasons = append(reasons, ReasonMissingUserAgent)
+	}
+
+	v.FraudScore = 0
+	for _, r := range reasons {
+		v.FraudScore += weights[r]
+	}
+	v.FraudReasons = strings.Join(reasons, ",")
+	v.Status = domain.VoteCounted
+	if v.FraudScore >= s.cfg.QuarantineScore {
+		v.Status = domain.VoteQuarantined
+	}
+}
+
+// Subnet returns the /24 (IPv4) or /64 (IPv6) network ip belongs to, or ""
+// if ip cannot be parsed.
+func Subnet(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(64, 128)), Mask: net.CIDRMask(64, 128)}).String()
+}
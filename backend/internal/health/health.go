@@ -0,0 +1,162 @@
+// Package health serves the liveness and readiness endpoints of the API and
+// the worker. Readiness runs one check per dependency, each with its own
+// timeout, and answers with a JSON breakdown so a failing probe says which
+// dependency is down.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// CheckFunc reports whether a dependency is usable. It must give up when ctx
+// is done.
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of one check.
+type Result struct {
+	Status     string `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Report is the body of /readyz.
+type Report struct {
+	Status   string            `json:"status"`
+	Draining bool              `json:"draining,omitempty"`
+	Checks   map[string]Result `json:"checks"`
+	Info     map[string]any    `json:"info,omitempty"`
+}
+
+// Checker runs the registered checks concurrently.
+type Checker struct {
+	timeout time.Duration
+	checks  map[string]CheckFunc
+	info    map[string]func() any
+
+	draining atomic.Bool
+}
+
+// New returns a Checker that gives each check at most timeout.
+func New(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  map[string]CheckFunc{},
+		info:    map[string]func() any{},
+	}
+}
+
+// Add registers a check that must pass for the process to be ready.
+func (h *Checker) Add(name string, fn CheckFunc) *Checker {
+	h.checks[name] = fn
+	return h
+}
+
+// Info registers a value reported next to the checks (e.g. the current job)
+// that never makes the process unready.
+func (h *Checker) Info(name string, fn func() any) *Checker {
+	h.info[name] = fn
+	return h
+}
+
+// Drain marks the process as shutting down: from then on it is reported
+// unready, so the load balancer stops routing to it while requests finish.
+func (h *Checker) Drain() {
+	h.draining.Store(true)
+}
+
+// Run executes every check and returns the report. The process is ready only
+// if all checks pass and it is not draining.
+func (h *Checker) Run(ctx context.Context) Report {
+	rep := Report{Status: StatusOK, Checks: make(map[string]Result, len(h.checks))}
+	if h.draining.Load() {
+		rep.Status = StatusUnavailable
+		rep.Draining = true
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, fn := range h.checks {
+		wg.Add(1)
+		go func(name string, fn CheckFunc) {
+			defer wg.Done()
+			res := h.run(ctx, fn)
+			mu.Lock()
+			defer mu.Unlock()
+			rep.Checks[name] = res
+			if res.Status != StatusOK {
+				rep.Status = StatusUnavailable
+			}
+		}(name, fn)
+	}
+	wg.Wait()
+
+	if len(h.info) > 0 {
+		rep.Info = make(map[string]any, len(h.info))
+		for name, fn := range h.info {
+			rep.Info[name] = fn()
+		}
+	}
+	return rep
+}
+
+// run bounds fn by the checker timeout, even if fn ignores its context.
+func (h *Checker) run(ctx context.Context, fn CheckFunc) Result {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	res := Result{Status: StatusOK, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		res.Status = StatusUnavailable
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Ready serves the report, with 503 when any check fails so load balancers
+// take the instance out of rotation.
+func (h *Checker) Ready() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rep := h.Run(r.Context())
+		code := http.StatusOK
+		if rep.Status != StatusOK {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, rep)
+	})
+}
+
+// Live answers 200 as long as the process can serve HTTP. It checks no
+// dependency: an outage of Postgres must not get every replica restarted.
+func Live() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,44 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/ranking"
+)
+
+type AdminHandlers struct {
+	rankings *ranking.Service
+}
+
+func NewAdminHandlers(rankings *ranking.Service) *AdminHandlers {
+	return &AdminHandlers{rankings: rankings}
+}
+
+// TakeSnapshot godoc
+// @Summary Take a ranking snapshot
+// @Description Persist the current rankings. With final=true the snapshot freezes the final standings served by /public/rankings/final. Admin only.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param final query bool false "Freeze these standings as final (default: false)"
+// @Success 201 {object} map[string]interface{} "Snapshot taken"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin role required"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/rankings/snapshots [post]
+func (h *AdminHandlers) TakeSnapshot(c *gin.Context) {
+	final := c.Query("final") == "true"
+	snap, err := h.rankings.Snapshot(c.Request.Context(), final)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error taking ranking snapshot"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"snapshot_id": snap.ID,
+		"taken_at":    snap.TakenAt,
+		"final":       snap.Final,
+		"entries":     len(snap.Entries),
+	})
+}
@@ -11,11 +11,17 @@ import (
 
 	"github.com/Cloud-2025-2/anb-platform/internal/auth"
 	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/ranking"
 )
 
-type AuthHandlers struct{ svc *auth.Service }
+type AuthHandlers struct {
+	svc      *auth.Service
+	rankings *ranking.Service
+}
 
-func NewAuthHandlers(s *auth.Service) *AuthHandlers { return &AuthHandlers{svc: s} }
+func NewAuthHandlers(s *auth.Service, rankings *ranking.Service) *AuthHandlers {
+	return &AuthHandlers{svc: s, rankings: rankings}
+}
 
 // SignUp godoc
 // @Summary Register a new player
@@ -30,6 +36,7 @@ func NewAuthHandlers(s *auth.Service) *AuthHandlers { return &AuthHandlers{svc:
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /auth/signup [post]
 func (h *AuthHandlers) SignUp(c *gin.Context) {
+	ctx := c.Request.Context()
 	var in SignUpIn
 	if err := c.ShouldBindJSON(&in); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -42,7 +49,7 @@ func (h *AuthHandlers) SignUp(c *gin.Context) {
 		City:      in.City,
 		Country:   in.Country,
 	}
-	if err := h.svc.SignUp(u, in.Password1, in.Password2); err != nil {
+	if err := h.svc.SignUp(ctx, u, in.Password1, in.Password2); err != nil {
 		// Handle different types of errors appropriately
 		if strings.Contains(err.Error(), "passwords do not match") {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Passwords do not match"})
@@ -73,12 +80,13 @@ func (h *AuthHandlers) SignUp(c *gin.Context) {
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /auth/login [post]
 func (h *AuthHandlers) Login(c *gin.Context) {
+	ctx := c.Request.Context()
 	var in LoginIn
 	if err := c.ShouldBindJSON(&in); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	result, err := h.svc.Login(in.Email, in.Password)
+	result, err := h.svc.Login(ctx, in.Email, in.Password)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
@@ -97,13 +105,16 @@ func (h *AuthHandlers) Login(c *gin.Context) {
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /auth [delete]
 func (h *AuthHandlers) DeleteUser(c *gin.Context) {
+	ctx := c.Request.Context()
 	uid, err := uuid.Parse(c.GetString("user_id"))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
 		return
 	}
 
-	if err := h.svc.DeleteUser(uid); err != nil {
+	// The leaderboard takes back the votes cast by the user once the account is gone
+	err = h.rankings.RemoveUser(ctx, uid, func() error { return h.svc.DeleteUser(ctx, uid) })
+	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 			return
@@ -1,5 +1,15 @@
 package httpapi
 
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+	vidsvc "github.com/Cloud-2025-2/anb-platform/internal/video"
+)
+
 type SignUpIn struct {
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name"  binding:"required"`
@@ -14,3 +24,284 @@ type LoginIn struct {
 	Email    string `json:"email"    binding:"required,email"`
 	Password string `json:"password" binding:"required"`
 }
+
+// Response DTOs. Handlers never serialize GORM models directly, so adding a
+// column to a domain model cannot leak it through the API.
+
+// PlayerView is the public profile of a video's owner. It never carries the
+// email, role or credentials.
+type PlayerView struct {
+	ID        uuid.UUID `json:"id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	City      string    `json:"city"`
+	Country   string    `json:"country"`
+}
+
+func playerView(u domain.User) PlayerView {
+	return PlayerView{ID: u.ID, FirstName: u.FirstName, LastName: u.LastName, City: u.City, Country: u.Country}
+}
+
+// VideoView is a video as anyone can see it in the public list.
+type VideoView struct {
+	ID            uuid.UUID  `json:"id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description,omitempty"`
+	ProcessedURL  *string    `json:"processed_url,omitempty"`
+	PublishedAt   *time.Time `json:"published_at,omitempty"`
+	TournamentID  *uuid.UUID `json:"tournament_id,omitempty"`
+	Player        PlayerView `json:"player"`
+	VoteCount     int64      `json:"vote_count"`
+	TrendingVotes int64      `json:"trending_votes"`
+	VotedByMe     bool       `json:"voted_by_me"`
+}
+
+func videoView(v domain.Video) VideoView {
+	return VideoView{
+		ID:            v.ID,
+		Title:         v.Title,
+		Description:   v.Description,
+		ProcessedURL:  v.ProcessedURL,
+		PublishedAt:   v.PublishedAt,
+		TournamentID:  v.TournamentID,
+		Player:        playerView(v.User),
+		VoteCount:     v.VoteCount,
+		TrendingVotes: v.TrendingVotes,
+		VotedByMe:     v.VotedByMe,
+	}
+}
+
+// OwnerVideoView adds the processing and moderation details that only the
+// owner and moderators see.
+type OwnerVideoView struct {
+	VideoView
+	Status           domain.VideoStatus `json:"status"`
+	Profile          string             `json:"profile"`
+	IsPublicForVote  bool               `json:"is_public_for_vote"`
+	UploadedAt       time.Time          `json:"uploaded_at"`
+	ProcessedAt      *time.Time         `json:"processed_at,omitempty"`
+	WidthProc        *int               `json:"width,omitempty"`
+	HeightProc       *int               `json:"height,omitempty"`
+	ModerationReason *string            `json:"moderation_reason,omitempty"`
+	ModeratedAt      *time.Time         `json:"moderated_at,omitempty"`
+}
+
+func ownerVideoView(v domain.Video) OwnerVideoView {
+	return OwnerVideoView{
+		VideoView:        videoView(v),
+		Status:           v.Status,
+		Profile:          v.Profile,
+		IsPublicForVote:  v.IsPublicForVote,
+		UploadedAt:       v.UploadedAt,
+		ProcessedAt:      v.ProcessedAt,
+		WidthProc:        v.WidthProc,
+		HeightProc:       v.HeightProc,
+		ModerationReason: v.ModerationReason,
+		ModeratedAt:      v.ModeratedAt,
+	}
+}
+
+func ownerVideoViews(list []domain.Video) []OwnerVideoView {
+	out := make([]OwnerVideoView, 0, len(list))
+	for _, v := range list {
+		out = append(out, ownerVideoView(v))
+	}
+	return out
+}
+
+// StatusEventView is one status change of a video. From is empty for the
+// initial upload and ActorID for changes made by the system.
+type StatusEventView struct {
+	ID      uuid.UUID          `json:"id"`
+	VideoID uuid.UUID          `json:"video_id"`
+	From    domain.VideoStatus `json:"from,omitempty"`
+	To      domain.VideoStatus `json:"to"`
+	Reason  string             `json:"reason,omitempty"`
+	ActorID *uuid.UUID         `json:"actor_id,omitempty"`
+	At      time.Time          `json:"at"`
+}
+
+func statusEventViews(list []domain.VideoStatusEvent) []StatusEventView {
+	out := make([]StatusEventView, 0, len(list))
+	for _, e := range list {
+		out = append(out, StatusEventView{
+			ID:      e.ID,
+			VideoID: e.VideoID,
+			From:    e.From,
+			To:      e.To,
+			Reason:  e.Reason,
+			ActorID: e.ActorID,
+			At:      e.CreatedAt,
+		})
+	}
+	return out
+}
+
+// RankingRowView is one entry of a ranking. VideoID and Title are only set
+// with scope=video.
+type RankingRowView struct {
+	Position         int         `json:"position"`
+	UserID           uuid.UUID   `json:"user_id"`
+	Username         string      `json:"username"`
+	City             string      `json:"city"`
+	Country          string      `json:"country"`
+	VideoID          *uuid.UUID  `json:"video_id,omitempty"`
+	Title            string      `json:"title,omitempty"`
+	VideoIDs         []uuid.UUID `json:"video_ids"`
+	Votes            int64       `json:"votes"`
+	PreviousPosition *int        `json:"previous_position,omitempty"`
+}
+
+func rankingRowViews(rows []repo.RankingRow) []RankingRowView {
+	out := make([]RankingRowView, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, RankingRowView{
+			Position:         r.Position,
+			UserID:           r.UserID,
+			Username:         r.Username,
+			City:             r.City,
+			Country:          r.Country,
+			VideoID:          r.VideoID,
+			Title:            r.Title,
+			VideoIDs:         r.VideoIDs,
+			Votes:            r.Votes,
+			PreviousPosition: r.PreviousPosition,
+		})
+	}
+	return out
+}
+
+// FinalRankingsView is the standings frozen by a final snapshot.
+type FinalRankingsView struct {
+	FrozenAt time.Time        `json:"frozen_at"`
+	Rankings []RankingRowView `json:"rankings"`
+}
+
+// ReportView is a user report as shown to moderators. The reporter is only
+// identified by ID.
+type ReportView struct {
+	ID         uuid.UUID           `json:"id"`
+	VideoID    uuid.UUID           `json:"video_id"`
+	VideoTitle string              `json:"video_title"`
+	ReporterID uuid.UUID           `json:"reporter_id"`
+	Reason     string              `json:"reason"`
+	Details    string              `json:"details,omitempty"`
+	Status     domain.ReportStatus `json:"status"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+func reportView(r domain.VideoReport) ReportView {
+	return ReportView{
+		ID:         r.ID,
+		VideoID:    r.VideoID,
+		VideoTitle: r.Video.Title,
+		ReporterID: r.ReporterID,
+		Reason:     r.Reason,
+		Details:    r.Details,
+		Status:     r.Status,
+		CreatedAt:  r.CreatedAt,
+	}
+}
+
+// TaskView is the status of a processing task as shown to the video owner.
+// EstimatedQueuePosition is only set while the task is queued (1 = next to
+// run). It is an estimate: tasks run concurrently and retries wait out their
+// backoff, so the actual order may differ.
+type TaskView struct {
+	ID                     uuid.UUID         `json:"id"`
+	VideoID                uuid.UUID         `json:"video_id"`
+	Status                 domain.TaskStatus `json:"status"`
+	Lane                   domain.TaskLane   `json:"lane"`
+	EstimatedQueuePosition *int64            `json:"estimated_queue_position,omitempty"`
+	Step                   string            `json:"step,omitempty"`
+	Progress               int               `json:"progress"`
+	Attempts               int               `json:"attempts"`
+	LastError              *string           `json:"last_error,omitempty"`
+	EnqueuedAt             time.Time         `json:"enqueued_at"`
+	StartedAt              *time.Time        `json:"started_at,omitempty"`
+	FinishedAt             *time.Time        `json:"finished_at,omitempty"`
+}
+
+func taskView(t vidsvc.TaskStatus) TaskView {
+	return TaskView{
+		ID:                     t.ID,
+		VideoID:                t.VideoID,
+		Status:                 t.Status,
+		Lane:                   t.Lane,
+		EstimatedQueuePosition: t.EstimatedQueuePosition,
+		Step:                   t.Step,
+		Progress:               t.Progress,
+		Attempts:               t.Attempts,
+		LastError:              t.LastError,
+		EnqueuedAt:             t.EnqueuedAt,
+		StartedAt:              t.StartedAt,
+		FinishedAt:             t.FinishedAt,
+	}
+}
+
+// FinalistDecisionView is one player's finalist decision as shown to admins
+// and the jury.
+type FinalistDecisionView struct {
+	ID        uuid.UUID                   `json:"id"`
+	UserID    uuid.UUID                   `json:"user_id"`
+	Username  string                      `json:"username"`
+	City      string                      `json:"city"`
+	Country   string                      `json:"country"`
+	Position  int                         `json:"position"`
+	Votes     int64                       `json:"votes"`
+	Decision  domain.FinalistDecisionKind `json:"decision"`
+	Source    domain.DecisionSource       `json:"source"`
+	Reason    string                      `json:"reason"`
+	DecidedBy *uuid.UUID                  `json:"decided_by,omitempty"`
+	DecidedAt time.Time                   `json:"decided_at"`
+}
+
+func finalistDecisionView(d domain.FinalistDecision) FinalistDecisionView {
+	return FinalistDecisionView{
+		ID:        d.ID,
+		UserID:    d.UserID,
+		Username:  d.Username,
+		City:      d.City,
+		Country:   d.Country,
+		Position:  d.Position,
+		Votes:     d.Votes,
+		Decision:  d.Decision,
+		Source:    d.Source,
+		Reason:    d.Reason,
+		DecidedBy: d.DecidedBy,
+		DecidedAt: d.DecidedAt,
+	}
+}
+
+func finalistDecisionViews(list []domain.FinalistDecision) []FinalistDecisionView {
+	out := make([]FinalistDecisionView, 0, len(list))
+	for _, d := range list {
+		out = append(out, finalistDecisionView(d))
+	}
+	return out
+}
+
+// FinalistSelectionView is a finalist selection with all of its decisions.
+type FinalistSelectionView struct {
+	ID           uuid.UUID              `json:"id"`
+	TournamentID *uuid.UUID             `json:"tournament_id,omitempty"`
+	SlotsPerCity int                    `json:"slots_per_city"`
+	Status       domain.SelectionStatus `json:"status"`
+	ComputedAt   time.Time              `json:"computed_at"`
+	FrozenAt     *time.Time             `json:"frozen_at,omitempty"`
+	FrozenBy     *uuid.UUID             `json:"frozen_by,omitempty"`
+	Decisions    []FinalistDecisionView `json:"decisions"`
+}
+
+func finalistSelectionView(s domain.FinalistSelection) FinalistSelectionView {
+	return FinalistSelectionView{
+		ID:           s.ID,
+		TournamentID: s.TournamentID,
+		SlotsPerCity: s.SlotsPerCity,
+		Status:       s.Status,
+		ComputedAt:   s.ComputedAt,
+		FrozenAt:     s.FrozenAt,
+		FrozenBy:     s.FrozenBy,
+		Decisions:    finalistDecisionViews(s.Decisions),
+	}
+}
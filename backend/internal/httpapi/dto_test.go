@@ -0,0 +1,202 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/cache"
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/ranking"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+)
+
+// Values of the sensitive fields of the fixture user. None of them, nor the
+// names of their fields, may appear in a public response.
+const (
+	leakEmail = "leak.canary@example.com"
+	leakHash  = "$2a$10$leakcanaryhashleakcanaryhash"
+)
+
+var leakKeys = []string{`"email"`, `"password_hash"`, `"role"`, `"Email"`, `"PasswordHash"`, `"Role"`}
+
+func fixtureUser() domain.User {
+	return domain.User{
+		ID:           uuid.New(),
+		FirstName:    "Ana",
+		LastName:     "Pérez",
+		Email:        leakEmail,
+		PasswordHash: leakHash,
+		City:         "Bogotá",
+		Country:      "Colombia",
+		Role:         domain.RoleAdmin,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+}
+
+func fixtureVideo(u domain.User) domain.Video {
+	now := time.Now()
+	url := "/storage/processed.mp4"
+	tournament := uuid.New()
+	return domain.Video{
+		ID:              uuid.New(),
+		UserID:          u.ID,
+		User:            u,
+		Title:           "Clavada",
+		Description:     "Final",
+		OriginalURL:     "/storage/original.mp4",
+		ProcessedURL:    &url,
+		Status:          domain.VideoPublished,
+		UploadedAt:      now,
+		ProcessedAt:     &now,
+		PublishedAt:     &now,
+		IsPublicForVote: true,
+		TournamentID:    &tournament,
+		VoteCount:       3,
+		TrendingVotes:   1,
+	}
+}
+
+func fixtureVote(u domain.User, v domain.Video) domain.Vote {
+	return domain.Vote{
+		ID:        uuid.New(),
+		UserID:    u.ID,
+		User:      u,
+		VideoID:   v.ID,
+		Video:     v,
+		CreatedAt: time.Now(),
+		Status:    domain.VoteCounted,
+	}
+}
+
+func assertNoLeak(t *testing.T, body []byte) {
+	t.Helper()
+	s := string(body)
+	for _, needle := range append([]string{leakEmail, leakHash}, leakKeys...) {
+		if strings.Contains(s, needle) {
+			t.Errorf("response contains %s: %s", needle, s)
+		}
+	}
+	if strings.Contains(s, `"`+string(domain.RoleAdmin)+`"`) {
+		t.Errorf("response contains the user's role: %s", s)
+	}
+}
+
+func TestPublicViewsDoNotLeakSensitiveFields(t *testing.T) {
+	u := fixtureUser()
+	v := fixtureVideo(u)
+	vote := fixtureVote(u, v)
+	report := domain.VideoReport{ID: uuid.New(), VideoID: v.ID, Video: v, ReporterID: u.ID, Reporter: u, Reason: "spam"}
+	decision := domain.FinalistDecision{ID: uuid.New(), UserID: u.ID, Username: u.FirstName + " " + u.LastName, City: u.City, Country: u.Country}
+
+	tests := []struct {
+		name string
+		view any
+	}{
+		{"PlayerView", playerView(u)},
+		{"VideoView", videoView(v)},
+		{"OwnerVideoView", ownerVideoView(v)},
+		{"ReportView", reportView(report)},
+		{"CastVote", CastVote{VideoID: vote.VideoID, Title: vote.Video.Title, PlayerID: vote.Video.UserID}},
+		{"RankingRowView", rankingRowViews([]repo.RankingRow{{UserID: u.ID, Username: u.FirstName, City: u.City, Country: u.Country, VideoID: &v.ID}})},
+		{"FinalRankingsView", FinalRankingsView{FrozenAt: time.Now(), Rankings: rankingRowViews([]repo.RankingRow{{UserID: u.ID}})}},
+		{"StatusEventView", statusEventViews([]domain.VideoStatusEvent{{ID: uuid.New(), VideoID: v.ID, Video: &v, From: domain.VideoPendingReview, To: domain.VideoPublished, ActorID: &u.ID}})},
+		{"VideoDetail", VideoDetail{OwnerVideoView: ownerVideoView(v), StatusHistory: statusEventViews([]domain.VideoStatusEvent{{VideoID: v.ID, Video: &v, To: domain.VideoUploaded}})}},
+		{"FinalistDecisionView", finalistDecisionView(decision)},
+		{"FinalistSelectionView", finalistSelectionView(domain.FinalistSelection{ID: uuid.New(), Decisions: []domain.FinalistDecision{decision}})},
+		{"TournamentView", tournamentView(domain.Tournament{ID: uuid.New(), Name: "Copa"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.view)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertNoLeak(t, body)
+		})
+	}
+}
+
+type fakeVideos struct {
+	repo.VideoRepository
+	list []domain.Video
+}
+
+func (f fakeVideos) ListPublic(context.Context, repo.VideoSearch) ([]domain.Video, *repo.VideoCursor, error) {
+	return f.list, nil, nil
+}
+
+type fakeVotes struct {
+	repo.VoteRepository
+	votes []domain.Vote
+	rows  []repo.RankingRow
+}
+
+func (f fakeVotes) VotedVideoIDs(_ context.Context, _ uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	return map[uuid.UUID]bool{ids[0]: true}, nil
+}
+
+func (f fakeVotes) ListByVoter(context.Context, uuid.UUID) ([]domain.Vote, error) {
+	return f.votes, nil
+}
+
+func (f fakeVotes) TopByCity(context.Context, repo.RankingQuery) ([]repo.RankingRow, error) {
+	return f.rows, nil
+}
+
+type fakeSnapshots struct {
+	repo.SnapshotRepository
+	final []repo.RankingRow
+}
+
+func (fakeSnapshots) PreviousPositions(context.Context, repo.RankingQuery, []uuid.UUID) (map[uuid.UUID]int, error) {
+	return map[uuid.UUID]int{}, nil
+}
+
+func (f fakeSnapshots) LatestFinal(context.Context, repo.RankingQuery) (*domain.RankingSnapshot, []repo.RankingRow, error) {
+	return &domain.RankingSnapshot{TakenAt: time.Now()}, f.final, nil
+}
+
+func TestPublicHandlersDoNotLeakSensitiveFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	u := fixtureUser()
+	v := fixtureVideo(u)
+	votes := fakeVotes{
+		votes: []domain.Vote{fixtureVote(u, v)},
+		rows:  []repo.RankingRow{{Position: 1, UserID: u.ID, Username: u.FirstName + " " + u.LastName, City: u.City, Country: u.Country, Votes: 3}},
+	}
+
+	// Nothing listens there: the leaderboard and the cache miss, and rankings
+	// come from the vote repository
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 50 * time.Millisecond})
+	defer rdb.Close()
+	rankings := ranking.NewService(votes, nil, fakeSnapshots{final: votes.rows}, nil, cache.NewLeaderboard(rdb), cache.NewRankingsCache(rdb, time.Minute), nil)
+
+	h := NewPublicHandlers(fakeVideos{list: []domain.Video{v}}, votes, nil, nil, rankings, nil, repo.VoteQuota{PerCity: 2})
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", u.ID.String()) })
+	r.GET("/api/public/videos", h.ListVideos)
+	r.GET("/api/public/rankings", h.Rankings)
+	r.GET("/api/public/rankings/final", h.FinalRankings)
+	r.GET("/api/votes", h.MyVotes)
+
+	for _, path := range []string{"/api/public/videos", "/api/public/rankings", "/api/public/rankings?scope=video", "/api/public/rankings/final", "/api/votes"} {
+		t.Run(path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			assertNoLeak(t, w.Body.Bytes())
+		})
+	}
+}
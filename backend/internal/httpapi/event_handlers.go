@@ -0,0 +1,80 @@
+package httpapi
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/auth"
+	"github.com/Cloud-2025-2/anb-platform/internal/events"
+)
+
+// heartbeatInterval keeps idle connections alive through nginx and the ALB.
+const heartbeatInterval = 25 * time.Second
+
+type EventHandlers struct {
+	hub  *events.Hub
+	auth *auth.Service
+}
+
+func NewEventHandlers(hub *events.Hub, auth *auth.Service) *EventHandlers {
+	return &EventHandlers{hub: hub, auth: auth}
+}
+
+// Ticket godoc
+// @Summary Live updates stream ticket
+// @Description Issues a short-lived ticket to open the live updates stream as the current user. EventSource cannot send the Authorization header, and the ticket goes in the query string instead of the access token, which would end up in proxy logs.
+// @Tags Events
+// @Produce json
+// @Success 200 {object} auth.StreamTicketResult "Stream ticket"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Security BearerAuth
+// @Router /events/ticket [post]
+func (h *EventHandlers) Ticket(c *gin.Context) {
+	res, err := h.auth.StreamTicket(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue stream ticket"})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// Stream godoc
+// @Summary Live updates stream
+// @Description Server-Sent Events stream with ranking changes (ranking.updated) and, when authenticated, status transitions of the user's own videos (video.status). Browsers using EventSource pass a stream ticket from POST /events/ticket in the ticket query parameter.
+// @Tags Events
+// @Produce text/event-stream
+// @Param ticket query string false "Stream ticket, alternative to the Authorization header"
+// @Success 200 {string} string "Event stream"
+// @Failure 401 {object} map[string]string "Invalid or expired stream ticket"
+// @Router /events [get]
+func (h *EventHandlers) Stream(c *gin.Context) {
+	ch, unsubscribe := h.hub.Subscribe(c.GetString("user_id"))
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable nginx response buffering
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-h.hub.Done():
+			// Shutting down; EventSource reconnects on its own
+			return false
+		case ev := <-ch:
+			c.SSEvent(ev.Type, ev.Data)
+			return true
+		case <-heartbeat.C:
+			_, _ = io.WriteString(w, ": ping\n\n")
+			return true
+		}
+	})
+}
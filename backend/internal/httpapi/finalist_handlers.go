@@ -0,0 +1,321 @@
+package httpapi
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/finalists"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+)
+
+type FinalistHandlers struct {
+	svc          *finalists.Service
+	defaultSlots int
+}
+
+func NewFinalistHandlers(svc *finalists.Service, defaultSlots int) *FinalistHandlers {
+	return &FinalistHandlers{svc: svc, defaultSlots: defaultSlots}
+}
+
+// ComputeFinalistsRequest selects what to compute finalists for.
+type ComputeFinalistsRequest struct {
+	TournamentID *uuid.UUID `json:"tournament_id"`  // vacío = toda la plataforma
+	SlotsPerCity int        `json:"slots_per_city"` // 0 = valor por defecto
+}
+
+// DecisionRequest is a jury override for one player.
+type DecisionRequest struct {
+	UserID   uuid.UUID `json:"user_id" binding:"required"`
+	Decision string    `json:"decision" binding:"required"`
+	Reason   string    `json:"reason" binding:"required"`
+}
+
+// Compute godoc
+// @Summary Compute finalists
+// @Description Compute the finalists from the player rankings: the top slots_per_city players of every city are selected and the next ones become alternates. Recomputing a draft keeps jury decisions. Tournaments must have closed voting. Admin only.
+// @Tags Finalists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ComputeFinalistsRequest false "Tournament and slots per city"
+// @Success 200 {object} FinalistSelectionView "Draft selection"
+// @Failure 400 {object} map[string]string "Bad request - invalid parameters"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin role required"
+// @Failure 404 {object} map[string]string "Tournament not found"
+// @Failure 409 {object} map[string]string "Conflict - voting still open or results already frozen"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/finalists/compute [post]
+func (h *FinalistHandlers) Compute(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req ComputeFinalistsRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.SlotsPerCity == 0 {
+		req.SlotsPerCity = h.defaultSlots
+	}
+
+	sel, err := h.svc.Compute(ctx, req.TournamentID, req.SlotsPerCity)
+	if err != nil {
+		switch {
+		case errors.Is(err, finalists.ErrInvalidSlots):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		case errors.Is(err, finalists.ErrVotingOpen):
+			c.JSON(http.StatusConflict, gin.H{"error": "Voting has not closed yet"})
+		case errors.Is(err, repo.ErrSelectionFrozen):
+			c.JSON(http.StatusConflict, gin.H{"error": "Results are already frozen"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error computing finalists"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, finalistSelectionView(*sel))
+}
+
+// Get godoc
+// @Summary Get a finalist selection
+// @Description Get a finalist selection with every decision and its reason, including drafts. Admin and jury only.
+// @Tags Finalists
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Selection ID"
+// @Success 200 {object} FinalistSelectionView "Selection"
+// @Failure 400 {object} map[string]string "Bad request - invalid selection ID"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin or jury role required"
+// @Failure 404 {object} map[string]string "Selection not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /jury/finalists/{id} [get]
+func (h *FinalistHandlers) Get(c *gin.Context) {
+	sel, ok := h.load(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, finalistSelectionView(*sel))
+}
+
+// Decide godoc
+// @Summary Override a finalist decision
+// @Description Select, keep as alternate or exclude a player, with a mandatory reason. Overrides survive recomputation. Not allowed once the results are frozen. Admin and jury only.
+// @Tags Finalists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Selection ID"
+// @Param decision body DecisionRequest true "Decision: selected, alternate or excluded"
+// @Success 200 {object} FinalistDecisionView "Recorded decision"
+// @Failure 400 {object} map[string]string "Bad request - invalid decision"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin or jury role required"
+// @Failure 404 {object} map[string]string "Selection or player not found"
+// @Failure 409 {object} map[string]string "Conflict - results already frozen"
+// @Failure 422 {object} map[string]string "Player not eligible for the tournament"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /jury/finalists/{id}/decisions [post]
+func (h *FinalistHandlers) Decide(c *gin.Context) {
+	ctx := c.Request.Context()
+	juror, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid selection ID"})
+		return
+	}
+	var req DecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	kind := domain.FinalistDecisionKind(req.Decision)
+	if kind != domain.FinalistSelected && kind != domain.FinalistAlternate && kind != domain.FinalistExcluded {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "decision must be selected, alternate or excluded"})
+		return
+	}
+
+	d, err := h.svc.Decide(ctx, id, req.UserID, kind, req.Reason, juror)
+	if err != nil {
+		switch {
+		case errors.Is(err, finalists.ErrReasonRequired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Selection or player not found"})
+		case errors.Is(err, repo.ErrSelectionFrozen):
+			c.JSON(http.StatusConflict, gin.H{"error": "Results are already frozen"})
+		case errors.Is(err, finalists.ErrNotEligible):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Player is not eligible for this tournament"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error recording decision"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, finalistDecisionView(*d))
+}
+
+// Freeze godoc
+// @Summary Freeze and publish finalists
+// @Description Freeze the selection so it can no longer change, publish it on /public/finalists and freeze the final rankings. Admin only.
+// @Tags Finalists
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Selection ID"
+// @Success 200 {object} FinalistSelectionView "Frozen selection"
+// @Failure 400 {object} map[string]string "Bad request - invalid selection ID"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin role required"
+// @Failure 404 {object} map[string]string "Selection not found"
+// @Failure 409 {object} map[string]string "Conflict - already frozen"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/finalists/{id}/freeze [post]
+func (h *FinalistHandlers) Freeze(c *gin.Context) {
+	admin, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid selection ID"})
+		return
+	}
+	sel, err := h.svc.Freeze(c.Request.Context(), id, admin)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Selection not found"})
+		case errors.Is(err, repo.ErrSelectionFrozen):
+			c.JSON(http.StatusConflict, gin.H{"error": "Results are already frozen"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error freezing results"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, finalistSelectionView(*sel))
+}
+
+// Roster godoc
+// @Summary Export the finalist roster
+// @Description Export the selected players of a selection, ordered by city and position, as JSON or CSV. Admin and jury only.
+// @Tags Finalists
+// @Produce json
+// @Produce text/csv
+// @Security BearerAuth
+// @Param id path string true "Selection ID"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {array} FinalistDecisionView "Roster"
+// @Failure 400 {object} map[string]string "Bad request - invalid parameters"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin or jury role required"
+// @Failure 404 {object} map[string]string "Selection not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /jury/finalists/{id}/roster [get]
+func (h *FinalistHandlers) Roster(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+	sel, ok := h.load(c)
+	if !ok {
+		return
+	}
+	roster := finalists.Roster(sel)
+	if format == "json" {
+		c.JSON(http.StatusOK, finalistDecisionViews(roster))
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="roster-%s.csv"`, sel.ID))
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"city", "country", "position", "user_id", "player", "votes", "source", "reason"})
+	for _, d := range roster {
+		_ = w.Write([]string{
+			d.City, d.Country, strconv.Itoa(d.Position), d.UserID.String(), d.Username,
+			strconv.FormatInt(d.Votes, 10), string(d.Source), d.Reason,
+		})
+	}
+	w.Flush()
+}
+
+// Results godoc
+// @Summary Get published finalists
+// @Description Get the frozen finalist roster, grouped by city, of a tournament or, without tournament, of the whole platform.
+// @Tags Public
+// @Produce json
+// @Param tournament query string false "Tournament ID"
+// @Success 200 {object} map[string]interface{} "Published results"
+// @Failure 400 {object} map[string]string "Bad request - invalid tournament ID"
+// @Failure 404 {object} map[string]string "Results not published yet"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /public/finalists [get]
+func (h *FinalistHandlers) Results(c *gin.Context) {
+	ctx := c.Request.Context()
+	tournamentID, err := parseTournament(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sel, err := h.svc.Published(ctx, tournamentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Results not published yet"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving results"})
+		}
+		return
+	}
+
+	type finalist struct {
+		UserID   uuid.UUID `json:"user_id"`
+		Username string    `json:"username"`
+		Country  string    `json:"country"`
+		Position int       `json:"position"`
+		Votes    int64     `json:"votes"`
+	}
+	byCity := map[string][]finalist{}
+	for _, d := range finalists.Roster(sel) {
+		byCity[d.City] = append(byCity[d.City], finalist{d.UserID, d.Username, d.Country, d.Position, d.Votes})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"tournament_id":  sel.TournamentID,
+		"slots_per_city": sel.SlotsPerCity,
+		"published_at":   sel.FrozenAt,
+		"finalists":      byCity,
+	})
+}
+
+func (h *FinalistHandlers) load(c *gin.Context) (*domain.FinalistSelection, bool) {
+	ctx := c.Request.Context()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid selection ID"})
+		return nil, false
+	}
+	sel, err := h.svc.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Selection not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving selection"})
+		}
+		return nil, false
+	}
+	return sel, true
+}
@@ -0,0 +1,173 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/ranking"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+)
+
+type FraudHandlers struct {
+	votes    repo.VoteRepository
+	videos   repo.VideoRepository
+	rankings *ranking.Service
+}
+
+func NewFraudHandlers(votes repo.VoteRepository, videos repo.VideoRepository, rankings *ranking.Service) *FraudHandlers {
+	return &FraudHandlers{votes: votes, videos: videos, rankings: rankings}
+}
+
+// SuspiciousVote is a quarantined vote as shown to reviewers.
+type SuspiciousVote struct {
+	ID           uuid.UUID  `json:"id"`
+	VoterID      uuid.UUID  `json:"voter_id"`
+	VoterEmail   string     `json:"voter_email"`
+	VoterSince   time.Time  `json:"voter_since"`
+	VideoID      uuid.UUID  `json:"video_id"`
+	VideoTitle   string     `json:"video_title"`
+	PlayerID     uuid.UUID  `json:"player_id"`
+	IP           string     `json:"ip"`
+	Subnet       string     `json:"subnet"`
+	UserAgent    string     `json:"user_agent"`
+	Fingerprint  string     `json:"fingerprint"`
+	FraudScore   int        `json:"fraud_score"`
+	FraudReasons []string   `json:"fraud_reasons"`
+	Status       string     `json:"status"`
+	CastAt       time.Time  `json:"cast_at"`
+	ReviewedBy   *uuid.UUID `json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+}
+
+func suspiciousVote(v domain.Vote) SuspiciousVote {
+	reasons := []string{}
+	if v.FraudReasons != "" {
+		reasons = strings.Split(v.FraudReasons, ",")
+	}
+	return SuspiciousVote{
+		ID:           v.ID,
+		VoterID:      v.UserID,
+		VoterEmail:   v.User.Email,
+		VoterSince:   v.User.CreatedAt,
+		VideoID:      v.VideoID,
+		VideoTitle:   v.Video.Title,
+		PlayerID:     v.Video.UserID,
+		IP:           v.IP,
+		Subnet:       v.Subnet,
+		UserAgent:    v.UserAgent,
+		Fingerprint:  v.Fingerprint,
+		FraudScore:   v.FraudScore,
+		FraudReasons: reasons,
+		Status:       string(v.Status),
+		CastAt:       v.CreatedAt,
+		ReviewedBy:   v.ReviewedBy,
+		ReviewedAt:   v.ReviewedAt,
+	}
+}
+
+// ListQuarantined godoc
+// @Summary List quarantined votes
+// @Description List votes held for review by the fraud scorer, most suspicious first. Admin only.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Number of votes to return (default: 50, max: 200)"
+// @Param offset query int false "Number of votes to skip (default: 0)"
+// @Success 200 {array} SuspiciousVote "Quarantined votes"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin role required"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/votes/quarantined [get]
+func (h *FraudHandlers) ListQuarantined(c *gin.Context) {
+	ctx := c.Request.Context()
+	limit, offset := pageParams(c)
+	list, err := h.votes.ListByStatus(ctx, domain.VoteQuarantined, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving quarantined votes"})
+		return
+	}
+	out := make([]SuspiciousVote, 0, len(list))
+	for _, v := range list {
+		out = append(out, suspiciousVote(v))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// ApproveVote godoc
+// @Summary Approve a quarantined vote
+// @Description Count a quarantined vote; it is added to the live rankings. Admin only.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Vote ID"
+// @Success 200 {object} SuspiciousVote "Reviewed vote"
+// @Failure 400 {object} map[string]string "Bad request - invalid vote ID"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin role required"
+// @Failure 404 {object} map[string]string "Vote not found"
+// @Failure 409 {object} map[string]string "Conflict - vote already reviewed"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/votes/{id}/approve [post]
+func (h *FraudHandlers) ApproveVote(c *gin.Context) {
+	h.review(c, domain.VoteCounted)
+}
+
+// RejectVote godoc
+// @Summary Reject a quarantined vote
+// @Description Discard a quarantined vote; it never counts towards the rankings. Admin only.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Vote ID"
+// @Success 200 {object} SuspiciousVote "Reviewed vote"
+// @Failure 400 {object} map[string]string "Bad request - invalid vote ID"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin role required"
+// @Failure 404 {object} map[string]string "Vote not found"
+// @Failure 409 {object} map[string]string "Conflict - vote already reviewed"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/votes/{id}/reject [post]
+func (h *FraudHandlers) RejectVote(c *gin.Context) {
+	h.review(c, domain.VoteRejected)
+}
+
+func (h *FraudHandlers) review(c *gin.Context, status domain.VoteStatus) {
+	ctx := c.Request.Context()
+	reviewer, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vote ID"})
+		return
+	}
+
+	vote, err := h.votes.Review(ctx, id, status, reviewer)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Vote not found"})
+		case errors.Is(err, repo.ErrVoteNotQuarantined):
+			c.JSON(http.StatusConflict, gin.H{"error": "Vote already reviewed"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reviewing vote"})
+		}
+		return
+	}
+
+	if status == domain.VoteCounted {
+		if video, err := h.videos.FindByID(ctx, vote.VideoID); err == nil && video.IsPublicForVote {
+			h.rankings.RecordVote(ctx, video, 1)
+		}
+	}
+	c.JSON(http.StatusOK, suspiciousVote(*vote))
+}
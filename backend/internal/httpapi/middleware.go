@@ -1,13 +1,65 @@
 package httpapi
 
 import (
+	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/auth"
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/logging"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
 )
 
+// RequestIDHeader carries the request ID in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID tags each request with an ID, reusing a well-formed one sent by
+// the client or a proxy. The ID is echoed in the response and attached to the
+// request context, so logs and Kafka tasks produced by the request carry it.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 64 || strings.ContainsAny(id, " \t\r\n") {
+			id = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.With(c.Request.Context(), slog.String("request_id", id)))
+		c.Next()
+	}
+}
+
+// AccessLog logs one line per request, replacing gin's default logger.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		slog.Log(c.Request.Context(), level, "request",
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"path", c.Request.URL.Path,
+			"status", status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"user_id", c.GetString("user_id"),
+		)
+	}
+}
+
 func JWT(secret string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		h := c.GetHeader("Authorization")
@@ -15,21 +67,100 @@ func JWT(secret string) gin.HandlerFunc {
 			c.AbortWithStatus(http.StatusUnauthorized)
 			return
 		}
-		tokenStr := strings.TrimSpace(h[7:])
-		t, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
-		if err != nil || !t.Valid {
-			c.AbortWithStatus(http.StatusUnauthorized)
+		if sub, ok := parseSubject(secret, strings.TrimSpace(h[7:]), ""); ok {
+			c.Set("user_id", sub)
+			c.Next()
 			return
 		}
-		if claims, ok := t.Claims.(jwt.MapClaims); ok {
-			if sub, ok2 := claims["sub"].(string); ok2 {
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}
+}
+
+// OptionalJWT sets user_id when a valid token is present in the
+// Authorization header, and lets anonymous requests through otherwise.
+func OptionalJWT(secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h := c.GetHeader("Authorization"); strings.HasPrefix(strings.ToLower(h), "bearer ") {
+			if sub, ok := parseSubject(secret, strings.TrimSpace(h[7:]), ""); ok {
+				c.Set("user_id", sub)
+			}
+		}
+		c.Next()
+	}
+}
+
+// StreamAuth is OptionalJWT for the live updates stream: EventSource cannot
+// send headers, so it also accepts a stream ticket in the ticket query
+// parameter. Access tokens are never read from the query string, where
+// proxies log them. A ticket that is no longer valid is rejected, so the
+// client asks for a new one instead of silently reconnecting anonymously.
+func StreamAuth(secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ticket := c.Query("ticket"); ticket != "" {
+			sub, ok := parseSubject(secret, ticket, auth.StreamAudience)
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired stream ticket"})
+				return
+			}
+			c.Set("user_id", sub)
+		} else if h := c.GetHeader("Authorization"); strings.HasPrefix(strings.ToLower(h), "bearer ") {
+			if sub, ok := parseSubject(secret, strings.TrimSpace(h[7:]), ""); ok {
 				c.Set("user_id", sub)
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireRole only lets through users with one of the given roles. It must
+// run after JWT; the role is read from the database so that revoking it takes
+// effect immediately.
+func RequireRole(users repo.UserRepository, roles ...domain.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, err := uuid.Parse(c.GetString("user_id"))
+		if err != nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		u, err := users.FindByID(c.Request.Context(), uid)
+		if err != nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		for _, r := range roles {
+			if u.Role == r {
+				c.Set("user_role", string(u.Role))
 				c.Next()
 				return
 			}
 		}
-		c.AbortWithStatus(http.StatusUnauthorized)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	}
+}
+
+// parseSubject returns the subject of a valid token issued for audience; ""
+// is an access token, which carries no audience.
+func parseSubject(secret, tokenStr, audience string) (string, bool) {
+	t, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+	if err != nil || !t.Valid {
+		return "", false
+	}
+	claims, ok := t.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", false
+	}
+	if aud, _ := claims.GetAudience(); !slices.Equal(aud, audienceOf(audience)) {
+		return "", false
+	}
+	sub, ok := claims["sub"].(string)
+	return sub, ok
+}
+
+func audienceOf(audience string) jwt.ClaimStrings {
+	if audience == "" {
+		return nil
 	}
+	return jwt.ClaimStrings{audience}
 }
@@ -0,0 +1,266 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/moderation"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+)
+
+type ModerationHandlers struct {
+	svc *moderation.Service
+}
+
+func NewModerationHandlers(svc *moderation.Service) *ModerationHandlers {
+	return &ModerationHandlers{svc: svc}
+}
+
+// ModerationRequest carries the reason of a rejection or re-upload request.
+type ModerationRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ReportRequest is a user's complaint about a video.
+type ReportRequest struct {
+	Reason  string `json:"reason" binding:"required"` // p.ej. "offensive", "not_basketball", "copyright"
+	Details string `json:"details"`
+}
+
+// QueuedVideo is a video waiting for review.
+type QueuedVideo struct {
+	OwnerVideoView
+	OpenReports int64 `json:"open_reports"`
+}
+
+// Queue godoc
+// @Summary Moderation queue
+// @Description List processed videos waiting for review before they become votable, oldest first, with their number of open user reports. Admin and jury only.
+// @Tags Moderation
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Number of videos to return (default: 50, max: 200)"
+// @Param offset query int false "Number of videos to skip (default: 0)"
+// @Success 200 {array} QueuedVideo "Videos pending review"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin or jury role required"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /jury/moderation/queue [get]
+func (h *ModerationHandlers) Queue(c *gin.Context) {
+	ctx := c.Request.Context()
+	limit, offset := pageParams(c)
+	list, reports, err := h.svc.Queue(ctx, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving moderation queue"})
+		return
+	}
+	out := make([]QueuedVideo, 0, len(list))
+	for _, v := range list {
+		out = append(out, QueuedVideo{OwnerVideoView: ownerVideoView(v), OpenReports: reports[v.ID]})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Reports godoc
+// @Summary Open video reports
+// @Description List user reports not yet resolved by a moderation decision, oldest first. Admin and jury only.
+// @Tags Moderation
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Number of reports to return (default: 50, max: 200)"
+// @Param offset query int false "Number of reports to skip (default: 0)"
+// @Success 200 {array} ReportView "Open reports"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin or jury role required"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /jury/moderation/reports [get]
+func (h *ModerationHandlers) Reports(c *gin.Context) {
+	ctx := c.Request.Context()
+	limit, offset := pageParams(c)
+	list, err := h.svc.Reports(ctx, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving reports"})
+		return
+	}
+	out := make([]ReportView, 0, len(list))
+	for _, r := range list {
+		out = append(out, reportView(r))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Approve godoc
+// @Summary Approve a video
+// @Description Publish a video pending review so it can be voted. Open reports are dismissed. Admin and jury only.
+// @Tags Moderation
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Video ID"
+// @Success 200 {object} OwnerVideoView "Published video"
+// @Failure 400 {object} map[string]string "Bad request - invalid video ID"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin or jury role required"
+// @Failure 404 {object} map[string]string "Video not found"
+// @Failure 409 {object} map[string]string "Conflict - video is not pending review"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /jury/moderation/videos/{id}/approve [post]
+func (h *ModerationHandlers) Approve(c *gin.Context) {
+	moderator, id, ok := moderationTarget(c)
+	if !ok {
+		return
+	}
+	v, err := h.svc.Approve(c.Request.Context(), id, moderator)
+	respondModeration(c, v, err)
+}
+
+// Reject godoc
+// @Summary Reject a video
+// @Description Keep a video pending review out of voting, with a reason shown to its owner. Open reports are upheld. Admin and jury only.
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Video ID"
+// @Param request body ModerationRequest true "Reason"
+// @Success 200 {object} OwnerVideoView "Rejected video"
+// @Failure 400 {object} map[string]string "Bad request - invalid video ID or missing reason"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin or jury role required"
+// @Failure 404 {object} map[string]string "Video not found"
+// @Failure 409 {object} map[string]string "Conflict - video is not pending review"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /jury/moderation/videos/{id}/reject [post]
+func (h *ModerationHandlers) Reject(c *gin.Context) {
+	moderator, id, ok := moderationTarget(c)
+	if !ok {
+		return
+	}
+	var req ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+	v, err := h.svc.Reject(c.Request.Context(), id, moderator, req.Reason)
+	respondModeration(c, v, err)
+}
+
+// RequestReupload godoc
+// @Summary Request a re-upload
+// @Description Send a video pending review back to its owner, with a reason, asking for a new file. The video frees its tournament slot. Admin and jury only.
+// @Tags Moderation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Video ID"
+// @Param request body ModerationRequest true "Reason"
+// @Success 200 {object} OwnerVideoView "Video awaiting re-upload"
+// @Failure 400 {object} map[string]string "Bad request - invalid video ID or missing reason"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin or jury role required"
+// @Failure 404 {object} map[string]string "Video not found"
+// @Failure 409 {object} map[string]string "Conflict - video is not pending review"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /jury/moderation/videos/{id}/request-reupload [post]
+func (h *ModerationHandlers) RequestReupload(c *gin.Context) {
+	moderator, id, ok := moderationTarget(c)
+	if !ok {
+		return
+	}
+	var req ModerationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+	v, err := h.svc.RequestReupload(c.Request.Context(), id, moderator, req.Reason)
+	respondModeration(c, v, err)
+}
+
+// Report godoc
+// @Summary Report a video
+// @Description Report a published video as inappropriate. Videos reaching the configured number of open reports are hidden until a moderator reviews them. One report per user per video.
+// @Tags Public
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Video ID"
+// @Param request body ReportRequest true "Report"
+// @Success 201 {object} map[string]string "Report received"
+// @Failure 400 {object} map[string]string "Bad request - invalid video ID, missing reason or video not published"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 404 {object} map[string]string "Video not found"
+// @Failure 409 {object} map[string]string "Conflict - already reported"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /public/videos/{id}/report [post]
+func (h *ModerationHandlers) Report(c *gin.Context) {
+	reporter, id, ok := moderationTarget(c)
+	if !ok {
+		return
+	}
+	var req ReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+	err := h.svc.Report(c.Request.Context(), id, reporter, req.Reason, req.Details)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, gin.H{"message": "Report received. Thank you."})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+	case errors.Is(err, moderation.ErrNotReportable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only published videos can be reported"})
+	case errors.Is(err, repo.ErrDuplicateReport):
+		c.JSON(http.StatusConflict, gin.H{"error": "Already reported this video"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error recording report"})
+	}
+}
+
+// moderationTarget reads the acting user and the video ID from the request.
+func moderationTarget(c *gin.Context) (userID, videoID uuid.UUID, ok bool) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	videoID, err = uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, videoID, true
+}
+
+func respondModeration(c *gin.Context, v *domain.Video, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, ownerVideoView(*v))
+	case errors.Is(err, moderation.ErrReasonRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+	case errors.Is(err, moderation.ErrNotReviewable), errors.Is(err, repo.ErrStaleStatus):
+		c.JSON(http.StatusConflict, gin.H{"error": "Video is not pending review"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error moderating video"})
+	}
+}
+
+// pageParams reads limit (default 50, max 200) and offset.
+func pageParams(c *gin.Context) (limit, offset int) {
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
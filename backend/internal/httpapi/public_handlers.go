@@ -1,69 +1,114 @@
 package httpapi
 
 import (
-	"context"
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
-	"github.com/Cloud-2025-2/anb-platform/internal/cache"
 	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/fraud"
+	"github.com/Cloud-2025-2/anb-platform/internal/metrics"
+	"github.com/Cloud-2025-2/anb-platform/internal/ranking"
 	"github.com/Cloud-2025-2/anb-platform/internal/repo"
 )
 
 type PublicHandlers struct {
-	videos repo.VideoRepository
-	votes  repo.VoteRepository
-	users  repo.UserRepository
-	cache  *cache.RankingsCache
+	videos   repo.VideoRepository
+	votes    repo.VoteRepository
+	users    repo.UserRepository
+	rankings *ranking.Service
+	fraud    *fraud.Scorer
+	quota    repo.VoteQuota
+
+	tournaments repo.TournamentRepository
 }
 
-func NewPublicHandlers(videos repo.VideoRepository, votes repo.VoteRepository, users repo.UserRepository, cache *cache.RankingsCache) *PublicHandlers {
-	return &PublicHandlers{videos: videos, votes: votes, users: users, cache: cache}
+func NewPublicHandlers(videos repo.VideoRepository, votes repo.VoteRepository, users repo.UserRepository, tournaments repo.TournamentRepository, rankings *ranking.Service, scorer *fraud.Scorer, quota repo.VoteQuota) *PublicHandlers {
+	return &PublicHandlers{videos: videos, votes: votes, users: users, tournaments: tournaments, rankings: rankings, fraud: scorer, quota: quota}
 }
 
 // ListVideos godoc
 // @Summary List public videos
-// @Description Get all videos available for public voting
+// @Description Get the videos available for public voting, with their counted votes. Supports full-text search on the title and the player's name, filters and keyset pagination: pass the X-Next-Cursor response header as cursor to get the next page (the header is absent on the last page). With a valid token each video tells whether the current user voted for it (voted_by_me).
 // @Tags Public
 // @Produce json
-// @Param limit query int false "Number of videos to return (default: 20)"
-// @Param offset query int false "Number of videos to skip (default: 0)"
-// @Success 200 {array} domain.Video "List of public videos"
+// @Param q query string false "Search the title and the player's name"
+// @Param city query string false "Filter by the player's city"
+// @Param country query string false "Filter by the player's country"
+// @Param tournament query string false "Only videos submitted to this tournament"
+// @Param sort query string false "newest (default), votes or trending (most votes in the last 24 hours)"
+// @Param limit query int false "Number of videos to return (default: 20, max: 100)"
+// @Param cursor query string false "Cursor from X-Next-Cursor of the previous page"
+// @Success 200 {array} VideoView "List of public videos"
+// @Header 200 {string} X-Next-Cursor "Cursor of the next page"
 // @Failure 400 {object} map[string]string "Bad request - invalid query parameters"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /public/videos [get]
 func (h *PublicHandlers) ListVideos(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
-	list, err := h.videos.ListPublic(limit, offset)
+	ctx := c.Request.Context()
+	q, err := parseVideoSearch(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	list, next, err := h.videos.ListPublic(ctx, q)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving public videos"})
+		if errors.Is(err, repo.ErrInvalidVideoCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving public videos"})
+		}
 		return
 	}
-	c.JSON(http.StatusOK, list)
+	if next != nil {
+		c.Header("X-Next-Cursor", next.Encode())
+	}
+
+	if uid, err := uuid.Parse(c.GetString("user_id")); err == nil && len(list) > 0 {
+		ids := make([]uuid.UUID, len(list))
+		for i := range list {
+			ids[i] = list[i].ID
+		}
+		voted, err := h.votes.VotedVideoIDs(ctx, uid, ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving public videos"})
+			return
+		}
+		for i := range list {
+			list[i].VotedByMe = voted[list[i].ID]
+		}
+	}
+	out := make([]VideoView, 0, len(list))
+	for _, v := range list {
+		out = append(out, videoView(v))
+	}
+	c.JSON(http.StatusOK, out)
 }
 
 // Vote godoc
 // @Summary Vote for a video
-// @Description Cast a vote for a public video. Requires authentication. One vote per user per video.
+// @Description Cast a vote for a public video. Requires authentication. One vote per user per video, and at most the configured quota of votes per city. Videos submitted to a tournament can only be voted during its voting phase, within the tournament's own vote quota. Votes are screened for fraud (IP/subnet bursts, fresh accounts, shared devices); suspicious ones are held for admin review before they count. Clients may send a stable device identifier in X-Device-Fingerprint.
 // @Tags Public
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Video ID"
+// @Param X-Device-Fingerprint header string false "Stable device identifier"
 // @Success 200 {object} map[string]string "Vote registered successfully"
 // @Failure 400 {object} map[string]string "Bad request - already voted or invalid video ID"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
-// @Failure 403 {object} map[string]string "Forbidden - cannot vote on own video"
+// @Failure 403 {object} map[string]string "Forbidden - vote quota reached or tournament voting not open"
 // @Failure 404 {object} map[string]string "Video not found or not available for voting"
 // @Failure 409 {object} map[string]string "Conflict - duplicate vote"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /public/videos/{id}/vote [post]
 func (h *PublicHandlers) Vote(c *gin.Context) {
+	ctx := c.Request.Context()
 	uid, err := uuid.Parse(c.GetString("user_id"))
 	if err != nil { 
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
@@ -77,7 +122,7 @@ func (h *PublicHandlers) Vote(c *gin.Context) {
 	}
 
 	// Check if video exists and is public for voting
-	video, err := h.videos.FindByID(vid)
+	video, err := h.videos.FindByID(ctx, vid)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
@@ -90,58 +135,456 @@ func (h *PublicHandlers) Vote(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Video not available for voting"})
 		return
 	}
+	quota := h.quota
+	if t := video.Tournament; t != nil {
+		if !t.VotingOpen(time.Now()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Voting is not open for this tournament"})
+			return
+		}
+		quota.PerTournament = t.VotesPerUser
+	}
+
+	voter, err := h.users.FindByID(ctx, uid)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
+		return
+	}
+
+	vote := &domain.Vote{
+		UserID:      uid,
+		VideoID:     vid,
+		IP:          c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		Fingerprint: c.GetHeader("X-Device-Fingerprint"),
+	}
+	h.fraud.Screen(ctx, voter, video, vote)
 
-	if err := h.votes.CastOnce(uid, vid); err != nil {
+	if err := h.votes.CastOnce(ctx, vote, quota); err != nil {
 		if errors.Is(err, repo.ErrDuplicateVote) {
 			c.JSON(http.StatusConflict, gin.H{"error": "Already voted for this video"})
 			return
 		}
+		if errors.Is(err, repo.ErrVoteQuotaExceeded) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Vote quota reached; remove a vote to change it"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 		return
 	}
+	metrics.VotesCast.WithLabelValues(string(vote.Status)).Inc()
 	
-	// Invalidate rankings cache since vote counts have changed
-	ctx := context.Background()
-	_ = h.cache.InvalidateAll(ctx) // Ignore errors to avoid blocking the response
-	
+	// Update the live leaderboard incrementally instead of invalidating cached rankings.
+	// Quarantined votes only count once an admin approves them; the response is the
+	// same so that fraudsters cannot probe the scorer.
+	if vote.Status == domain.VoteCounted {
+		h.rankings.RecordVote(ctx, video, 1)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Vote registered successfully"})
 }
 
+// Unvote godoc
+// @Summary Remove a vote
+// @Description Take back the vote the current user cast for a video, freeing up quota to vote for another one.
+// @Tags Public
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Video ID"
+// @Success 200 {object} map[string]string "Vote removed successfully"
+// @Failure 400 {object} map[string]string "Bad request - invalid video ID"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - tournament voting is closed or video withdrawn"
+// @Failure 404 {object} map[string]string "No vote for this video"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /public/videos/{id}/vote [delete]
+func (h *PublicHandlers) Unvote(c *gin.Context) {
+	ctx := c.Request.Context()
+	uid, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
+		return
+	}
+
+	vid, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	video, err := h.videos.FindByID(ctx, vid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving video"})
+		}
+		return
+	}
+	// Votes are frozen once the tournament's voting closes
+	if video.Tournament != nil && video.Tournament.Phase(time.Now()) == domain.TournamentClosed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Voting is closed for this tournament"})
+		return
+	}
+	// Votes of withdrawn and archived videos are kept for audit
+	if video.Status == domain.VideoUnpublished || video.Status == domain.VideoArchived {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Video has been withdrawn from voting"})
+		return
+	}
+
+	vote, err := h.votes.Retract(ctx, uid, vid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "You have not voted for this video"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		}
+		return
+	}
+
+	// Only counted votes are on the leaderboard
+	if vote.Status == domain.VoteCounted && video.IsPublicForVote {
+		h.rankings.RecordVote(ctx, video, -1)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vote removed successfully"})
+}
+
+// CastVote is a vote of the current user.
+type CastVote struct {
+	VideoID    uuid.UUID `json:"video_id"`
+	Title      string    `json:"title"`
+	PlayerID   uuid.UUID `json:"player_id"`
+	PlayerName string    `json:"player_name"`
+	City       string    `json:"city"`
+	VotedAt    time.Time `json:"voted_at"`
+}
+
+// MyVotes godoc
+// @Summary List my votes
+// @Description Get the videos the current user has voted for, newest first, and the remaining vote quota per city when one is configured.
+// @Tags Public
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Votes and quota"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /votes [get]
+func (h *PublicHandlers) MyVotes(c *gin.Context) {
+	ctx := c.Request.Context()
+	uid, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
+		return
+	}
+
+	list, err := h.votes.ListByVoter(ctx, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving votes"})
+		return
+	}
+
+	out := make([]CastVote, 0, len(list))
+	perCity := map[string]int{}
+	for _, v := range list {
+		out = append(out, CastVote{
+			VideoID:    v.VideoID,
+			Title:      v.Video.Title,
+			PlayerID:   v.Video.UserID,
+			PlayerName: v.Video.User.FirstName + " " + v.Video.User.LastName,
+			City:       v.Video.User.City,
+			VotedAt:    v.CreatedAt,
+		})
+		perCity[v.Video.User.City]++
+	}
+
+	resp := gin.H{"votes": out}
+	if h.quota.PerCity > 0 {
+		remaining := make(map[string]int, len(perCity))
+		for city, n := range perCity {
+			remaining[city] = max(h.quota.PerCity-n, 0)
+		}
+		resp["quota_per_city"] = h.quota.PerCity
+		resp["remaining_by_city"] = remaining
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
 // Rankings godoc
-// @Summary Get player rankings
-// @Description Get current player rankings based on votes. Can be filtered by city. Results are cached for improved performance.
+// @Summary Get rankings
+// @Description Get current rankings based on votes, per player (default) or per video, across the platform or within one tournament. Tied entries share the same position and are ordered by ID. Served from a live Redis leaderboard updated on every vote. When more rows are available the X-Next-Cursor header holds the cursor of the next page.
 // @Tags Public
 // @Produce json
-// @Param limit query int false "Number of rankings to return (default: 50)"
+// @Param scope query string false "What to rank: player or video (default: player)"
+// @Param limit query int false "Number of rankings to return (default: 50, max: 100)"
+// @Param tournament query string false "Only rank videos submitted to this tournament"
 // @Param city query string false "Filter by city"
-// @Success 200 {array} map[string]interface{} "Player rankings"
+// @Param country query string false "Filter by country"
+// @Param window query string false "Named time window: all (default), today, week or tournament (the tournament's voting phase; requires tournament)"
+// @Param from query string false "Only count votes cast from this instant (RFC3339 or YYYY-MM-DD)"
+// @Param to query string false "Only count votes cast before this instant (RFC3339 or YYYY-MM-DD)"
+// @Param cursor query string false "Cursor returned in X-Next-Cursor by the previous page"
+// @Success 200 {array} RankingRowView "Rankings"
 // @Failure 400 {object} map[string]string "Bad request - invalid query parameters"
+// @Failure 404 {object} map[string]string "Tournament not found"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /public/rankings [get]
 func (h *PublicHandlers) Rankings(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-	city := c.DefaultQuery("city", "")
-	
-	ctx := context.Background()
-	
-	// Try to get from cache first
-	if cachedRankings, found := h.cache.GetRankings(ctx, limit, city); found {
-		c.JSON(http.StatusOK, cachedRankings)
+	ctx := c.Request.Context()
+	q, err := parseRankingQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	
-	// Cache miss - get from database
-	rows, err := h.votes.TopByCity(limit, city)
+	if c.Query("window") == "tournament" {
+		t, err := h.tournaments.FindByID(ctx, *q.TournamentID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving tournament"})
+			}
+			return
+		}
+		q.From, q.To = &t.VotingOpensAt, &t.VotingClosesAt
+	}
+
+	rows, err := h.rankings.Top(ctx, q)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving rankings"})
 		return
 	}
-	
-	// Store in cache (ignore errors to avoid blocking the response)
-	// Note: We'll skip caching for now since the data structure changed
-	// _ = h.cache.SetRankings(ctx, limit, city, rankings)
-	
-	c.JSON(http.StatusOK, rows)
+
+	if len(rows) == q.Limit {
+		c.Header("X-Next-Cursor", repo.CursorAfter(rows[len(rows)-1]).Encode())
+	}
+	c.JSON(http.StatusOK, rankingRowViews(rows))
+}
+
+func parseRankingQuery(c *gin.Context) (repo.RankingQuery, error) {
+	q := repo.RankingQuery{
+		Scope:   repo.RankingScope(c.DefaultQuery("scope", string(repo.RankingByPlayer))),
+		City:    c.Query("city"),
+		Country: c.Query("country"),
+	}
+	if q.Scope != repo.RankingByPlayer && q.Scope != repo.RankingByVideo {
+		return q, errors.New("scope must be player or video")
+	}
+	tournamentID, err := parseTournament(c)
+	if err != nil {
+		return q, err
+	}
+	q.TournamentID = tournamentID
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		return q, errors.New("limit must be a positive integer")
+	}
+	q.Limit = min(limit, 100)
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := repo.DecodeRankingCursor(cursor)
+		if err != nil {
+			return q, err
+		}
+		q.After = after
+	}
+
+	if c.Query("window") == "tournament" {
+		if q.TournamentID == nil {
+			return q, errors.New("window=tournament requires tournament")
+		}
+		if c.Query("from") != "" || c.Query("to") != "" {
+			return q, errors.New("window cannot be combined with from/to")
+		}
+		return q, nil // the caller resolves the tournament's voting phase
+	}
+	from, to, err := parseWindow(c)
+	if err != nil {
+		return q, err
+	}
+	q.From, q.To = from, to
+	return q, nil
+}
+
+func parseVideoSearch(c *gin.Context) (repo.VideoSearch, error) {
+	q := repo.VideoSearch{
+		Query:   strings.TrimSpace(c.Query("q")),
+		City:    c.Query("city"),
+		Country: c.Query("country"),
+		Sort:    repo.VideoSort(c.DefaultQuery("sort", string(repo.VideoSortNewest))),
+	}
+	switch q.Sort {
+	case repo.VideoSortNewest, repo.VideoSortVotes, repo.VideoSortTrending:
+	default:
+		return q, errors.New("sort must be newest, votes or trending")
+	}
+	tournamentID, err := parseTournament(c)
+	if err != nil {
+		return q, err
+	}
+	q.TournamentID = tournamentID
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		return q, errors.New("limit must be a positive integer")
+	}
+	q.Limit = min(limit, 100)
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := repo.DecodeVideoCursor(cursor)
+		if err != nil {
+			return q, err
+		}
+		q.After = after
+	}
+	return q, nil
+}
+
+func parseTournament(c *gin.Context) (*uuid.UUID, error) {
+	raw := c.Query("tournament")
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, errors.New("invalid tournament ID")
+	}
+	return &id, nil
+}
+
+// parseWindow resolves either a named window or explicit from/to bounds.
+func parseWindow(c *gin.Context) (from, to *time.Time, err error) {
+	window := c.DefaultQuery("window", "all")
+	if window != "all" && (c.Query("from") != "" || c.Query("to") != "") {
+		return nil, nil, errors.New("window cannot be combined with from/to")
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch window {
+	case "all":
+	case "today":
+		return &today, nil, nil
+	case "week":
+		// Weeks start on Monday
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		return &monday, nil, nil
+	default:
+		return nil, nil, errors.New("window must be all, today, week or tournament")
+	}
+
+	if from, err = parseInstant(c.Query("from")); err != nil {
+		return nil, nil, errors.New("from must be RFC3339 or YYYY-MM-DD")
+	}
+	if to, err = parseInstant(c.Query("to")); err != nil {
+		return nil, nil, errors.New("to must be RFC3339 or YYYY-MM-DD")
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		return nil, nil, errors.New("from must be before to")
+	}
+	return from, to, nil
+}
+
+func parseInstant(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RankingHistory godoc
+// @Summary Get ranking history
+// @Description Get the positions of one player or video across ranking snapshots, oldest first, for "rank over time" charts.
+// @Tags Public
+// @Produce json
+// @Param id query string true "Player ID (scope=player) or video ID (scope=video)"
+// @Param scope query string false "player or video (default: player)"
+// @Param tournament query string false "Board of this tournament"
+// @Param city query string false "Board filtered by city"
+// @Param country query string false "Board filtered by country"
+// @Param from query string false "Only snapshots taken from this instant (RFC3339 or YYYY-MM-DD)"
+// @Param to query string false "Only snapshots taken before this instant (RFC3339 or YYYY-MM-DD)"
+// @Success 200 {array} repo.HistoryPoint "Position history"
+// @Failure 400 {object} map[string]string "Bad request - invalid query parameters"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /public/rankings/history [get]
+func (h *PublicHandlers) RankingHistory(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := uuid.Parse(c.Query("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid id"})
+		return
+	}
+	tournamentID, err := parseTournament(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q := repo.RankingQuery{
+		Scope:        repo.RankingScope(c.DefaultQuery("scope", string(repo.RankingByPlayer))),
+		TournamentID: tournamentID,
+		City:         c.Query("city"),
+		Country:      c.Query("country"),
+	}
+	from, errFrom := parseInstant(c.Query("from"))
+	to, errTo := parseInstant(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from/to must be RFC3339 or YYYY-MM-DD"})
+		return
+	}
+
+	points, err := h.rankings.History(ctx, q, id, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving ranking history"})
+		return
+	}
+	c.JSON(http.StatusOK, points)
+}
+
+// FinalRankings godoc
+// @Summary Get final standings
+// @Description Get the standings frozen by the latest final snapshot, taken when voting closed.
+// @Tags Public
+// @Produce json
+// @Param scope query string false "player or video (default: player)"
+// @Param tournament query string false "Standings of this tournament"
+// @Param city query string false "Filter by city"
+// @Param country query string false "Filter by country"
+// @Success 200 {object} FinalRankingsView "Frozen standings with the snapshot timestamp"
+// @Failure 400 {object} map[string]string "Bad request - invalid tournament ID"
+// @Failure 404 {object} map[string]string "Final standings not published yet"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /public/rankings/final [get]
+func (h *PublicHandlers) FinalRankings(c *gin.Context) {
+	ctx := c.Request.Context()
+	tournamentID, err := parseTournament(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	q := repo.RankingQuery{
+		Scope:        repo.RankingScope(c.DefaultQuery("scope", string(repo.RankingByPlayer))),
+		TournamentID: tournamentID,
+		City:         c.Query("city"),
+		Country:      c.Query("country"),
+	}
+	snap, rows, err := h.rankings.Final(ctx, q)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Final standings not published yet"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving final standings"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, FinalRankingsView{FrozenAt: snap.TakenAt, Rankings: rankingRowViews(rows)})
 }
 
 // GetCities godoc
@@ -153,7 +596,8 @@ func (h *PublicHandlers) Rankings(c *gin.Context) {
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /public/cities [get]
 func (h *PublicHandlers) GetCities(c *gin.Context) {
-	cities, err := h.users.GetDistinctCities()
+	ctx := c.Request.Context()
+	cities, err := h.users.GetDistinctCities(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving cities"})
 		return
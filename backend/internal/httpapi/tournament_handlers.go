@@ -0,0 +1,220 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+)
+
+type TournamentHandlers struct {
+	tournaments repo.TournamentRepository
+}
+
+func NewTournamentHandlers(tournaments repo.TournamentRepository) *TournamentHandlers {
+	return &TournamentHandlers{tournaments: tournaments}
+}
+
+// TournamentView is a tournament together with its current phase.
+type TournamentView struct {
+	ID                   uuid.UUID              `json:"id"`
+	Name                 string                 `json:"name"`
+	Description          string                 `json:"description,omitempty"`
+	RegistrationOpensAt  time.Time              `json:"registration_opens_at"`
+	RegistrationClosesAt time.Time              `json:"registration_closes_at"`
+	VotingOpensAt        time.Time              `json:"voting_opens_at"`
+	VotingClosesAt       time.Time              `json:"voting_closes_at"`
+	EligibleCities       []string               `json:"eligible_cities"`
+	EligibleCountries    []string               `json:"eligible_countries"`
+	MaxEntriesPerPlayer  int                    `json:"max_entries_per_player"`
+	VotesPerUser         int                    `json:"votes_per_user"`
+	Phase                domain.TournamentPhase `json:"phase"`
+}
+
+func tournamentView(t domain.Tournament) TournamentView {
+	return TournamentView{
+		ID:                   t.ID,
+		Name:                 t.Name,
+		Description:          t.Description,
+		RegistrationOpensAt:  t.RegistrationOpensAt,
+		RegistrationClosesAt: t.RegistrationClosesAt,
+		VotingOpensAt:        t.VotingOpensAt,
+		VotingClosesAt:       t.VotingClosesAt,
+		EligibleCities:       append([]string{}, t.EligibleCities...),
+		EligibleCountries:    append([]string{}, t.EligibleCountries...),
+		MaxEntriesPerPlayer:  t.MaxEntriesPerPlayer,
+		VotesPerUser:         t.VotesPerUser,
+		Phase:                t.Phase(time.Now()),
+	}
+}
+
+// TournamentRequest creates or replaces a tournament.
+type TournamentRequest struct {
+	Name                 string    `json:"name" binding:"required"`
+	Description          string    `json:"description"`
+	RegistrationOpensAt  time.Time `json:"registration_opens_at" binding:"required"`
+	RegistrationClosesAt time.Time `json:"registration_closes_at" binding:"required"`
+	VotingOpensAt        time.Time `json:"voting_opens_at" binding:"required"`
+	VotingClosesAt       time.Time `json:"voting_closes_at" binding:"required"`
+	EligibleCities       []string  `json:"eligible_cities"`
+	EligibleCountries    []string  `json:"eligible_countries"`
+	MaxEntriesPerPlayer  int       `json:"max_entries_per_player"`
+	VotesPerUser         int       `json:"votes_per_user"`
+}
+
+func (req TournamentRequest) apply(t *domain.Tournament) {
+	t.Name = req.Name
+	t.Description = req.Description
+	t.RegistrationOpensAt = req.RegistrationOpensAt
+	t.RegistrationClosesAt = req.RegistrationClosesAt
+	t.VotingOpensAt = req.VotingOpensAt
+	t.VotingClosesAt = req.VotingClosesAt
+	t.EligibleCities = req.EligibleCities
+	t.EligibleCountries = req.EligibleCountries
+	t.MaxEntriesPerPlayer = req.MaxEntriesPerPlayer
+	if t.MaxEntriesPerPlayer == 0 {
+		t.MaxEntriesPerPlayer = 1
+	}
+	t.VotesPerUser = req.VotesPerUser
+}
+
+// List godoc
+// @Summary List tournaments
+// @Description Get every tournament with its current phase (upcoming, registration, voting or closed)
+// @Tags Tournaments
+// @Produce json
+// @Success 200 {array} TournamentView "Tournaments"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /public/tournaments [get]
+func (h *TournamentHandlers) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	list, err := h.tournaments.List(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving tournaments"})
+		return
+	}
+	out := make([]TournamentView, 0, len(list))
+	for _, t := range list {
+		out = append(out, tournamentView(t))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Get godoc
+// @Summary Get a tournament
+// @Description Get one tournament with its current phase
+// @Tags Tournaments
+// @Produce json
+// @Param id path string true "Tournament ID"
+// @Success 200 {object} TournamentView "Tournament"
+// @Failure 400 {object} map[string]string "Bad request - invalid tournament ID"
+// @Failure 404 {object} map[string]string "Tournament not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /public/tournaments/{id} [get]
+func (h *TournamentHandlers) Get(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tournament ID"})
+		return
+	}
+	t, err := h.tournaments.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving tournament"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, tournamentView(*t))
+}
+
+// Create godoc
+// @Summary Create a tournament
+// @Description Create a tournament with its registration and voting windows. Empty eligible cities/countries mean everyone can enter. Admin only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param tournament body TournamentRequest true "Tournament"
+// @Success 201 {object} TournamentView "Tournament created"
+// @Failure 400 {object} map[string]string "Bad request - invalid tournament"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin role required"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/tournaments [post]
+func (h *TournamentHandlers) Create(c *gin.Context) {
+	ctx := c.Request.Context()
+	var req TournamentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var t domain.Tournament
+	req.apply(&t)
+	if err := t.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.tournaments.Create(ctx, &t); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating tournament"})
+		return
+	}
+	c.JSON(http.StatusCreated, tournamentView(t))
+}
+
+// Update godoc
+// @Summary Update a tournament
+// @Description Replace the settings of a tournament, e.g. to extend its voting window. Admin only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Tournament ID"
+// @Param tournament body TournamentRequest true "Tournament"
+// @Success 200 {object} TournamentView "Tournament updated"
+// @Failure 400 {object} map[string]string "Bad request - invalid tournament"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 403 {object} map[string]string "Forbidden - admin role required"
+// @Failure 404 {object} map[string]string "Tournament not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/tournaments/{id} [put]
+func (h *TournamentHandlers) Update(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tournament ID"})
+		return
+	}
+	var req TournamentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t, err := h.tournaments.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving tournament"})
+		}
+		return
+	}
+	req.apply(t)
+	if err := t.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.tournaments.Update(ctx, t); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating tournament"})
+		return
+	}
+	c.JSON(http.StatusOK, tournamentView(*t))
+}
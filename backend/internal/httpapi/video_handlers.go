@@ -2,15 +2,19 @@ package httpapi
 
 import (
 	"errors"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
 	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/metrics"
+	"github.com/Cloud-2025-2/anb-platform/internal/processing"
 	"github.com/Cloud-2025-2/anb-platform/internal/repo"
 	vidsvc "github.com/Cloud-2025-2/anb-platform/internal/video"
 )
@@ -34,28 +38,41 @@ func NewVideoHandlers(users repo.UserRepository, videos repo.VideoRepository, sv
 // @Security BearerAuth
 // @Param video_file formData file true "Video file (MP4, max 100MB)"
 // @Param title formData string true "Video title"
+// @Param tournament_id formData string false "Tournament to submit the video to; it must be in its registration phase"
 // @Success 201 {object} map[string]interface{} "Video uploaded successfully"
 // @Failure 400 {object} map[string]string "Bad request - file validation error"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
-// @Failure 403 {object} map[string]string "Forbidden - user not allowed to upload"
+// @Failure 403 {object} map[string]string "Forbidden - user not allowed to upload, not eligible or out of entries for the tournament"
+// @Failure 404 {object} map[string]string "Tournament not found"
+// @Failure 409 {object} map[string]string "Conflict - tournament registration is closed"
 // @Failure 413 {object} map[string]string "Request entity too large - file exceeds 100MB"
 // @Failure 415 {object} map[string]string "Unsupported media type - invalid file format"
 // @Failure 422 {object} map[string]string "Unprocessable entity - missing required fields"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /videos/upload [post]
 func (h *VideoHandlers) Upload(c *gin.Context) {
+	ctx := c.Request.Context()
 	uid, err := uuid.Parse(c.GetString("user_id"))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
 		return
 	}
-	u, err := h.users.FindByID(uid)
+	u, err := h.users.FindByID(ctx, uid)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 		return
 	}
 
 	title := c.PostForm("title")
+	var tournamentID *uuid.UUID
+	if raw := c.PostForm("tournament_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tournament ID"})
+			return
+		}
+		tournamentID = &id
+	}
 	file, err := c.FormFile("video_file")
 	if err != nil || file.Size == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "video_file required"})
@@ -73,11 +90,23 @@ func (h *VideoHandlers) Upload(c *gin.Context) {
 	}
 	defer os.Remove(tmp)
 
-	taskID, videoID, err := h.svc.UploadAndEnqueue(*u, tmp, title)
+	taskID, videoID, err := h.svc.UploadAndEnqueue(ctx, *u, tmp, title, tournamentID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing video"})
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tournament not found"})
+		case errors.Is(err, vidsvc.ErrRegistrationClosed):
+			c.JSON(http.StatusConflict, gin.H{"error": "Tournament registration is closed"})
+		case errors.Is(err, vidsvc.ErrNotEligible):
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not eligible for this tournament"})
+		case errors.Is(err, vidsvc.ErrTooManyEntries):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Maximum entries for this tournament reached"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing video"})
+		}
 		return
 	}
+	metrics.UploadBytes.Add(float64(file.Size))
 	c.JSON(http.StatusCreated, gin.H{
 		"message":  "Video uploaded successfully. Processing in progress.",
 		"task_id":  taskID,
@@ -91,23 +120,24 @@ func (h *VideoHandlers) Upload(c *gin.Context) {
 // @Tags Videos
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {array} domain.Video "List of user's videos"
+// @Success 200 {array} OwnerVideoView "List of user's videos"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
 // @Failure 403 {object} map[string]string "Forbidden - access denied"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /videos [get]
 func (h *VideoHandlers) MyVideos(c *gin.Context) {
+	ctx := c.Request.Context()
 	uid, err := uuid.Parse(c.GetString("user_id"))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
 		return
 	}
-	list, err := h.videos.FindByUser(uid)
+	list, err := h.videos.FindByUser(ctx, uid)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving videos"})
 		return
 	}
-	c.JSON(http.StatusOK, list)
+	c.JSON(http.StatusOK, ownerVideoViews(list))
 }
 
 // Detail godoc
@@ -117,7 +147,7 @@ func (h *VideoHandlers) MyVideos(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Video ID"
-// @Success 200 {object} domain.Video "Video details"
+// @Success 200 {object} VideoDetail "Video details with its status history"
 // @Failure 400 {object} map[string]string "Bad request - invalid video ID format"
 // @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
 // @Failure 403 {object} map[string]string "Forbidden - video does not belong to user"
@@ -125,6 +155,7 @@ func (h *VideoHandlers) MyVideos(c *gin.Context) {
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /videos/{id} [get]
 func (h *VideoHandlers) Detail(c *gin.Context) {
+	ctx := c.Request.Context()
 	uid, err := uuid.Parse(c.GetString("user_id"))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
@@ -135,7 +166,7 @@ func (h *VideoHandlers) Detail(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
 		return
 	}
-	v, err := h.videos.FindByIDForUser(id, uid)
+	v, err := h.videos.FindByIDForUser(ctx, id, uid)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
@@ -144,12 +175,293 @@ func (h *VideoHandlers) Detail(c *gin.Context) {
 		}
 		return
 	}
-	c.JSON(http.StatusOK, v)
+	history, err := h.svc.History(ctx, v.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving video history"})
+		return
+	}
+	c.JSON(http.StatusOK, VideoDetail{OwnerVideoView: ownerVideoView(*v), StatusHistory: statusEventViews(history)})
+}
+
+// Task godoc
+// @Summary Get processing task status
+// @Description Get the status and progress of a processing task of one of the user's videos. While the task is queued, estimated_queue_position estimates how many tasks run before it (1 = next); uploads of users with many pending tasks go to a lower-priority lane.
+// @Tags Videos
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Task ID (returned by the upload)"
+// @Success 200 {object} TaskView "Task status"
+// @Failure 400 {object} map[string]string "Bad request - invalid task ID format"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 404 {object} map[string]string "Task not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /tasks/{id} [get]
+func (h *VideoHandlers) Task(c *gin.Context) {
+	uid, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+	t, err := h.svc.TaskStatus(c.Request.Context(), id, uid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving task"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, taskView(*t))
+}
+
+// VideoDetail is a video together with its status changes, oldest first.
+type VideoDetail struct {
+	OwnerVideoView
+	StatusHistory []StatusEventView `json:"status_history"`
+}
+
+// StatusChangeRequest carries an optional reason for an owner's status change.
+type StatusChangeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ReprocessRequest picks the processing profile; empty means the default one.
+type ReprocessRequest struct {
+	Profile string `json:"profile"`
+}
+
+// VideoUpdateRequest holds the owner-editable fields; omitted ones are kept.
+type VideoUpdateRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+}
+
+// Retry godoc
+// @Summary Retry processing
+// @Description Send a video whose processing failed back to the processing queue
+// @Tags Videos
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Video ID"
+// @Success 202 {object} OwnerVideoView "Video queued again"
+// @Failure 400 {object} map[string]string "Bad request - invalid video ID format"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 404 {object} map[string]string "Video not found"
+// @Failure 409 {object} map[string]string "Conflict - video has not failed"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /videos/{id}/retry [post]
+func (h *VideoHandlers) Retry(c *gin.Context) {
+	uid, v, ok := h.ownedVideo(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.Retry(c.Request.Context(), v, uid); err != nil {
+		respondTransition(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, ownerVideoView(*v))
+}
+
+// Reprocess godoc
+// @Summary Reprocess a video
+// @Description Process the original file again, optionally with another profile (720p, 1080p or 480p; default 720p). A published video leaves voting until moderation approves the new result; its votes are kept.
+// @Tags Videos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Video ID"
+// @Param request body ReprocessRequest false "Processing profile"
+// @Success 202 {object} OwnerVideoView "Video queued for processing"
+// @Failure 400 {object} map[string]string "Bad request - invalid video ID, malformed body or unknown profile"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 404 {object} map[string]string "Video not found"
+// @Failure 409 {object} map[string]string "Conflict - video cannot be reprocessed in its current status"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /videos/{id}/reprocess [post]
+func (h *VideoHandlers) Reprocess(c *gin.Context) {
+	uid, v, ok := h.ownedVideo(c)
+	if !ok {
+		return
+	}
+	var req ReprocessRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+	profile, err := processing.ParseProfile(req.Profile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.Reprocess(c.Request.Context(), v, uid, profile); err != nil {
+		respondTransition(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, ownerVideoView(*v))
+}
+
+// Update godoc
+// @Summary Edit a video
+// @Description Change the title and description of a video owned by the user
+// @Tags Videos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Video ID"
+// @Param request body VideoUpdateRequest true "New title and description"
+// @Success 200 {object} OwnerVideoView "Updated video"
+// @Failure 400 {object} map[string]string "Bad request - invalid video ID or missing title"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 404 {object} map[string]string "Video not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /videos/{id} [patch]
+func (h *VideoHandlers) Update(c *gin.Context) {
+	_, v, ok := h.ownedVideo(c)
+	if !ok {
+		return
+	}
+	var req VideoUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	title, description := v.Title, v.Description
+	if req.Title != nil {
+		title = strings.TrimSpace(*req.Title)
+	}
+	if req.Description != nil {
+		description = strings.TrimSpace(*req.Description)
+	}
+	if err := h.svc.UpdateDetails(c.Request.Context(), v, title, description); err != nil {
+		if errors.Is(err, vidsvc.ErrTitleRequired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "title is required"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating video"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, ownerVideoView(*v))
+}
+
+// Withdraw godoc
+// @Summary Withdraw a video from voting
+// @Description Take a published video out of voting. Its votes are kept for audit and count again if it goes back through review and is published.
+// @Tags Videos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Video ID"
+// @Param request body StatusChangeRequest false "Optional reason"
+// @Success 200 {object} OwnerVideoView "Withdrawn video"
+// @Failure 400 {object} map[string]string "Bad request - invalid video ID format or malformed body"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 404 {object} map[string]string "Video not found"
+// @Failure 409 {object} map[string]string "Conflict - video is not published"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /videos/{id}/withdraw [post]
+func (h *VideoHandlers) Withdraw(c *gin.Context) {
+	uid, v, ok := h.ownedVideo(c)
+	if !ok {
+		return
+	}
+	var req StatusChangeRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+	if err := h.svc.Withdraw(c.Request.Context(), v, uid, req.Reason); err != nil {
+		respondTransition(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ownerVideoView(*v))
+}
+
+// Archive godoc
+// @Summary Archive a video
+// @Description Archive a video for good. Archived videos leave voting and cannot change status again.
+// @Tags Videos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Video ID"
+// @Param request body StatusChangeRequest false "Optional reason"
+// @Success 200 {object} OwnerVideoView "Archived video"
+// @Failure 400 {object} map[string]string "Bad request - invalid video ID format or malformed body"
+// @Failure 401 {object} map[string]string "Unauthorized - invalid or missing token"
+// @Failure 404 {object} map[string]string "Video not found"
+// @Failure 409 {object} map[string]string "Conflict - video cannot be archived in its current status"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /videos/{id}/archive [post]
+func (h *VideoHandlers) Archive(c *gin.Context) {
+	uid, v, ok := h.ownedVideo(c)
+	if !ok {
+		return
+	}
+	var req StatusChangeRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+	if err := h.svc.Archive(c.Request.Context(), v, uid, req.Reason); err != nil {
+		respondTransition(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ownerVideoView(*v))
+}
+
+// ownedVideo loads the video in the :id param for the authenticated owner,
+// writing the error response when it can't.
+func (h *VideoHandlers) ownedVideo(c *gin.Context) (uuid.UUID, *domain.Video, bool) {
+	ctx := c.Request.Context()
+	uid, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
+		return uuid.Nil, nil, false
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return uuid.Nil, nil, false
+	}
+	v, err := h.videos.FindByIDForUser(ctx, id, uid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving video"})
+		}
+		return uuid.Nil, nil, false
+	}
+	return uid, v, true
+}
+
+// bindOptionalJSON binds the request body into req, which may be left empty.
+// A malformed body gets a 400 and false.
+func bindOptionalJSON(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return false
+	}
+	return true
+}
+
+func respondTransition(c *gin.Context, err error) {
+	var illegal domain.ErrIllegalTransition
+	switch {
+	case errors.As(err, &illegal):
+		c.JSON(http.StatusConflict, gin.H{"error": illegal.Error()})
+	case errors.Is(err, repo.ErrStaleStatus):
+		c.JSON(http.StatusConflict, gin.H{"error": "Video status changed, try again"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating video status"})
+	}
 }
 
 // Delete godoc
 // @Summary Delete a video
-// @Description Delete a video owned by the user (only if not published for voting)
+// @Description Delete a video owned by the user (only if it was never published for voting; withdraw or archive it otherwise)
 // @Tags Videos
 // @Produce json
 // @Security BearerAuth
@@ -161,6 +473,7 @@ func (h *VideoHandlers) Detail(c *gin.Context) {
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /videos/{id} [delete]
 func (h *VideoHandlers) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
 	uid, err := uuid.Parse(c.GetString("user_id"))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user token"})
@@ -172,7 +485,7 @@ func (h *VideoHandlers) Delete(c *gin.Context) {
 		return
 	}
 
-	v, err := h.videos.FindByIDForUser(id, uid)
+	v, err := h.videos.FindByIDForUser(ctx, id, uid)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
@@ -182,8 +495,9 @@ func (h *VideoHandlers) Delete(c *gin.Context) {
 		return
 	}
 
-	// Check if video can be deleted based on status
-	if v.Status == domain.VideoPublished {
+	// Check if video can be deleted based on status. Videos that were ever
+	// published may hold votes, which are kept for audit: withdraw or archive them.
+	if v.Status == domain.VideoPublished || v.PublishedAt != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Video cannot be deleted - already published"})
 		return
 	}
@@ -191,7 +505,7 @@ func (h *VideoHandlers) Delete(c *gin.Context) {
 	// os.Remove(v.OriginalURL); if v.ProcessedURL != nil { os.Remove(*v.ProcessedURL) }
 
 	// Usa Save/SoftDelete según tu repo; aquí reuse Update→Status, o implementa Delete en repo.
-	if err := h.videos.DeleteByIDForUser(id, uid); err != nil {
+	if err := h.videos.DeleteByIDForUser(ctx, id, uid); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
 		} else {
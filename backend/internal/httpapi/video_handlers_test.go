@@ -0,0 +1,44 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBindOptionalJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name       string
+		body       string
+		wantOK     bool
+		wantReason string
+	}{
+		{name: "empty body", body: "", wantOK: true},
+		{name: "reason", body: `{"reason":"duplicado"}`, wantOK: true, wantReason: "duplicado"},
+		{name: "malformed", body: `{"reason":`, wantOK: false},
+		{name: "wrong type", body: `{"reason":42}`, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/api/videos/x/withdraw", strings.NewReader(tt.body))
+
+			var req StatusChangeRequest
+			ok := bindOptionalJSON(c, &req)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok && w.Code != http.StatusBadRequest {
+				t.Errorf("status %d, want 400", w.Code)
+			}
+			if req.Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", req.Reason, tt.wantReason)
+			}
+		})
+	}
+}
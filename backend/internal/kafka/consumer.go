@@ -2,35 +2,62 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"log"
-	"math"
-	"path/filepath"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/IBM/sarama"
-	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/logging"
+	"github.com/Cloud-2025-2/anb-platform/internal/metrics"
+	"github.com/Cloud-2025-2/anb-platform/internal/queue"
+	"github.com/Cloud-2025-2/anb-platform/internal/tracing"
 )
 
+// Consumer runs the tasks of the processing and retry topics; it is the
+// queue.TaskConsumer of the kafka backend.
 type Consumer struct {
-	consumer      sarama.ConsumerGroup
-	producer      *Producer
-	processor     VideoProcessorInterface
-	maxRetries    int
-	baseBackoffMs int
+	groupID  string
+	consumer sarama.ConsumerGroup
+	producer *Producer
+	handler  queue.Handler
+	// drainTimeout is how long the tasks in progress may keep running once
+	// the session ends (shutdown or rebalance) before they are aborted
+	drainTimeout time.Duration
+	// pool bounds how many tasks run at once across all partitions
+	pool *queue.Pool
+
+	mu         sync.Mutex
+	membership *Membership
 }
 
-type VideoProcessorInterface interface {
-	ProcessVideo(inputPath, outputPath string) error
+// Membership describes the consumer group session this worker is part of.
+type Membership struct {
+	GroupID      string             `json:"group_id"`
+	MemberID     string             `json:"member_id"`
+	GenerationID int32              `json:"generation_id"`
+	Claims       map[string][]int32 `json:"claims"`
+	Since        time.Time          `json:"since"`
 }
 
-func NewConsumer(brokers []string, groupID string, producer *Producer, processor VideoProcessorInterface) (*Consumer, error) {
+// NewConsumer returns a consumer that runs handler on up to concurrency tasks
+// at once (at least one). Failed tasks are retried and dead-lettered through
+// producer.
+func NewConsumer(brokers []string, groupID string, producer *Producer, handler queue.Handler, concurrency int, drainTimeout time.Duration) (*Consumer, error) {
 	config := sarama.NewConfig()
 	config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
 	config.Consumer.Offsets.Initial = sarama.OffsetOldest
 	config.Consumer.Group.Session.Timeout = 10 * time.Second
 	config.Consumer.Group.Heartbeat.Interval = 3 * time.Second
+	// Marked offsets are committed in the background and when a session ends
+	config.Consumer.Offsets.AutoCommit.Enable = true
+	config.Consumer.Offsets.AutoCommit.Interval = time.Second
 
 	consumer, err := sarama.NewConsumerGroup(brokers, groupID, config)
 	if err != nil {
@@ -38,59 +65,126 @@ func NewConsumer(brokers []string, groupID string, producer *Producer, processor
 	}
 
 	return &Consumer{
-		consumer:      consumer,
-		producer:      producer,
-		processor:     processor,
-		maxRetries:    3,
-		baseBackoffMs: 1000, // 1 second base backoff
+		groupID:      groupID,
+		consumer:     consumer,
+		producer:     producer,
+		handler:      handler,
+		drainTimeout: drainTimeout,
+		pool:         queue.NewPool(concurrency),
 	}, nil
 }
 
 func (c *Consumer) Start(ctx context.Context) error {
-	topics := []string{TopicVideoProcessing, TopicVideoRetry}
+	topics := []string{TopicVideoProcessing, TopicVideoReprocess, TopicVideoBulk, TopicVideoRetry}
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Println("Consumer context cancelled")
+			slog.Info("kafka: consumer context cancelled")
 			return ctx.Err()
 		default:
 			if err := c.consumer.Consume(ctx, topics, c); err != nil {
-				log.Printf("Error from consumer: %v", err)
+				slog.Error("kafka: consumer error", "error", err)
 				return err
 			}
 		}
 	}
 }
 
-func (c *Consumer) Setup(sarama.ConsumerGroupSession) error {
-	log.Println("Consumer group session setup")
+func (c *Consumer) Setup(session sarama.ConsumerGroupSession) error {
+	slog.Info("kafka: consumer group session setup",
+		"member_id", session.MemberID(), "generation_id", session.GenerationID())
+	c.mu.Lock()
+	c.membership = &Membership{
+		GroupID:      c.groupID,
+		MemberID:     session.MemberID(),
+		GenerationID: session.GenerationID(),
+		Claims:       session.Claims(),
+		Since:        time.Now(),
+	}
+	c.mu.Unlock()
 	return nil
 }
 
 func (c *Consumer) Cleanup(sarama.ConsumerGroupSession) error {
-	log.Println("Consumer group session cleanup")
+	slog.Info("kafka: consumer group session cleanup")
+	c.mu.Lock()
+	c.membership = nil
+	c.mu.Unlock()
+	return nil
+}
+
+// Membership returns the current group session, or nil between sessions
+// (before the first join and during rebalances).
+func (c *Consumer) Membership() *Membership {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.membership
+}
+
+// Ready fails while the worker is not a member of the consumer group, i.e.
+// before the first join and during rebalances.
+func (c *Consumer) Ready(context.Context) error {
+	if c.Membership() == nil {
+		return errors.New("not a member of consumer group " + c.groupID)
+	}
 	return nil
 }
 
+// ConsumeClaim hands the messages of one partition to the shared pool of
+// slots, so several tasks of the partition can run at once. Offsets are
+// marked in order, and the claim returns only once its tasks finished or
+// were aborted.
 func (c *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	offsets := newOffsetTracker()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
 	for {
+		// Once the session ends no new message is started, even if one is
+		// already buffered
+		if session.Context().Err() != nil {
+			return nil
+		}
 		select {
 		case message := <-claim.Messages():
 			if message == nil {
 				return nil
 			}
 
-			log.Printf("Processing message from topic %s, partition %d, offset %d",
-				message.Topic, message.Partition, message.Offset)
-
-			if err := c.processMessage(message); err != nil {
-				log.Printf("Error processing message: %v", err)
-				// Don't mark as processed if there's an error
+			// Messages still waiting behind this one on the partition
+			metrics.KafkaConsumerLag.WithLabelValues(message.Topic, strconv.Itoa(int(message.Partition))).
+				Set(float64(claim.HighWaterMarkOffset() - message.Offset - 1))
+
+			headers := consumerHeaders(message.Headers)
+			lane := domain.TaskLane(headers.Get(HeaderLane))
+			// A retry whose backoff has not elapsed is parked off the partition
+			// loop, so the messages behind it keep flowing. Its offset stays
+			// pending until it runs, and a parked retry is redelivered if the
+			// session ends first
+			notBefore, _ := time.Parse(time.RFC3339Nano, headers.Get(HeaderNotBefore))
+			if time.Now().Before(notBefore) {
+				offsets.add(message.Offset)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if !queue.WaitUntil(session.Context(), notBefore) || !c.pool.Acquire(session.Context(), lane) {
+						return
+					}
+					c.run(session, message, offsets)
+				}()
 				continue
 			}
-
-			session.MarkMessage(message, "")
+			if !c.acquire(session.Context(), message.Topic, message.Partition, lane) {
+				// Session ended while waiting; the message is redelivered
+				return nil
+			}
+			offsets.add(message.Offset)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.run(session, message, offsets)
+			}()
 
 		case <-session.Context().Done():
 			return nil
@@ -98,76 +192,88 @@ func (c *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim saram
 	}
 }
 
-func (c *Consumer) processMessage(message *sarama.ConsumerMessage) error {
-	var task VideoProcessingTask
-	if err := json.Unmarshal(message.Value, &task); err != nil {
-		log.Printf("Failed to unmarshal task: %v", err)
-		return err
+// run handles a message that holds a slot of the pool, and marks its offset
+// once every earlier message of the partition is done too. Marked offsets are
+// committed by sarama's auto-commit and when the session ends.
+func (c *Consumer) run(session sarama.ConsumerGroupSession, message *sarama.ConsumerMessage, offsets *offsetTracker) {
+	defer c.pool.Release()
+	if c.handleMessage(session.Context(), message) {
+		// Aborted: left pending, so no later offset is committed and the next
+		// owner of the partition runs it again
+		return
 	}
-
-	log.Printf("Processing video task for VideoID: %s, RetryCount: %d", task.VideoID, task.RetryCount)
-
-	// Apply exponential backoff for retry messages
-	if message.Topic == TopicVideoRetry && task.RetryCount > 0 {
-		backoffDuration := c.calculateBackoff(task.RetryCount)
-		log.Printf("Applying backoff of %v for retry %d", backoffDuration, task.RetryCount)
-		time.Sleep(backoffDuration)
+	// Failed tasks were already sent to retry or the DLQ
+	if next, ok := offsets.done(message.Offset); ok {
+		session.MarkOffset(message.Topic, message.Partition, next, "")
 	}
-
-	// Process the video
-	if err := c.processVideoTask(task); err != nil {
-		return c.handleProcessingError(task, err)
-	}
-
-	log.Printf("Successfully processed video: %s", task.VideoID)
-	return nil
 }
 
-func (c *Consumer) processVideoTask(task VideoProcessingTask) error {
-	log.Printf("Processing video: %s", task.VideoID)
-
-	// Parse video ID from string to UUID
-	videoID, err := uuid.Parse(task.VideoID)
-	if err != nil {
-		return fmt.Errorf("invalid video ID format: %w", err)
+// acquire takes a slot of the pool for a task of lane. While the pool is full
+// the partition is paused, so the broker stops sending messages no one can
+// take, and the next free slot goes to the highest lane waiting.
+func (c *Consumer) acquire(session context.Context, topic string, partition int32, lane domain.TaskLane) bool {
+	if c.pool.TryAcquire() {
+		return true
 	}
 
-	// Generate proper output path using the original video ID
-	inputDir := filepath.Dir(task.FilePath)
-	outputPath := filepath.Join(inputDir, fmt.Sprintf("%s_processed.mp4", videoID.String()))
-
-	// Call ProcessVideoWithID with the correct video ID from the Kafka message
-	if processor, ok := c.processor.(interface {
-		ProcessVideoWithID(uuid.UUID, string, string) error
-	}); ok {
-		return processor.ProcessVideoWithID(videoID, task.FilePath, outputPath)
-	}
-
-	// Fallback to old method if ProcessVideoWithID is not available
-	return c.processor.ProcessVideo(task.FilePath, outputPath)
+	paused := map[string][]int32{topic: {partition}}
+	c.consumer.Pause(paused)
+	metrics.KafkaPartitionsPaused.Inc()
+	defer func() {
+		c.consumer.Resume(paused)
+		metrics.KafkaPartitionsPaused.Dec()
+	}()
+	return c.pool.Acquire(session, lane)
 }
 
-func (c *Consumer) handleProcessingError(task VideoProcessingTask, err error) error {
-	log.Printf("Processing failed for video %s: %v", task.VideoID, err)
-
-	if task.RetryCount >= c.maxRetries {
-		log.Printf("Max retries exceeded for video %s, sending to DLQ", task.VideoID)
-		return c.producer.PublishToDLQ(task, err.Error())
+// handleMessage processes one message and reports whether it was aborted by
+// the end of the session.
+func (c *Consumer) handleMessage(session context.Context, message *sarama.ConsumerMessage) bool {
+	ctx, cancel := queue.WithDrain(session, c.drainTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.processMessage(ctx, message)
+	metrics.Since(metrics.KafkaConsumeDuration.WithLabelValues(message.Topic, metrics.Result(err)), start)
+	if ctx.Err() != nil {
+		slog.Warn("kafka: task aborted by shutdown, leaving it for redelivery",
+			"topic", message.Topic, "partition", message.Partition, "offset", message.Offset)
+		return true
 	}
-
-	log.Printf("Retrying video %s (attempt %d/%d)", task.VideoID, task.RetryCount+1, c.maxRetries)
-	return c.producer.PublishToRetryTopic(task)
+	return false
 }
 
-func (c *Consumer) calculateBackoff(retryCount int) time.Duration {
-	// Exponential backoff: baseBackoff * 2^retryCount with jitter
-	backoffMs := c.baseBackoffMs * int(math.Pow(2, float64(retryCount)))
-
-	// Add some jitter (±20%)
-	jitter := int(float64(backoffMs) * 0.2)
-	backoffMs += (retryCount*137)%(2*jitter) - jitter // Simple pseudo-random jitter
+func (c *Consumer) processMessage(ctx context.Context, message *sarama.ConsumerMessage) (err error) {
+	// Continue the trace started by the publisher of the task, and log with
+	// the request and task that produced it
+	headers := consumerHeaders(message.Headers)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, headers)
+	ctx = logging.With(ctx,
+		slog.String("topic", message.Topic),
+		slog.Int("partition", int(message.Partition)),
+		slog.Int64("offset", message.Offset),
+	)
+	for _, key := range []string{HeaderRequestID, HeaderTaskID} {
+		if v := headers.Get(key); v != "" {
+			ctx = logging.With(ctx, slog.String(key, v))
+		}
+	}
+	ctx, span := tracing.Tracer.Start(ctx, "kafka.consume "+message.Topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", message.Topic),
+			attribute.Int("messaging.kafka.destination.partition", int(message.Partition)),
+			attribute.Int64("messaging.kafka.message.offset", message.Offset),
+		))
+	defer func() {
+		if err != nil {
+			slog.ErrorContext(ctx, "kafka: message not processed", "error", err)
+		}
+		tracing.End(span, err)
+	}()
 
-	return time.Duration(backoffMs) * time.Millisecond
+	return queue.Receive(ctx, c.handler, message.Value, c.producer.PublishToRetryTopic, c.producer.PublishToDLQ)
 }
 
 func (c *Consumer) Close() error {
@@ -0,0 +1,59 @@
+package kafka
+
+import (
+	"github.com/IBM/sarama"
+)
+
+// producerHeaders carries the trace context into the headers of an outgoing
+// message, next to task_id, request_id and timestamp.
+type producerHeaders struct{ msg *sarama.ProducerMessage }
+
+func (h producerHeaders) Get(key string) string {
+	for _, rh := range h.msg.Headers {
+		if string(rh.Key) == key {
+			return string(rh.Value)
+		}
+	}
+	return ""
+}
+
+func (h producerHeaders) Set(key, value string) {
+	for i, rh := range h.msg.Headers {
+		if string(rh.Key) == key {
+			h.msg.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	h.msg.Headers = append(h.msg.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+func (h producerHeaders) Keys() []string {
+	keys := make([]string, len(h.msg.Headers))
+	for i, rh := range h.msg.Headers {
+		keys[i] = string(rh.Key)
+	}
+	return keys
+}
+
+// consumerHeaders reads the trace context from the headers of a consumed message.
+type consumerHeaders []*sarama.RecordHeader
+
+func (h consumerHeaders) Get(key string) string {
+	for _, rh := range h {
+		if string(rh.Key) == key {
+			return string(rh.Value)
+		}
+	}
+	return ""
+}
+
+// Set is a no-op: consumed messages are never modified.
+func (h consumerHeaders) Set(string, string) {}
+
+func (h consumerHeaders) Keys() []string {
+	keys := make([]string, len(h))
+	for i, rh := range h {
+		keys[i] = string(rh.Key)
+	}
+	return keys
+}
@@ -0,0 +1,43 @@
+package kafka
+
+import "sync"
+
+// offsetTracker lets the messages of one partition finish in any order while
+// the committed offset only moves past a message once every earlier message
+// has finished too, so a crash never skips a task still in progress.
+type offsetTracker struct {
+	mu      sync.Mutex
+	order   []int64        // offsets in flight, in arrival (ascending) order
+	pending map[int64]bool // offset -> finished
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{pending: make(map[int64]bool)}
+}
+
+// add registers a message that started processing.
+func (t *offsetTracker) add(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.order = append(t.order, offset)
+	t.pending[offset] = false
+}
+
+// done records that offset finished. If that completes a run of finished
+// messages at the head, it returns the offset to commit (the next one to
+// read) and true.
+func (t *offsetTracker) done(offset int64) (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[offset] = true
+
+	var next int64
+	advanced := false
+	for len(t.order) > 0 && t.pending[t.order[0]] {
+		next = t.order[0] + 1
+		delete(t.pending, t.order[0])
+		t.order = t.order[1:]
+		advanced = true
+	}
+	return next, advanced
+}
@@ -1,33 +1,61 @@
 package kafka
 
 import (
-	"encoding/json"
-	"log"
+	"context"
+	"log/slog"
 	"time"
 
 	"github.com/IBM/sarama"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/logging"
+	"github.com/Cloud-2025-2/anb-platform/internal/metrics"
+	"github.com/Cloud-2025-2/anb-platform/internal/queue"
+	"github.com/Cloud-2025-2/anb-platform/internal/tracing"
 )
 
+// Producer publishes tasks to Kafka; it is the queue.TaskPublisher of the
+// kafka backend.
 type Producer struct {
+	client   sarama.Client
 	producer sarama.SyncProducer
 }
 
-type VideoProcessingTask struct {
-	VideoID    string    `json:"video_id"`
-	UserID     string    `json:"user_id"`
-	Title      string    `json:"title"`
-	FilePath   string    `json:"file_path"`
-	Timestamp  time.Time `json:"timestamp"`
-	RetryCount int       `json:"retry_count"`
-}
+// Record headers that correlate a task with the request that created it.
+const (
+	HeaderTaskID    = "task_id"
+	HeaderRequestID = "request_id"
+	// HeaderLane lets the consumer rank a task before decoding it
+	HeaderLane = "lane"
+	// HeaderNotBefore lets the consumer wait for a retry's backoff before
+	// taking a slot for it (RFC 3339)
+	HeaderNotBefore = "not_before"
+)
 
+// Each lane has its own topic; the upload lane keeps the original one.
 const (
 	TopicVideoProcessing = "video-processing"
+	TopicVideoReprocess  = "video-processing-reprocess"
+	TopicVideoBulk       = "video-processing-bulk"
 	TopicVideoRetry      = "video-processing-retry"
 	TopicVideoDLQ        = "video-processing-dlq"
 )
 
+func topicFor(lane domain.TaskLane) string {
+	switch lane {
+	case domain.LaneReprocess:
+		return TopicVideoReprocess
+	case domain.LaneBulk:
+		return TopicVideoBulk
+	default:
+		return TopicVideoProcessing
+	}
+}
+
 func NewProducer(brokers []string) (*Producer, error) {
 	config := sarama.NewConfig()
 	config.Producer.RequiredAcks = sarama.WaitForAll
@@ -35,28 +63,62 @@ func NewProducer(brokers []string) (*Producer, error) {
 	config.Producer.Return.Successes = true
 	config.Producer.Partitioner = sarama.NewRoundRobinPartitioner
 
-	producer, err := sarama.NewSyncProducer(brokers, config)
+	// The client is kept to answer the readiness check with broker metadata
+	client, err := sarama.NewClient(brokers, config)
+	if err != nil {
+		return nil, err
+	}
+	producer, err := sarama.NewSyncProducerFromClient(client)
 	if err != nil {
+		client.Close()
 		return nil, err
 	}
 
-	return &Producer{producer: producer}, nil
+	return &Producer{client: client, producer: producer}, nil
 }
 
-func (p *Producer) PublishVideoProcessingTask(task VideoProcessingTask) error {
-	taskBytes, err := json.Marshal(task)
+// Ping refreshes the cluster metadata, which fails when no broker answers.
+func (p *Producer) Ping(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() { done <- p.client.RefreshMetadata() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if len(p.client.Brokers()) == 0 {
+		return sarama.ErrOutOfBrokers
+	}
+	return nil
+}
+
+// Publish sends task to the topic of its lane, keyed by video so the tasks of
+// a video stay in order.
+func (p *Producer) Publish(ctx context.Context, task queue.Task) error {
+	taskBytes, err := queue.EncodeTask(ctx, task)
 	if err != nil {
 		return err
 	}
 
+	taskID := task.TaskID
+	if taskID == "" {
+		taskID = uuid.New().String()
+	}
 	msg := &sarama.ProducerMessage{
-		Topic: TopicVideoProcessing,
+		Topic: topicFor(task.Lane),
 		Key:   sarama.StringEncoder(task.VideoID),
 		Value: sarama.ByteEncoder(taskBytes),
 		Headers: []sarama.RecordHeader{
 			{
-				Key:   []byte("task_id"),
-				Value: []byte(uuid.New().String()),
+				Key:   []byte(HeaderTaskID),
+				Value: []byte(taskID),
+			},
+			{
+				Key:   []byte(HeaderLane),
+				Value: []byte(task.Lane),
 			},
 			{
 				Key:   []byte("timestamp"),
@@ -65,18 +127,20 @@ func (p *Producer) PublishVideoProcessingTask(task VideoProcessingTask) error {
 		},
 	}
 
-	partition, offset, err := p.producer.SendMessage(msg)
+	partition, offset, err := p.send(ctx, msg)
 	if err != nil {
 		return err
 	}
 
-	log.Printf("Video processing task sent to partition %d at offset %d", partition, offset)
+	slog.InfoContext(ctx, "kafka: video processing task sent",
+		"video_id", task.VideoID, "lane", task.Lane, "topic", msg.Topic, "partition", partition, "offset", offset)
 	return nil
 }
 
-func (p *Producer) PublishToRetryTopic(task VideoProcessingTask) error {
-	task.RetryCount++
-	taskBytes, err := json.Marshal(task)
+// PublishToRetryTopic sends a failed task back for another attempt; task
+// already carries the RetryCount and NotBefore of that attempt.
+func (p *Producer) PublishToRetryTopic(ctx context.Context, task queue.Task) error {
+	taskBytes, err := queue.EncodeTask(ctx, task)
 	if err != nil {
 		return err
 	}
@@ -85,38 +149,73 @@ func (p *Producer) PublishToRetryTopic(task VideoProcessingTask) error {
 		Topic: TopicVideoRetry,
 		Key:   sarama.StringEncoder(task.VideoID),
 		Value: sarama.ByteEncoder(taskBytes),
+		Headers: []sarama.RecordHeader{
+			{
+				Key:   []byte(HeaderLane),
+				Value: []byte(task.Lane),
+			},
+		},
+	}
+	if task.NotBefore != nil {
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{
+			Key:   []byte(HeaderNotBefore),
+			Value: []byte(task.NotBefore.Format(time.RFC3339Nano)),
+		})
 	}
 
-	_, _, err = p.producer.SendMessage(msg)
+	_, _, err = p.send(ctx, msg)
 	return err
 }
 
-func (p *Producer) PublishToDLQ(task VideoProcessingTask, errorMsg string) error {
-	dlqTask := struct {
-		VideoProcessingTask
-		Error    string    `json:"error"`
-		FailedAt time.Time `json:"failed_at"`
-	}{
-		VideoProcessingTask: task,
-		Error:               errorMsg,
-		FailedAt:            time.Now(),
-	}
-
-	taskBytes, err := json.Marshal(dlqTask)
+// PublishToDLQ sends a task that won't be retried, or a message that could
+// not be read, to the dead letter topic.
+func (p *Producer) PublishToDLQ(ctx context.Context, dl queue.DeadLetter) error {
+	taskBytes, err := queue.EncodeDeadLetter(ctx, dl)
 	if err != nil {
 		return err
 	}
 
 	msg := &sarama.ProducerMessage{
 		Topic: TopicVideoDLQ,
-		Key:   sarama.StringEncoder(task.VideoID),
+		Key:   sarama.StringEncoder(dl.VideoID),
 		Value: sarama.ByteEncoder(taskBytes),
 	}
 
-	_, _, err = p.producer.SendMessage(msg)
+	_, _, err = p.send(ctx, msg)
 	return err
 }
 
+// send publishes msg under a producer span whose context travels in the
+// message headers, together with the request and task IDs attached to ctx,
+// and records how long the broker took to acknowledge it.
+func (p *Producer) send(ctx context.Context, msg *sarama.ProducerMessage) (int32, int64, error) {
+	headers := producerHeaders{msg}
+	for _, key := range []string{HeaderRequestID, HeaderTaskID} {
+		if v := logging.Value(ctx, key); v != "" && headers.Get(key) == "" {
+			headers.Set(key, v)
+		}
+	}
+
+	ctx, span := tracing.Tracer.Start(ctx, "kafka.publish "+msg.Topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", msg.Topic),
+		))
+	otel.GetTextMapPropagator().Inject(ctx, headers)
+
+	start := time.Now()
+	partition, offset, err := p.producer.SendMessage(msg)
+	metrics.Since(metrics.KafkaProduceDuration.WithLabelValues(msg.Topic, metrics.Result(err)), start)
+	tracing.End(span, err)
+	return partition, offset, err
+}
+
 func (p *Producer) Close() error {
-	return p.producer.Close()
+	// A producer built from a client does not close it
+	if err := p.producer.Close(); err != nil {
+		p.client.Close()
+		return err
+	}
+	return p.client.Close()
 }
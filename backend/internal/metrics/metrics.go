@@ -0,0 +1,154 @@
+// Package metrics defines the Prometheus collectors shared by the API and the
+// worker. Both expose them on /metrics through Handler.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "anb"
+
+var (
+	// HTTP
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by gin route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	UploadBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upload_bytes_total",
+		Help:      "Bytes of video received through uploads.",
+	})
+
+	VotesCast = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "votes_cast_total",
+		Help:      "Votes stored, by the status fraud screening gave them.",
+	}, []string{"status"})
+
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Cache lookups by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	// Kafka
+	KafkaProduceDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kafka_produce_duration_seconds",
+		Help:      "Time to publish a message, by topic and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"topic", "result"})
+
+	KafkaConsumeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "kafka_consume_duration_seconds",
+		Help:      "Time to handle a consumed message, by topic and result.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"topic", "result"})
+
+	KafkaConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "kafka_consumer_lag",
+		Help:      "Messages behind the high water mark, by topic and partition.",
+	}, []string{"topic", "partition"})
+
+	KafkaPartitionsPaused = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "kafka_partitions_paused",
+		Help:      "Partitions paused because every worker slot is busy.",
+	})
+
+	TaskRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "processing_retries_total",
+		Help:      "Processing tasks sent back to the retry topic.",
+	})
+
+	TaskDeadLetters = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "processing_dead_letters_total",
+		Help:      "Processing tasks sent to the DLQ after exhausting their retries.",
+	})
+
+	QueueMessagesDecoded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queue_messages_decoded_total",
+		Help:      "Queue messages read, by schema version and result (ok, upgraded or rejected).",
+	}, []string{"schema_version", "result"})
+
+	// Processing
+	WorkerConcurrency = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_concurrency",
+		Help:      "Tasks a worker may process at once.",
+	})
+
+	WorkerJobsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_jobs_in_flight",
+		Help:      "Tasks being processed right now.",
+	})
+
+	WorkerSlotWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "worker_slot_wait_seconds",
+		Help:      "Time a consumed message waited for a free worker slot.",
+		Buckets:   []float64{0.1, 1, 5, 15, 30, 60, 120, 300},
+	})
+
+	FFmpegStepDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_step_duration_seconds",
+		Help:      "Duration of each ffmpeg step of the processing pipeline.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 40, 80, 160},
+	}, []string{"step", "result"})
+)
+
+// Handler serves the registered metrics in the Prometheus text format.
+func Handler() http.Handler { return promhttp.Handler() }
+
+// Gin records the latency of every request under its route template, so
+// /api/videos/:id is one series however many videos there are.
+func Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDB exports the connection pool stats of db under the given name.
+func RegisterDB(name string, db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Result labels an outcome for the result label of the collectors above.
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// Since observes the seconds elapsed since start on o.
+func Since(o prometheus.Observer, start time.Time) {
+	o.Observe(time.Since(start).Seconds())
+}
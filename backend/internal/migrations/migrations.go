@@ -0,0 +1,304 @@
+// Package migrations applies the versioned SQL files in sql/ to Postgres.
+// Files are named NNNN_description.up.sql / NNNN_description.down.sql and run
+// in version order. Applied versions are recorded in schema_migrations; a
+// Postgres advisory lock keeps concurrent runs from racing.
+//
+// Only cmd/migrate changes the schema. Services call RequireCurrent on startup
+// and refuse to run against an outdated database.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID is the pg_advisory_lock key held while migrating ("anbmigr").
+const lockID = 0x616e626d69677200
+
+// Migration is one version of the schema.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// ErrOutdated is returned by RequireCurrent when migrations are pending.
+var ErrOutdated = errors.New("database schema is outdated; run `migrate up`")
+
+// ErrNotAdoptable is returned by Up when the baseline migration finds tables
+// created by an older AutoMigrate that lack some of its columns.
+var ErrNotAdoptable = errors.New("existing tables do not match the baseline schema")
+
+// baselineVersion is the migration that creates the original AutoMigrate
+// schema, and adopts databases created by it. Later migrations use IF NOT
+// EXISTS, so they also upgrade databases where a newer AutoMigrate had
+// already added their tables and columns.
+const baselineVersion = 1
+
+// All returns the embedded migrations in version order.
+func All() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		base, direction, ok := cutDirection(name)
+		if !ok {
+			return nil, fmt.Errorf("migrations: unexpected file %s", name)
+		}
+		num, label, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migrations: bad version in %s", name)
+		}
+		body, err := files.ReadFile("sql/" + name)
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migrations: version %d needs both up and down files", m.Version)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+func cutDirection(name string) (base, direction string, ok bool) {
+	if base, ok = strings.CutSuffix(name, ".up.sql"); ok {
+		return base, "up", true
+	}
+	if base, ok = strings.CutSuffix(name, ".down.sql"); ok {
+		return base, "down", true
+	}
+	return "", "", false
+}
+
+// Latest returns the version the code expects.
+func Latest() (int, error) {
+	all, err := All()
+	if err != nil || len(all) == 0 {
+		return 0, err
+	}
+	return all[len(all)-1].Version, nil
+}
+
+// Current returns the highest applied version, 0 for an empty database.
+func Current(ctx context.Context, db *sql.DB) (int, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+	var v int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v)
+	return v, err
+}
+
+// RequireCurrent fails unless every embedded migration has been applied. A
+// database ahead of the code (during a rolling deploy) is accepted.
+func RequireCurrent(ctx context.Context, db *sql.DB) error {
+	latest, err := Latest()
+	if err != nil {
+		return err
+	}
+	current, err := Current(ctx, db)
+	if err != nil {
+		return err
+	}
+	if current < latest {
+		return fmt.Errorf("%w (at version %d, want %d)", ErrOutdated, current, latest)
+	}
+	return nil
+}
+
+// Up applies every pending migration and returns the versions it applied.
+func Up(ctx context.Context, db *sql.DB) ([]int, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	var applied []int
+	err = withLock(ctx, db, func(conn *sql.Conn) error {
+		current, err := currentOn(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range all {
+			if m.Version <= current {
+				continue
+			}
+			if m.Version == baselineVersion {
+				if err := checkAdoptable(ctx, conn, m.Up); err != nil {
+					return fmt.Errorf("migration %04d_%s up: %w", m.Version, m.Name, err)
+				}
+			}
+			if err := run(ctx, conn, m.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+				return fmt.Errorf("migration %04d_%s up: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m.Version)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations and returns the versions it
+// reverted.
+func Down(ctx context.Context, db *sql.DB, steps int) ([]int, error) {
+	all, err := All()
+	if err != nil {
+		return nil, err
+	}
+	var reverted []int
+	err = withLock(ctx, db, func(conn *sql.Conn) error {
+		current, err := currentOn(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(all) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := all[i]
+			if m.Version > current {
+				continue
+			}
+			if err := run(ctx, conn, m.Down, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
+				return fmt.Errorf("migration %04d_%s down: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m.Version)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// withLock runs fn on a single connection holding the migrations advisory
+// lock, after making sure schema_migrations exists.
+func withLock(ctx context.Context, db *sql.DB, fn func(*sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, int64(lockID)); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, int64(lockID))
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    integer PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func currentOn(ctx context.Context, conn *sql.Conn) (int, error) {
+	var v int
+	err := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&v)
+	return v, err
+}
+
+// checkAdoptable fails when tables the baseline would create already exist
+// without some of its columns: CREATE TABLE IF NOT EXISTS would leave them as
+// they are. The baseline is run in a scratch schema, in a transaction that is
+// rolled back, and its columns are compared with those of the existing tables.
+func checkAdoptable(ctx context.Context, conn *sql.Conn, baseline string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var schema string
+	if err := tx.QueryRowContext(ctx, `SELECT current_schema()`).Scan(&schema); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE SCHEMA migrate_baseline_check`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `SET LOCAL search_path TO migrate_baseline_check, "`+strings.ReplaceAll(schema, `"`, `""`)+`"`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, baseline); err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT b.table_name, b.column_name
+		FROM information_schema.columns b
+		JOIN information_schema.tables t ON t.table_schema = $1 AND t.table_name = b.table_name
+		WHERE b.table_schema = 'migrate_baseline_check'
+		  AND NOT EXISTS (
+			SELECT 1 FROM information_schema.columns c
+			WHERE c.table_schema = $1 AND c.table_name = b.table_name AND c.column_name = b.column_name
+		  )
+		ORDER BY b.table_name, b.column_name`, schema)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var missing []string
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return err
+		}
+		missing = append(missing, table+"."+column)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing columns %s", ErrNotAdoptable, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// run executes a migration body and its bookkeeping statement in one
+// transaction, so a failed migration leaves no trace.
+func run(ctx context.Context, conn *sql.Conn, body, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
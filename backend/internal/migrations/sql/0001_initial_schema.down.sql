@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS votes;
+DROP TABLE IF EXISTS videos;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,57 @@
+-- Esquema base: exactamente lo que AutoMigrate creaba al arrancar antes de
+-- las migraciones versionadas (users, videos y votes). Usa IF NOT EXISTS para
+-- adoptar bases de datos creadas por AutoMigrate; antes de aplicarla,
+-- migrations.Up compara las columnas de las tablas existentes y falla si
+-- falta alguna. Las tablas y columnas posteriores llegan en las migraciones
+-- 0005 en adelante, que también toleran bases donde AutoMigrate ya las creó.
+
+CREATE EXTENSION IF NOT EXISTS "pgcrypto";
+
+CREATE TABLE IF NOT EXISTS users (
+    id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    first_name    text NOT NULL,
+    last_name     text NOT NULL,
+    email         text NOT NULL,
+    password_hash text NOT NULL,
+    city          text NOT NULL,
+    country       text NOT NULL,
+    role          text NOT NULL DEFAULT 'player',
+    created_at    timestamptz,
+    updated_at    timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+
+CREATE TABLE IF NOT EXISTS videos (
+    id                 uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id            uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    title              text NOT NULL,
+    original_url       text NOT NULL,
+    processed_url      text,
+    status             text NOT NULL DEFAULT 'uploaded',
+    uploaded_at        timestamptz,
+    processed_at       timestamptz,
+    published_at       timestamptz,
+    duration_orig_sec  bigint,
+    duration_proc_sec  bigint,
+    width_orig         bigint,
+    height_orig        bigint,
+    width_proc         bigint,
+    height_proc        bigint,
+    aspect_proc        text,
+    has_audio_orig     boolean,
+    watermark          boolean DEFAULT false,
+    is_public_for_vote boolean DEFAULT false,
+    city_snapshot      text,
+    checksum_sha256    text
+);
+CREATE INDEX IF NOT EXISTS idx_videos_user_id ON videos (user_id);
+CREATE INDEX IF NOT EXISTS idx_videos_status ON videos (status);
+CREATE INDEX IF NOT EXISTS idx_videos_is_public_for_vote ON videos (is_public_for_vote);
+
+CREATE TABLE IF NOT EXISTS votes (
+    id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    video_id   uuid NOT NULL REFERENCES videos (id) ON DELETE CASCADE,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_video ON votes (user_id, video_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_videos_public_published;
+DROP INDEX IF EXISTS idx_users_name_fts;
+DROP INDEX IF EXISTS idx_videos_title_fts;
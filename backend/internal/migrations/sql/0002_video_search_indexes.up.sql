@@ -0,0 +1,9 @@
+-- Índices de expresión para la búsqueda y el orden del listado público
+-- (repo.VideoSearch); las expresiones deben coincidir con las de las consultas.
+
+CREATE INDEX IF NOT EXISTS idx_videos_title_fts
+    ON videos USING gin (to_tsvector('simple', coalesce(title, '')));
+CREATE INDEX IF NOT EXISTS idx_users_name_fts
+    ON users USING gin (to_tsvector('simple', first_name || ' ' || last_name));
+CREATE INDEX IF NOT EXISTS idx_videos_public_published
+    ON videos (COALESCE(published_at, uploaded_at) DESC, id DESC) WHERE is_public_for_vote;
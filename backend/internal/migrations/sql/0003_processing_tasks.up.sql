@@ -0,0 +1,20 @@
+-- Registro de cada tarea de procesamiento encolada: estado, intentos, último
+-- error y avance del pipeline de ffmpeg que reporta el worker.
+
+CREATE TABLE processing_tasks (
+    id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id     uuid NOT NULL REFERENCES videos (id) ON DELETE CASCADE,
+    task_type    text NOT NULL,
+    status       text NOT NULL DEFAULT 'queued',
+    attempts     bigint NOT NULL DEFAULT 0,
+    max_attempts bigint NOT NULL DEFAULT 5,
+    last_error   text,
+    step         text NOT NULL DEFAULT '',
+    progress     bigint NOT NULL DEFAULT 0,
+    enqueued_at  timestamptz,
+    started_at   timestamptz,
+    finished_at  timestamptz,
+    worker_id    text
+);
+CREATE INDEX idx_processing_tasks_video_id ON processing_tasks (video_id);
+CREATE INDEX idx_processing_tasks_status ON processing_tasks (status);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_processing_tasks_queue;
+ALTER TABLE processing_tasks DROP COLUMN IF EXISTS lane;
@@ -0,0 +1,5 @@
+-- Carril de prioridad de cada tarea (upload, reprocess, bulk). El índice
+-- sirve para calcular la posición de una tarea en la cola.
+
+ALTER TABLE processing_tasks ADD COLUMN lane text NOT NULL DEFAULT 'upload';
+CREATE INDEX idx_processing_tasks_queue ON processing_tasks (status, lane, enqueued_at);
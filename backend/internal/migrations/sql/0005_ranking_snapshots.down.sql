@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS ranking_snapshot_entries;
+DROP TABLE IF EXISTS ranking_snapshots;
+DROP INDEX IF EXISTS idx_votes_created_at;
+DROP INDEX IF EXISTS idx_votes_video_id;
@@ -0,0 +1,34 @@
+-- Fotos periódicas de los rankings y los índices de votos que usan los
+-- rankings por ventana de tiempo. Todo con IF NOT EXISTS: en bases adoptadas
+-- AutoMigrate pudo haberlos creado ya.
+
+CREATE INDEX IF NOT EXISTS idx_votes_video_id ON votes (video_id);
+CREATE INDEX IF NOT EXISTS idx_votes_created_at ON votes (created_at);
+
+CREATE TABLE IF NOT EXISTS ranking_snapshots (
+    id       uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    taken_at timestamptz NOT NULL,
+    final    boolean DEFAULT false
+);
+CREATE INDEX IF NOT EXISTS idx_ranking_snapshots_taken_at ON ranking_snapshots (taken_at);
+CREATE INDEX IF NOT EXISTS idx_ranking_snapshots_final ON ranking_snapshots (final);
+
+CREATE TABLE IF NOT EXISTS ranking_snapshot_entries (
+    id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    snapshot_id   uuid NOT NULL REFERENCES ranking_snapshots (id) ON DELETE CASCADE,
+    scope         text NOT NULL,
+    board_city    text,
+    board_country text,
+    entry_id      uuid NOT NULL,
+    user_id       uuid NOT NULL,
+    video_id      uuid,
+    username      text,
+    city          text,
+    country       text,
+    title         text,
+    position      bigint NOT NULL,
+    votes         bigint NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_snapshot_board
+    ON ranking_snapshot_entries (snapshot_id, scope, board_city, board_country);
+CREATE INDEX IF NOT EXISTS idx_ranking_snapshot_entries_entry_id ON ranking_snapshot_entries (entry_id);
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_votes_status;
+DROP INDEX IF EXISTS idx_votes_fingerprint;
+DROP INDEX IF EXISTS idx_votes_subnet;
+DROP INDEX IF EXISTS idx_votes_ip;
+ALTER TABLE votes
+    DROP COLUMN IF EXISTS reviewed_at,
+    DROP COLUMN IF EXISTS reviewed_by,
+    DROP COLUMN IF EXISTS fraud_reasons,
+    DROP COLUMN IF EXISTS fraud_score,
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS fingerprint,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS subnet,
+    DROP COLUMN IF EXISTS ip;
@@ -0,0 +1,17 @@
+-- Señales de fraude y estado de revisión de cada voto. Los votos existentes
+-- quedan como contados.
+
+ALTER TABLE votes
+    ADD COLUMN IF NOT EXISTS ip            text,
+    ADD COLUMN IF NOT EXISTS subnet        text,
+    ADD COLUMN IF NOT EXISTS user_agent    text,
+    ADD COLUMN IF NOT EXISTS fingerprint   text,
+    ADD COLUMN IF NOT EXISTS status        text NOT NULL DEFAULT 'counted',
+    ADD COLUMN IF NOT EXISTS fraud_score   bigint NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS fraud_reasons text,
+    ADD COLUMN IF NOT EXISTS reviewed_by   uuid,
+    ADD COLUMN IF NOT EXISTS reviewed_at   timestamptz;
+CREATE INDEX IF NOT EXISTS idx_votes_ip ON votes (ip);
+CREATE INDEX IF NOT EXISTS idx_votes_subnet ON votes (subnet);
+CREATE INDEX IF NOT EXISTS idx_votes_fingerprint ON votes (fingerprint);
+CREATE INDEX IF NOT EXISTS idx_votes_status ON votes (status);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_snapshot_board;
+ALTER TABLE ranking_snapshot_entries DROP COLUMN IF EXISTS board_tournament_id;
+CREATE INDEX idx_snapshot_board
+    ON ranking_snapshot_entries (snapshot_id, scope, board_city, board_country);
+
+DROP INDEX IF EXISTS idx_videos_tournament_id;
+ALTER TABLE videos DROP COLUMN IF EXISTS tournament_id;
+DROP TABLE IF EXISTS tournaments;
@@ -0,0 +1,30 @@
+-- Torneos, el torneo de cada video y el torneo de cada tabla guardada en las
+-- fotos de ranking. El índice de tablas se recrea para incluir el torneo:
+-- AutoMigrate no actualizaba índices que ya existían.
+
+CREATE TABLE IF NOT EXISTS tournaments (
+    id                     uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name                   text NOT NULL,
+    description            text,
+    registration_opens_at  timestamptz NOT NULL,
+    registration_closes_at timestamptz NOT NULL,
+    voting_opens_at        timestamptz NOT NULL,
+    voting_closes_at       timestamptz NOT NULL,
+    eligible_cities        jsonb NOT NULL DEFAULT '[]',
+    eligible_countries     jsonb NOT NULL DEFAULT '[]',
+    max_entries_per_player bigint NOT NULL DEFAULT 1,
+    votes_per_user         bigint NOT NULL DEFAULT 0,
+    created_at             timestamptz,
+    updated_at             timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_tournaments_voting_opens_at ON tournaments (voting_opens_at);
+CREATE INDEX IF NOT EXISTS idx_tournaments_voting_closes_at ON tournaments (voting_closes_at);
+
+ALTER TABLE videos
+    ADD COLUMN IF NOT EXISTS tournament_id uuid REFERENCES tournaments (id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_videos_tournament_id ON videos (tournament_id);
+
+ALTER TABLE ranking_snapshot_entries ADD COLUMN IF NOT EXISTS board_tournament_id uuid;
+DROP INDEX IF EXISTS idx_snapshot_board;
+CREATE INDEX idx_snapshot_board
+    ON ranking_snapshot_entries (snapshot_id, scope, board_tournament_id, board_city, board_country);
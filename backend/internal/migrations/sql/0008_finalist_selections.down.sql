@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS finalist_decisions;
+DROP TABLE IF EXISTS finalist_selections;
@@ -0,0 +1,33 @@
+-- Selecciones de finalistas por ciudad y la decisión tomada sobre cada
+-- jugador.
+
+CREATE TABLE IF NOT EXISTS finalist_selections (
+    id             uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    tournament_id  uuid,
+    slots_per_city bigint NOT NULL,
+    status         text NOT NULL DEFAULT 'draft',
+    computed_at    timestamptz NOT NULL,
+    frozen_at      timestamptz,
+    frozen_by      uuid,
+    created_at     timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_finalist_selections_tournament_id ON finalist_selections (tournament_id);
+CREATE INDEX IF NOT EXISTS idx_finalist_selections_status ON finalist_selections (status);
+
+CREATE TABLE IF NOT EXISTS finalist_decisions (
+    id           uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    selection_id uuid NOT NULL REFERENCES finalist_selections (id) ON DELETE CASCADE,
+    user_id      uuid NOT NULL,
+    username     text NOT NULL,
+    city         text NOT NULL,
+    country      text NOT NULL,
+    position     bigint,
+    votes        bigint NOT NULL DEFAULT 0,
+    decision     text NOT NULL,
+    source       text NOT NULL,
+    reason       text NOT NULL,
+    decided_by   uuid,
+    decided_at   timestamptz NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_selection_player ON finalist_decisions (selection_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_finalist_decisions_city ON finalist_decisions (city);
@@ -0,0 +1,5 @@
+ALTER TABLE videos
+    DROP COLUMN IF EXISTS moderated_at,
+    DROP COLUMN IF EXISTS moderated_by,
+    DROP COLUMN IF EXISTS moderation_reason;
+DROP TABLE IF EXISTS video_reports;
@@ -0,0 +1,21 @@
+-- Reportes de usuarios sobre videos y la última decisión de moderación de
+-- cada video.
+
+CREATE TABLE IF NOT EXISTS video_reports (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id    uuid NOT NULL REFERENCES videos (id) ON DELETE CASCADE,
+    reporter_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    reason      text NOT NULL,
+    details     text,
+    status      text NOT NULL DEFAULT 'open',
+    created_at  timestamptz,
+    resolved_by uuid,
+    resolved_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_report_video_reporter ON video_reports (video_id, reporter_id);
+CREATE INDEX IF NOT EXISTS idx_video_reports_status ON video_reports (status);
+
+ALTER TABLE videos
+    ADD COLUMN IF NOT EXISTS moderation_reason text,
+    ADD COLUMN IF NOT EXISTS moderated_by      uuid,
+    ADD COLUMN IF NOT EXISTS moderated_at      timestamptz;
@@ -0,0 +1,13 @@
+-- Historial de cambios de estado de cada video.
+
+CREATE TABLE IF NOT EXISTS video_status_events (
+    id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id    uuid NOT NULL REFERENCES videos (id) ON DELETE CASCADE,
+    "from"      text,
+    "to"        text NOT NULL,
+    reason      text,
+    actor_id    uuid,
+    created_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_video_status_events_video_id ON video_status_events (video_id);
+CREATE INDEX IF NOT EXISTS idx_video_status_events_created_at ON video_status_events (created_at);
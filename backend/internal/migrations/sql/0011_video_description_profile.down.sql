@@ -0,0 +1,3 @@
+ALTER TABLE videos
+    DROP COLUMN IF EXISTS profile,
+    DROP COLUMN IF EXISTS description;
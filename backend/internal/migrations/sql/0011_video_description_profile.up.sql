@@ -0,0 +1,6 @@
+-- Descripción del video y perfil de procesamiento elegido al subirlo. Los
+-- videos existentes se procesaron con el perfil por defecto.
+
+ALTER TABLE videos
+    ADD COLUMN IF NOT EXISTS description text,
+    ADD COLUMN IF NOT EXISTS profile     text NOT NULL DEFAULT '720p';
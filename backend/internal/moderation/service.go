@@ -0,0 +1,136 @@
+// Package moderation keeps processed videos out of voting until a moderator
+// has reviewed them, and sends published videos back to review when users
+// report them.
+package moderation
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+	"github.com/Cloud-2025-2/anb-platform/internal/video"
+)
+
+var (
+	ErrNotReviewable  = errors.New("video is not pending review")
+	ErrNotReportable  = errors.New("only published videos can be reported")
+	ErrReasonRequired = errors.New("a reason is required")
+)
+
+type Service struct {
+	videos    repo.VideoRepository
+	reports   repo.ReportRepository
+	lifecycle *video.Lifecycle
+
+	// Open reports that send a published video back to review (0 = never)
+	reportThreshold int
+}
+
+func NewService(videos repo.VideoRepository, reports repo.ReportRepository, lifecycle *video.Lifecycle, reportThreshold int) *Service {
+	return &Service{videos: videos, reports: reports, lifecycle: lifecycle, reportThreshold: reportThreshold}
+}
+
+// Queue returns the videos waiting for review, oldest first.
+func (s *Service) Queue(ctx context.Context, limit, offset int) ([]domain.Video, map[uuid.UUID]int64, error) {
+	list, err := s.videos.ListByStatus(ctx, domain.VideoPendingReview, limit, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]uuid.UUID, len(list))
+	for i := range list {
+		ids[i] = list[i].ID
+	}
+	reports, err := s.reports.CountOpenByVideo(ctx, ids)
+	return list, reports, err
+}
+
+// Reports returns the open user reports, oldest first.
+func (s *Service) Reports(ctx context.Context, limit, offset int) ([]domain.VideoReport, error) {
+	return s.reports.ListOpen(ctx, limit, offset)
+}
+
+// Approve publishes a video pending review and dismisses its reports.
+func (s *Service) Approve(ctx context.Context, videoID, moderatorID uuid.UUID) (*domain.Video, error) {
+	return s.decide(ctx, videoID, moderatorID, domain.VideoPublished, "")
+}
+
+// Reject keeps a video out of voting for good and upholds its reports.
+func (s *Service) Reject(ctx context.Context, videoID, moderatorID uuid.UUID, reason string) (*domain.Video, error) {
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	return s.decide(ctx, videoID, moderatorID, domain.VideoRejected, reason)
+}
+
+// RequestReupload asks the owner for a new file, e.g. because of bad quality.
+// The video frees its tournament slot so the owner can submit a new one.
+func (s *Service) RequestReupload(ctx context.Context, videoID, moderatorID uuid.UUID, reason string) (*domain.Video, error) {
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	return s.decide(ctx, videoID, moderatorID, domain.VideoReuploadRequested, reason)
+}
+
+func (s *Service) decide(ctx context.Context, videoID, moderatorID uuid.UUID, status domain.VideoStatus, reason string) (*domain.Video, error) {
+	v, err := s.videos.FindByID(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	if v.Status != domain.VideoPendingReview {
+		return nil, ErrNotReviewable
+	}
+
+	if err := s.lifecycle.Transition(ctx, v, status, &moderatorID, reason); err != nil {
+		return nil, err
+	}
+
+	reportStatus := domain.ReportUpheld
+	if status == domain.VideoPublished {
+		reportStatus = domain.ReportDismissed
+	}
+	if err := s.reports.Resolve(ctx, v.ID, reportStatus, moderatorID); err != nil {
+		slog.WarnContext(ctx, "moderation: failed to resolve reports", "video_id", v.ID, "error", err)
+	}
+	return v, nil
+}
+
+// Report records a user's complaint about a published video. Once the video
+// collects reportThreshold open reports it is hidden and sent back to review.
+func (s *Service) Report(ctx context.Context, videoID, reporterID uuid.UUID, reason, details string) error {
+	if reason == "" {
+		return ErrReasonRequired
+	}
+	v, err := s.videos.FindByID(ctx, videoID)
+	if err != nil {
+		return err
+	}
+	if v.Status != domain.VideoPublished {
+		return ErrNotReportable
+	}
+	if err := s.reports.Create(ctx, &domain.VideoReport{
+		VideoID:    videoID,
+		ReporterID: reporterID,
+		Reason:     reason,
+		Details:    details,
+		Status:     domain.ReportOpen,
+	}); err != nil {
+		return err
+	}
+
+	if s.reportThreshold <= 0 {
+		return nil
+	}
+	n, err := s.reports.CountOpen(ctx, videoID)
+	if err != nil || n < int64(s.reportThreshold) {
+		return err
+	}
+	if err := s.lifecycle.Transition(ctx, v, domain.VideoPendingReview, nil, "reported by users"); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "moderation: video sent back to review", "video_id", v.ID, "reports", n)
+	return nil
+}
@@ -0,0 +1,166 @@
+package processing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stderrTailBytes is how much of the end of ffmpeg's stderr an FFmpegError
+// keeps; the reason of a failure is in the last lines.
+const stderrTailBytes = 2048
+
+// FFmpegError is a failed ffmpeg (or ffprobe) run with the tail of its
+// stderr, so the error says why and not just "exit status 1".
+type FFmpegError struct {
+	Step   string
+	Err    error
+	Stderr string
+}
+
+func (e *FFmpegError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("ffmpeg %s: %v", e.Step, e.Err)
+	}
+	return fmt.Sprintf("ffmpeg %s: %v: %s", e.Step, e.Err, e.Stderr)
+}
+
+func (e *FFmpegError) Unwrap() error { return e.Err }
+
+// ProgressFunc receives the percent complete (0-100) of the whole pipeline and
+// the step running.
+type ProgressFunc func(step string, percent float64)
+
+// runFFmpeg runs ffmpeg with args, killing it when ctx is done or after
+// timeout. expected is the duration in seconds of the output being written;
+// when known, the -progress output is turned into the fraction of the step
+// done and passed to progress.
+func runFFmpeg(ctx context.Context, step string, timeout time.Duration, expected float64, progress func(float64), args ...string) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	full := append([]string{"-hide_banner", "-nostdin", "-nostats", "-y", "-progress", "pipe:1"}, args...)
+	cmd := exec.CommandContext(ctx, "ffmpeg", full...)
+	stderr := &tailBuffer{max: stderrTailBytes}
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return &FFmpegError{Step: step, Err: err}
+	}
+	if err := cmd.Start(); err != nil {
+		return &FFmpegError{Step: step, Err: err}
+	}
+
+	readProgress(stdout, expected, progress)
+
+	if err := cmd.Wait(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			// Killed: the cause is the cancellation or the timeout, not the signal
+			if errors.Is(ctxErr, context.DeadlineExceeded) && timeout > 0 {
+				ctxErr = fmt.Errorf("timed out after %s: %w", timeout, ctxErr)
+			}
+			err = ctxErr
+		}
+		return &FFmpegError{Step: step, Err: err, Stderr: stderr.String()}
+	}
+	return nil
+}
+
+// readProgress consumes the key=value blocks ffmpeg writes with -progress
+// until the pipe closes.
+func readProgress(r io.Reader, expected float64, progress func(float64)) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		key, value, ok := strings.Cut(sc.Text(), "=")
+		if !ok || progress == nil {
+			continue
+		}
+		switch key {
+		case "out_time_us", "out_time_ms": // both are microseconds
+			us, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || us < 0 || expected <= 0 {
+				continue
+			}
+			progress(min(float64(us)/1e6/expected, 1))
+		case "progress":
+			if value == "end" {
+				progress(1)
+			}
+		}
+	}
+}
+
+// FFmpegVersion returns the first line of `ffmpeg -version` (e.g.
+// "ffmpeg version 6.1.1 Copyright ..."). It fails when the binary the
+// pipeline runs is missing from PATH.
+func FFmpegVersion(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, "ffmpeg", "-version").Output()
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg -version: %w", err)
+	}
+	line, _, _ := bufio.NewReader(bytes.NewReader(out)).ReadLine()
+	return string(line), nil
+}
+
+// probeDuration returns the duration in seconds of the media at path.
+func probeDuration(ctx context.Context, path string, timeout time.Duration) (float64, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, "ffprobe", "-v", "error",
+		"-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", path)
+	stderr := &tailBuffer{max: stderrTailBytes}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, &FFmpegError{Step: "probe", Err: err, Stderr: stderr.String()}
+	}
+	d, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, &FFmpegError{Step: "probe", Err: fmt.Errorf("unexpected duration %q", strings.TrimSpace(string(out)))}
+	}
+	return d, nil
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = t.buf[over:]
+	}
+	return len(p), nil
+}
+
+// String returns the kept output from its first complete line, trimmed.
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := t.buf
+	if len(b) == t.max {
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			b = b[i+1:]
+		}
+	}
+	return strings.TrimSpace(string(b))
+}
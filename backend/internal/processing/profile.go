@@ -0,0 +1,43 @@
+package processing
+
+import "fmt"
+
+// Profile names a set of processing options an owner can pick when
+// reprocessing a video.
+type Profile string
+
+const (
+	ProfileStandard Profile = "720p" // perfil por defecto al subir
+	ProfileHD       Profile = "1080p"
+	ProfileLight    Profile = "480p"
+)
+
+// DefaultProfile is used for new uploads and for tasks without a profile.
+const DefaultProfile = ProfileStandard
+
+var profileSizes = map[Profile][2]int{
+	ProfileStandard: {1280, 720},
+	ProfileHD:       {1920, 1080},
+	ProfileLight:    {854, 480},
+}
+
+// ParseProfile validates a profile name; "" means DefaultProfile.
+func ParseProfile(s string) (Profile, error) {
+	if s == "" {
+		return DefaultProfile, nil
+	}
+	p := Profile(s)
+	if _, ok := profileSizes[p]; !ok {
+		return "", fmt.Errorf("unknown processing profile %q", s)
+	}
+	return p, nil
+}
+
+// Size returns the output width and height of the profile.
+func (p Profile) Size() (width, height int) {
+	size, ok := profileSizes[p]
+	if !ok {
+		size = profileSizes[DefaultProfile]
+	}
+	return size[0], size[1]
+}
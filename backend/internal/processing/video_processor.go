@@ -1,20 +1,25 @@
 package processing
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
-	ffmpeg "github.com/u2takey/ffmpeg-go"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/metrics"
+	"github.com/Cloud-2025-2/anb-platform/internal/tracing"
 )
 
 type VideoProcessor struct {
-	tempDir    string
-	assetsDir  string
-	storageDir string
+	tempDir     string
+	assetsDir   string
+	storageDir  string
+	stepTimeout time.Duration
 }
 
 type ProcessingOptions struct {
@@ -27,16 +32,35 @@ type ProcessingOptions struct {
 	RemoveAudio   bool
 }
 
-func NewVideoProcessor(tempDir, assetsDir, storageDir string) *VideoProcessor {
+// NewVideoProcessor returns a processor that kills any ffmpeg step running
+// longer than stepTimeout (0 = no limit), so a pathological input cannot
+// hold a worker forever.
+func NewVideoProcessor(tempDir, assetsDir, storageDir string, stepTimeout time.Duration) *VideoProcessor {
 	return &VideoProcessor{
-		tempDir:    tempDir,
-		assetsDir:  assetsDir,
-		storageDir: storageDir,
+		tempDir:     tempDir,
+		assetsDir:   assetsDir,
+		storageDir:  storageDir,
+		stepTimeout: stepTimeout,
 	}
 }
 
 func (vp *VideoProcessor) ProcessVideo(inputPath, outputPath string) error {
-	log.Printf("Starting video processing: %s -> %s", inputPath, outputPath)
+	return vp.ProcessVideoWithProfile(context.Background(), inputPath, outputPath, DefaultProfile, nil)
+}
+
+// Share of the whole pipeline each step accounts for in the reported
+// progress, roughly by how long it takes; cut only copies streams.
+var stepShares = []struct {
+	step  string
+	share float64
+}{{"cut", 10}, {"resize", 35}, {"watermark", 25}, {"concat", 30}}
+
+// ProcessVideoWithProfile runs the pipeline with the output size of profile.
+// Each step is traced as a child span of ctx. Cancelling ctx kills the running
+// ffmpeg; the intermediate files, and a partial output, are removed either way.
+// progress, if not nil, is called as the steps advance.
+func (vp *VideoProcessor) ProcessVideoWithProfile(ctx context.Context, inputPath, outputPath string, profile Profile, progress ProgressFunc) (err error) {
+	slog.InfoContext(ctx, "processing: starting pipeline", "profile", profile, "input", inputPath, "output", outputPath)
 
 	// Check if input file exists
 	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
@@ -49,80 +73,130 @@ func (vp *VideoProcessor) ProcessVideo(inputPath, outputPath string) error {
 	}
 
 	// Create processing options
+	width, height := profile.Size()
 	opts := ProcessingOptions{
 		MaxDuration:   30,
-		Width:         1280,
-		Height:        720,
+		Width:         width,
+		Height:        height,
 		WatermarkPath: filepath.Join(vp.assetsDir, "logo.png"),
 		IntroPath:     filepath.Join(vp.assetsDir, "intro.mp4"),
 		OutroPath:     filepath.Join(vp.assetsDir, "outro.mp4"),
 		RemoveAudio:   true,
 	}
 
-	// Step 1: Get video info and cut to max duration
+	// The input's duration turns ffmpeg's output time into a percentage; an
+	// input ffprobe cannot read would fail the first step anyway
+	duration, err := probeDuration(ctx, inputPath, vp.stepTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to read video: %w", err)
+	}
+	clip := min(duration, float64(opts.MaxDuration))
+
+	// Temp files are removed even when their step fails or is killed halfway
 	tempCut := filepath.Join(vp.tempDir, fmt.Sprintf("cut_%s.mp4", uuid.New().String()))
-	if err := vp.cutVideo(inputPath, tempCut, opts.MaxDuration); err != nil {
+	tempResized := filepath.Join(vp.tempDir, fmt.Sprintf("resized_%s.mp4", uuid.New().String()))
+	tempWatermarked := filepath.Join(vp.tempDir, fmt.Sprintf("watermarked_%s.mp4", uuid.New().String()))
+	defer func() {
+		for _, path := range []string{tempCut, tempResized, tempWatermarked} {
+			os.Remove(path)
+		}
+		if err != nil {
+			os.Remove(outputPath)
+		}
+	}()
+
+	// Step 1: Get video info and cut to max duration
+	if err := vp.timeStep(ctx, "cut", progress, func(ctx context.Context, report func(float64)) error {
+		return vp.cutVideo(ctx, inputPath, tempCut, opts.MaxDuration, clip, report)
+	}); err != nil {
 		return fmt.Errorf("failed to cut video: %w", err)
 	}
-	defer os.Remove(tempCut)
 
 	// Step 2: Adjust aspect ratio and resolution, remove audio
-	tempResized := filepath.Join(vp.tempDir, fmt.Sprintf("resized_%s.mp4", uuid.New().String()))
-	if err := vp.resizeAndRemoveAudio(tempCut, tempResized, opts); err != nil {
+	if err := vp.timeStep(ctx, "resize", progress, func(ctx context.Context, report func(float64)) error {
+		return vp.resizeAndRemoveAudio(ctx, tempCut, tempResized, opts, clip, report)
+	}); err != nil {
 		return fmt.Errorf("failed to resize video: %w", err)
 	}
-	defer os.Remove(tempResized)
 
 	// Step 3: Add watermark
-	tempWatermarked := filepath.Join(vp.tempDir, fmt.Sprintf("watermarked_%s.mp4", uuid.New().String()))
-	if err := vp.addWatermark(tempResized, tempWatermarked, opts.WatermarkPath); err != nil {
+	if err := vp.timeStep(ctx, "watermark", progress, func(ctx context.Context, report func(float64)) error {
+		return vp.addWatermark(ctx, tempResized, tempWatermarked, opts.WatermarkPath, clip, report)
+	}); err != nil {
 		return fmt.Errorf("failed to add watermark: %w", err)
 	}
-	defer os.Remove(tempWatermarked)
 
 	// Step 4: Concatenate intro + main video + outro
-	if err := vp.concatenateVideos(opts.IntroPath, tempWatermarked, opts.OutroPath, outputPath); err != nil {
+	if err := vp.timeStep(ctx, "concat", progress, func(ctx context.Context, report func(float64)) error {
+		return vp.concatenateVideos(ctx, opts.IntroPath, tempWatermarked, opts.OutroPath, outputPath, opts, clip, report)
+	}); err != nil {
 		return fmt.Errorf("failed to concatenate videos: %w", err)
 	}
 
-	log.Printf("Video processing completed successfully: %s", outputPath)
+	slog.InfoContext(ctx, "processing: pipeline completed", "output", outputPath)
 	return nil
 }
 
-func (vp *VideoProcessor) cutVideo(inputPath, outputPath string, maxDuration int) error {
-	log.Printf("Cutting video to %d seconds", maxDuration)
-
-	return ffmpeg.Input(inputPath).
-		Output(outputPath, ffmpeg.KwArgs{
-			"t":                 strconv.Itoa(maxDuration),
-			"c":                 "copy", // Copy streams without re-encoding when possible
-			"avoid_negative_ts": "make_zero",
-		}).
-		OverWriteOutput().
-		Run()
-}
+// timeStep runs one ffmpeg step of the pipeline in its own span and records
+// its duration. run gets a function to report the fraction of the step done,
+// which is forwarded to progress as a percentage of the whole pipeline.
+func (vp *VideoProcessor) timeStep(ctx context.Context, step string, progress ProgressFunc, run func(context.Context, func(float64)) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-func (vp *VideoProcessor) resizeAndRemoveAudio(inputPath, outputPath string, opts ProcessingOptions) error {
-	log.Printf("Resizing to %dx%d and removing audio", opts.Width, opts.Height)
-
-	args := ffmpeg.KwArgs{
-		"vf":     fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:black", opts.Width, opts.Height, opts.Width, opts.Height),
-		"c:v":    "libx264",
-		"crf":    "23",
-		"preset": "medium",
-		"r":      "30", // Force 30fps to maintain timing
-		"an":     "",   // Remove audio
-		"vsync":  "1",  // Ensure proper frame sync
+	var before, share float64
+	for _, s := range stepShares {
+		if s.step == step {
+			share = s.share
+			break
+		}
+		before += s.share
+	}
+	report := func(fraction float64) {
+		if progress != nil {
+			progress(step, before+share*fraction)
+		}
 	}
 
-	return ffmpeg.Input(inputPath).
-		Output(outputPath, args).
-		OverWriteOutput().
-		Run()
+	ctx, span := tracing.Tracer.Start(ctx, "ffmpeg."+step)
+	start := time.Now()
+	report(0)
+	err := run(ctx, report)
+	metrics.Since(metrics.FFmpegStepDuration.WithLabelValues(step, metrics.Result(err)), start)
+	slog.DebugContext(ctx, "processing: ffmpeg step finished", "step", step, "duration_ms", time.Since(start).Milliseconds(), "error", err)
+	tracing.End(span, err)
+	return err
 }
 
-func (vp *VideoProcessor) addWatermark(inputPath, outputPath, watermarkPath string) error {
-	log.Printf("Adding watermark: %s", watermarkPath)
+func (vp *VideoProcessor) cutVideo(ctx context.Context, inputPath, outputPath string, maxDuration int, expected float64, report func(float64)) error {
+	slog.Debug("processing: cutting video", "max_seconds", maxDuration)
+
+	return runFFmpeg(ctx, "cut", vp.stepTimeout, expected, report,
+		"-i", inputPath,
+		"-t", strconv.Itoa(maxDuration),
+		"-c", "copy", // Copy streams without re-encoding when possible
+		"-avoid_negative_ts", "make_zero",
+		outputPath)
+}
+
+func (vp *VideoProcessor) resizeAndRemoveAudio(ctx context.Context, inputPath, outputPath string, opts ProcessingOptions, expected float64, report func(float64)) error {
+	slog.Debug("processing: resizing and removing audio", "width", opts.Width, "height", opts.Height)
+
+	return runFFmpeg(ctx, "resize", vp.stepTimeout, expected, report,
+		"-i", inputPath,
+		"-vf", fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:black", opts.Width, opts.Height, opts.Width, opts.Height),
+		"-c:v", "libx264",
+		"-crf", "23",
+		"-preset", "medium",
+		"-r", "30", // Force 30fps to maintain timing
+		"-an",         // Remove audio
+		"-vsync", "1", // Ensure proper frame sync
+		outputPath)
+}
+
+func (vp *VideoProcessor) addWatermark(ctx context.Context, inputPath, outputPath, watermarkPath string, expected float64, report func(float64)) error {
+	slog.Debug("processing: adding watermark", "watermark", watermarkPath)
 
 	// Check if watermark exists
 	if _, err := os.Stat(watermarkPath); os.IsNotExist(err) {
@@ -130,67 +204,66 @@ func (vp *VideoProcessor) addWatermark(inputPath, outputPath, watermarkPath stri
 	}
 
 	// Position watermark at bottom-right corner with 10px padding
-	return ffmpeg.Filter(
-		[]*ffmpeg.Stream{
-			ffmpeg.Input(inputPath),
-			ffmpeg.Input(watermarkPath),
-		}, "overlay", ffmpeg.Args{"main_w-overlay_w-10:main_h-overlay_h-10"}).
-		Output(outputPath, ffmpeg.KwArgs{
-			"c:v":    "libx264",
-			"crf":    "23",
-			"preset": "medium",
-		}).
-		OverWriteOutput().
-		Run()
+	return runFFmpeg(ctx, "watermark", vp.stepTimeout, expected, report,
+		"-i", inputPath,
+		"-i", watermarkPath,
+		"-filter_complex", "overlay=main_w-overlay_w-10:main_h-overlay_h-10",
+		"-c:v", "libx264",
+		"-crf", "23",
+		"-preset", "medium",
+		outputPath)
 }
 
-func (vp *VideoProcessor) concatenateVideos(introPath, mainPath, outroPath, outputPath string) error {
-	log.Printf("Concatenating intro + main + outro videos")
+func (vp *VideoProcessor) concatenateVideos(ctx context.Context, introPath, mainPath, outroPath, outputPath string, opts ProcessingOptions, clip float64, report func(float64)) error {
+	slog.Debug("processing: concatenating intro, video and outro")
 
 	for _, path := range []string{introPath, mainPath, outroPath} {
 		if _, err := os.Stat(path); os.IsNotExist(err) {
-			log.Printf("WARNING: File does not exist: %s", path)
+			slog.Warn("processing: file does not exist", "path", path)
 			return fmt.Errorf("required file missing: %s", path)
 		}
-		log.Printf("File exists: %s", path)
+		slog.Debug("processing: file exists", "path", path)
 	}
 
 	absIntroPath, _ := filepath.Abs(introPath)
 	absMainPath, _ := filepath.Abs(mainPath)
 	absOutroPath, _ := filepath.Abs(outroPath)
 
+	// Progress is measured against intro + clip + outro; without their
+	// durations the step only reports its start and end
+	expected := clip
+	for _, path := range []string{absIntroPath, absOutroPath} {
+		d, err := probeDuration(ctx, path, vp.stepTimeout)
+		if err != nil {
+			expected = 0
+			break
+		}
+		expected += d
+	}
+
+	// concat needs every segment at the same size; intro and outro are
+	// scaled to the profile's resolution
+	fit := fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2:black,setsar=1",
+		opts.Width, opts.Height, opts.Width, opts.Height)
+	filter := fmt.Sprintf("[0:v]%[1]s[v0];[1:v]%[1]s[v1];[2:v]%[1]s[v2];[v0][v1][v2]concat=n=3:v=1:a=0[out]", fit)
+
 	// Use filter_complex for better handling of different formats
-	return ffmpeg.Filter(
-		[]*ffmpeg.Stream{
-			ffmpeg.Input(absIntroPath),
-			ffmpeg.Input(absMainPath),
-			ffmpeg.Input(absOutroPath),
-		},
-		"concat", ffmpeg.Args{"n=3:v=1:a=0"}, // 3 inputs, 1 video stream, 0 audio streams
-	).Output(outputPath, ffmpeg.KwArgs{
-		"c:v":    "libx264",
-		"crf":    "23",
-		"preset": "medium",
-		"r":      "30",
-		"an":     "", // No audio
-	}).OverWriteOutput().Run()
+	return runFFmpeg(ctx, "concat", vp.stepTimeout, expected, report,
+		"-i", absIntroPath,
+		"-i", absMainPath,
+		"-i", absOutroPath,
+		"-filter_complex", filter, // 3 inputs, 1 video stream, 0 audio streams
+		"-map", "[out]",
+		"-c:v", "libx264",
+		"-crf", "23",
+		"-preset", "medium",
+		"-r", "30",
+		"-an", // No audio
+		outputPath)
 }
 
-
 func (vp *VideoProcessor) GetVideoInfo(inputPath string) (duration float64, width, height int, err error) {
 	// This would typically use ffprobe, but for simplicity we'll return defaults
 	// In a real implementation, you'd parse ffprobe output
 	return 0, 0, 0, nil
 }
-
-func (vp *VideoProcessor) BatchProcess(tasks []string) error {
-	log.Printf("Starting batch processing of %d videos", len(tasks))
-
-	for i, _ := range tasks {
-		log.Printf("Processing batch item %d/%d", i+1, len(tasks))
-		// Process each video in the batch
-		// Implementation would depend on your specific batch processing needs
-	}
-
-	return nil
-}
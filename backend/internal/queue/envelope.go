@@ -0,0 +1,244 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/logging"
+)
+
+// Message types carried in Envelope.Type.
+const (
+	TypeVideoProcess    = "video.process"
+	TypeVideoDeadLetter = "video.process.dead_letter"
+)
+
+// SchemaVersion is the version of the messages this build writes. Version 1
+// is the bare task JSON written before the envelope existed; it is still read
+// and upgraded. Messages of a later version are rejected: the producer is
+// newer than this worker.
+//
+// The JSON Schema of each version lives in schemas/. A change that running
+// workers can't read needs a new version, its schemas and an upgrade path in
+// DecodeTask; unknown versions, older or newer, are rejected.
+const SchemaVersion = 2
+
+// Envelope wraps every message written to the queue.
+type Envelope struct {
+	Type          string `json:"type"`
+	SchemaVersion int    `json:"schema_version"`
+	ID            string `json:"id"`          // único por mensaje; un reintento es otro mensaje
+	ProducerID    string `json:"producer_id"` // binario@host que lo escribió
+	// Igual en todos los mensajes de la misma operación (la tarea y sus
+	// reintentos), para que el consumidor descarte los duplicados
+	IdempotencyKey string            `json:"idempotency_key"`
+	CreatedAt      time.Time         `json:"created_at"`
+	RequestID      string            `json:"request_id,omitempty"`
+	Trace          map[string]string `json:"trace,omitempty"` // traceparent, tracestate
+	Payload        json.RawMessage   `json:"payload"`
+}
+
+var (
+	// ErrInvalidMessage is a message that does not match its schema.
+	ErrInvalidMessage = errors.New("queue: invalid message")
+	// ErrUnsupportedVersion is a message of a schema version this build
+	// does not know.
+	ErrUnsupportedVersion = errors.New("queue: unsupported schema version")
+)
+
+// producerID identifies this process in the envelopes it writes.
+var producerID = func() string {
+	host, _ := os.Hostname()
+	return filepath.Base(os.Args[0]) + "@" + host
+}()
+
+// NewEnvelope wraps payload as a message of msgType, with the request ID and
+// trace context of ctx.
+func NewEnvelope(ctx context.Context, msgType, idempotencyKey string, payload any) (Envelope, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Envelope{}, err
+	}
+	trace := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, trace)
+	return Envelope{
+		Type:           msgType,
+		SchemaVersion:  SchemaVersion,
+		ID:             uuid.NewString(),
+		ProducerID:     producerID,
+		IdempotencyKey: idempotencyKey,
+		CreatedAt:      time.Now().UTC(),
+		RequestID:      logging.Value(ctx, FieldRequestID),
+		Trace:          trace,
+		Payload:        body,
+	}, nil
+}
+
+// EncodeTask returns the message that carries task.
+func EncodeTask(ctx context.Context, task Task) ([]byte, error) {
+	env, err := NewEnvelope(ctx, TypeVideoProcess, task.IdempotencyKey(), task)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(env)
+}
+
+// EncodeDeadLetter returns the message that carries dl.
+func EncodeDeadLetter(ctx context.Context, dl DeadLetter) ([]byte, error) {
+	env, err := NewEnvelope(ctx, TypeVideoDeadLetter, dl.IdempotencyKey(), dl)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(env)
+}
+
+// IdempotencyKey is shared by the task and its retries.
+func (t Task) IdempotencyKey() string {
+	if t.TaskID != "" {
+		return TypeVideoProcess + ":" + t.TaskID
+	}
+	// Tasks without a record (e.g. from test tools)
+	return TypeVideoProcess + ":video:" + t.VideoID + ":" + strconv.FormatInt(t.Timestamp.UnixNano(), 10)
+}
+
+// IdempotencyKey of a dead letter: the task's, or a fresh one for a rejected
+// message.
+func (dl DeadLetter) IdempotencyKey() string {
+	if dl.TaskID != "" {
+		return TypeVideoDeadLetter + ":" + dl.TaskID
+	}
+	return TypeVideoDeadLetter + ":" + uuid.NewString()
+}
+
+// DecodeTask reads a video.process message of any supported version. It
+// fails with ErrInvalidMessage or ErrUnsupportedVersion when the message can
+// never be processed. A message of an older version is upgraded: it comes
+// back with its envelope filled in from the task, and its original
+// SchemaVersion.
+func DecodeTask(body []byte) (Envelope, Task, error) {
+	var probe struct {
+		SchemaVersion *int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return Envelope{}, Task{}, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+
+	// Version 1 messages predate schema_version
+	version := 1
+	if probe.SchemaVersion != nil {
+		version = *probe.SchemaVersion
+	}
+	switch version {
+	case 1:
+		return decodeTaskV1(body)
+	case SchemaVersion:
+		return decodeTaskV2(body)
+	default:
+		return Envelope{}, Task{}, fmt.Errorf("%w: %d (this build reads 1 to %d)", ErrUnsupportedVersion, version, SchemaVersion)
+	}
+}
+
+// decodeTaskV1 upgrades a version 1 message: the bare task.
+func decodeTaskV1(body []byte) (Envelope, Task, error) {
+	var task Task
+	if err := validate("video.process.v1.json", body); err != nil {
+		return Envelope{}, task, err
+	}
+	if err := json.Unmarshal(body, &task); err != nil {
+		return Envelope{}, task, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	env := Envelope{
+		Type:           TypeVideoProcess,
+		SchemaVersion:  1,
+		IdempotencyKey: task.IdempotencyKey(),
+		CreatedAt:      task.Timestamp,
+		Payload:        body,
+	}
+	return env, task, nil
+}
+
+func decodeTaskV2(body []byte) (Envelope, Task, error) {
+	var env Envelope
+	var task Task
+	if err := validate("envelope.v2.json", body); err != nil {
+		return env, task, err
+	}
+	if err := json.Unmarshal(body, &env); err != nil {
+		return env, task, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if env.Type != TypeVideoProcess {
+		return env, task, fmt.Errorf("%w: unexpected type %q", ErrInvalidMessage, env.Type)
+	}
+	if err := validate("video.process.v2.json", env.Payload); err != nil {
+		return env, task, err
+	}
+	if err := json.Unmarshal(env.Payload, &task); err != nil {
+		return env, task, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	return env, task, nil
+}
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// schemas holds the compiled schemas by file name.
+var schemas = compileSchemas()
+
+func compileSchemas() map[string]*jsonschema.Schema {
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	names, err := fs.Glob(schemaFiles, "schemas/*.json")
+	if err != nil {
+		panic(err)
+	}
+	for _, name := range names {
+		f, err := schemaFiles.Open(name)
+		if err != nil {
+			panic(err)
+		}
+		doc, err := jsonschema.UnmarshalJSON(f)
+		f.Close()
+		if err != nil {
+			panic(fmt.Sprintf("queue: schema %s: %v", name, err))
+		}
+		if err := c.AddResource(path.Base(name), doc); err != nil {
+			panic(fmt.Sprintf("queue: schema %s: %v", name, err))
+		}
+	}
+	compiled := make(map[string]*jsonschema.Schema, len(names))
+	for _, name := range names {
+		sch, err := c.Compile(path.Base(name))
+		if err != nil {
+			panic(fmt.Sprintf("queue: schema %s: %v", name, err))
+		}
+		compiled[path.Base(name)] = sch
+	}
+	return compiled
+}
+
+// validate checks body against the schema in schemas/name.
+func validate(name string, body []byte) error {
+	inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if err := schemas[name].Validate(inst); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidMessage, name, err)
+	}
+	return nil
+}
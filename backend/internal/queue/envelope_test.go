@@ -0,0 +1,191 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+)
+
+func fixtureTask() Task {
+	notBefore := time.Date(2025, 10, 1, 12, 0, 8, 0, time.UTC)
+	return Task{
+		TaskID:     "6f1c2a3b-4d5e-4f60-8a7b-9c0d1e2f3a4b",
+		VideoID:    "0b7c6f8e-3f0a-4d8e-9c1a-2b3c4d5e6f70",
+		UserID:     "a1b2c3d4-e5f6-4a7b-8c9d-0e1f2a3b4c5d",
+		Title:      "Clavada",
+		FilePath:   "/storage/original.mp4",
+		Timestamp:  time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC),
+		RetryCount: 2,
+		Profile:    "720p",
+		Lane:       domain.LaneReprocess,
+		NotBefore:  &notBefore,
+	}
+}
+
+// encodedTask is the version 2 message of fixtureTask, as a map that mutate
+// may change before it is encoded again.
+func encodedTask(t *testing.T, mutate func(env, payload map[string]any)) []byte {
+	t.Helper()
+	body, err := EncodeTask(context.Background(), fixtureTask())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mutate == nil {
+		return body
+	}
+	var env map[string]any
+	if err := json.Unmarshal(body, &env); err != nil {
+		t.Fatal(err)
+	}
+	mutate(env, env["payload"].(map[string]any))
+	body, err = json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestDecodeTask(t *testing.T) {
+	v1 := `{"video_id":"0b7c6f8e-3f0a-4d8e-9c1a-2b3c4d5e6f70","user_id":"u","title":"Clavada",` +
+		`"file_path":"/storage/original.mp4","timestamp":"2025-10-01T12:00:00Z","retry_count":1}`
+	v1Task := Task{
+		VideoID:    "0b7c6f8e-3f0a-4d8e-9c1a-2b3c4d5e6f70",
+		UserID:     "u",
+		Title:      "Clavada",
+		FilePath:   "/storage/original.mp4",
+		Timestamp:  time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC),
+		RetryCount: 1,
+	}
+
+	tests := []struct {
+		name        string
+		body        []byte
+		wantVersion int
+		wantTask    *Task
+		wantErr     error
+	}{
+		{name: "bare v1 task is upgraded", body: []byte(v1), wantVersion: 1, wantTask: &v1Task},
+		{
+			name:        "explicit v1 task is upgraded",
+			body:        []byte(`{"schema_version":1,` + v1[1:]),
+			wantVersion: 1,
+			wantTask:    &v1Task,
+		},
+		{name: "valid v2 envelope", body: encodedTask(t, nil), wantVersion: 2, wantTask: &[]Task{fixtureTask()}[0]},
+		{
+			name:    "v3 is unsupported",
+			body:    encodedTask(t, func(env, _ map[string]any) { env["schema_version"] = 3 }),
+			wantErr: ErrUnsupportedVersion,
+		},
+		{
+			name:    "unknown older version is unsupported",
+			body:    encodedTask(t, func(env, _ map[string]any) { env["schema_version"] = 0 }),
+			wantErr: ErrUnsupportedVersion,
+		},
+		{name: "malformed JSON", body: []byte(`{"video_id":`), wantErr: ErrInvalidMessage},
+		{name: "v1 without file_path", body: []byte(`{"video_id":"0b7c6f8e-3f0a-4d8e-9c1a-2b3c4d5e6f70"}`), wantErr: ErrInvalidMessage},
+		{name: "v1 with invalid video_id", body: []byte(`{"video_id":"42","file_path":"/x.mp4"}`), wantErr: ErrInvalidMessage},
+		{
+			name:    "v2 envelope without id",
+			body:    encodedTask(t, func(env, _ map[string]any) { delete(env, "id") }),
+			wantErr: ErrInvalidMessage,
+		},
+		{
+			name:    "v2 envelope of another type",
+			body:    encodedTask(t, func(env, _ map[string]any) { env["type"] = TypeVideoDeadLetter }),
+			wantErr: ErrInvalidMessage,
+		},
+		{
+			name:    "v2 payload without video_id",
+			body:    encodedTask(t, func(_, payload map[string]any) { delete(payload, "video_id") }),
+			wantErr: ErrInvalidMessage,
+		},
+		{
+			name:    "v2 payload with invalid video_id",
+			body:    encodedTask(t, func(_, payload map[string]any) { payload["video_id"] = "42" }),
+			wantErr: ErrInvalidMessage,
+		},
+		{
+			name:    "v2 payload with negative retry_count",
+			body:    encodedTask(t, func(_, payload map[string]any) { payload["retry_count"] = -1 }),
+			wantErr: ErrInvalidMessage,
+		},
+		{
+			name:    "v2 payload with unknown lane",
+			body:    encodedTask(t, func(_, payload map[string]any) { payload["lane"] = "urgent" }),
+			wantErr: ErrInvalidMessage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, task, err := DecodeTask(tt.body)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if env.SchemaVersion != tt.wantVersion || env.Type != TypeVideoProcess {
+				t.Errorf("envelope = %s v%d, want %s v%d", env.Type, env.SchemaVersion, TypeVideoProcess, tt.wantVersion)
+			}
+			if env.IdempotencyKey != tt.wantTask.IdempotencyKey() {
+				t.Errorf("idempotency key = %q, want %q", env.IdempotencyKey, tt.wantTask.IdempotencyKey())
+			}
+			if !reflect.DeepEqual(task, *tt.wantTask) {
+				t.Errorf("task = %+v, want %+v", task, *tt.wantTask)
+			}
+		})
+	}
+}
+
+func TestDeadLetterRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		dl   DeadLetter
+	}{
+		{
+			name: "exhausted retries",
+			dl:   DeadLetter{Task: fixtureTask(), Error: "ffmpeg failed", FailedAt: time.Date(2025, 10, 1, 12, 1, 0, 0, time.UTC)},
+		},
+		{
+			name: "rejected message",
+			dl:   DeadLetter{Error: "queue: unsupported schema version: 3", FailedAt: time.Date(2025, 10, 1, 12, 1, 0, 0, time.UTC), Raw: `{"schema_version":3}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := EncodeDeadLetter(context.Background(), tt.dl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := validate("envelope.v2.json", body); err != nil {
+				t.Fatal(err)
+			}
+			var env Envelope
+			if err := json.Unmarshal(body, &env); err != nil {
+				t.Fatal(err)
+			}
+			if env.Type != TypeVideoDeadLetter || env.SchemaVersion != SchemaVersion {
+				t.Errorf("envelope = %s v%d", env.Type, env.SchemaVersion)
+			}
+			if err := validate("video.process.dead_letter.v2.json", env.Payload); err != nil {
+				t.Fatal(err)
+			}
+			var got DeadLetter
+			if err := json.Unmarshal(env.Payload, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.dl) {
+				t.Errorf("dead letter = %+v, want %+v", got, tt.dl)
+			}
+		})
+	}
+}
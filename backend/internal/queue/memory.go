@@ -0,0 +1,191 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+)
+
+// ErrFull is returned by Memory.Publish when the queue holds its capacity.
+var ErrFull = errors.New("queue: memory queue is full")
+
+// Memory is a queue held in the memory of the process. It is meant for tests
+// and single-process tools: tasks are lost when the process exits and only
+// consumers of the same Memory see them. Like the brokers, it hands out the
+// tasks of the highest lane first.
+type Memory struct {
+	capacity int
+	// added is signalled when a task is queued
+	added chan struct{}
+
+	mu    sync.Mutex
+	lanes [][]Task // by lane priority
+	n     int
+	dead  []DeadLetter
+}
+
+// MemoryCapacity is the capacity of the Memory queue of QUEUE_BACKEND=memory.
+const MemoryCapacity = 1000
+
+// NewMemory returns a queue that holds up to capacity tasks.
+func NewMemory(capacity int) *Memory {
+	return &Memory{
+		capacity: max(capacity, 1),
+		added:    make(chan struct{}, 1),
+		lanes:    make([][]Task, len(domain.TaskLanes)),
+	}
+}
+
+func (m *Memory) Publish(_ context.Context, task Task) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.n >= m.capacity {
+		return ErrFull
+	}
+	prio := task.Lane.Priority()
+	m.lanes[prio] = append(m.lanes[prio], task)
+	m.n++
+	m.signal()
+	return nil
+}
+
+func (m *Memory) Ping(context.Context) error { return nil }
+
+func (m *Memory) Close() error { return nil }
+
+// Len is the number of tasks waiting.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.n
+}
+
+// DeadLetters returns the tasks that exhausted their retries.
+func (m *Memory) DeadLetters() []DeadLetter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DeadLetter(nil), m.dead...)
+}
+
+func (m *Memory) deadLetter(_ context.Context, dl DeadLetter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dead = append(m.dead, dl)
+	return nil
+}
+
+// take waits for the first task of the highest non-empty lane.
+func (m *Memory) take(ctx context.Context) (Task, bool) {
+	for {
+		if task, ok := m.pop(); ok {
+			return task, true
+		}
+		select {
+		case <-m.added:
+		case <-ctx.Done():
+			return Task{}, false
+		}
+	}
+}
+
+func (m *Memory) pop() (Task, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for prio, tasks := range m.lanes {
+		if len(tasks) > 0 {
+			m.lanes[prio] = tasks[1:]
+			m.n--
+			if m.n > 0 {
+				// Wake another consumer for the rest
+				m.signal()
+			}
+			return tasks[0], true
+		}
+	}
+	return Task{}, false
+}
+
+func (m *Memory) signal() {
+	select {
+	case m.added <- struct{}{}:
+	default:
+	}
+}
+
+// MemoryConsumer runs the tasks of a Memory queue.
+type MemoryConsumer struct {
+	queue        *Memory
+	handler      Handler
+	pool         *Pool
+	drainTimeout time.Duration
+}
+
+// NewMemoryConsumer returns a consumer of m that runs handler on up to
+// concurrency tasks at once.
+func NewMemoryConsumer(m *Memory, handler Handler, concurrency int, drainTimeout time.Duration) *MemoryConsumer {
+	return &MemoryConsumer{queue: m, handler: handler, pool: NewPool(concurrency), drainTimeout: drainTimeout}
+}
+
+// Start runs tasks until ctx is cancelled, then waits for those in progress.
+// An aborted task goes back to the queue, as does a retry still in its
+// backoff.
+func (c *MemoryConsumer) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// The queue already hands out the highest lane first
+	for c.pool.Acquire(ctx, domain.LaneUpload) {
+		task, ok := c.queue.take(ctx)
+		if !ok {
+			c.pool.Release()
+			break
+		}
+
+		wg.Add(1)
+		if task.NotBefore != nil && time.Now().Before(*task.NotBefore) {
+			// A retry still in its backoff waits without a slot, and is
+			// queued again once due (or at shutdown)
+			c.pool.Release()
+			go func() {
+				defer wg.Done()
+				WaitUntil(ctx, *task.NotBefore)
+				if err := c.queue.Publish(context.WithoutCancel(ctx), task); err != nil {
+					slog.Error("queue: retry not put back", "video_id", task.VideoID, "error", err)
+				}
+			}()
+			continue
+		}
+		go func() {
+			defer wg.Done()
+			defer c.pool.Release()
+			c.run(ctx, task)
+		}()
+	}
+	return ctx.Err()
+}
+
+// run dispatches task in a slot of the pool.
+func (c *MemoryConsumer) run(ctx context.Context, task Task) {
+	taskCtx, cancel := WithDrain(ctx, c.drainTimeout)
+	defer cancel()
+	err := Dispatch(taskCtx, c.handler, task, c.queue.Publish, c.queue.deadLetter)
+	if taskCtx.Err() != nil {
+		if err := c.queue.Publish(context.Background(), task); err != nil {
+			slog.Error("queue: task aborted by shutdown and not put back", "video_id", task.VideoID, "error", err)
+		} else {
+			slog.Warn("queue: task aborted by shutdown, putting it back", "video_id", task.VideoID)
+		}
+		return
+	}
+	if err != nil {
+		slog.Error("queue: task not handed on", "video_id", task.VideoID, "error", err)
+	}
+}
+
+func (c *MemoryConsumer) Ready(context.Context) error { return nil }
+
+func (c *MemoryConsumer) Close() error { return nil }
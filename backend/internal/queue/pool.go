@@ -0,0 +1,130 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/metrics"
+)
+
+// Pool bounds how many tasks a consumer runs at once. While it is full,
+// waiters get the freed slots by lane priority, then in arrival order.
+type Pool struct {
+	size int
+
+	mu      sync.Mutex
+	used    int
+	waiters [][]chan struct{} // by lane priority
+}
+
+// NewPool returns a pool of size slots (at least one).
+func NewPool(size int) *Pool {
+	size = max(size, 1)
+	metrics.WorkerConcurrency.Set(float64(size))
+	return &Pool{size: size, waiters: make([][]chan struct{}, len(domain.TaskLanes))}
+}
+
+// Size is the number of slots.
+func (p *Pool) Size() int { return p.size }
+
+// Free is the number of slots not in use right now.
+func (p *Pool) Free() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.size - p.used
+}
+
+// TryAcquire takes a slot if one is free.
+func (p *Pool) TryAcquire() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.used < p.size {
+		p.used++
+		metrics.WorkerJobsInFlight.Inc()
+		return true
+	}
+	return false
+}
+
+// Acquire waits for a slot for a task of lane; it returns false if ctx is
+// done first.
+func (p *Pool) Acquire(ctx context.Context, lane domain.TaskLane) bool {
+	if p.TryAcquire() {
+		return true
+	}
+
+	p.mu.Lock()
+	if p.used < p.size {
+		// Freed in between
+		p.used++
+		p.mu.Unlock()
+		metrics.WorkerJobsInFlight.Inc()
+		return true
+	}
+	prio := lane.Priority()
+	ready := make(chan struct{})
+	p.waiters[prio] = append(p.waiters[prio], ready)
+	p.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case <-ready:
+		metrics.Since(metrics.WorkerSlotWait, start)
+		return true
+	case <-ctx.Done():
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, w := range p.waiters[prio] {
+		if w == ready {
+			p.waiters[prio] = append(p.waiters[prio][:i], p.waiters[prio][i+1:]...)
+			return false
+		}
+	}
+	// Handed a slot right as ctx ended: pass it on
+	p.release()
+	return false
+}
+
+// Release returns a slot taken with TryAcquire or Acquire.
+func (p *Pool) Release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.release()
+}
+
+// release hands the slot to the first waiter of the highest lane, if any.
+func (p *Pool) release() {
+	for prio, waiters := range p.waiters {
+		if len(waiters) > 0 {
+			close(waiters[0])
+			p.waiters[prio] = waiters[1:]
+			return
+		}
+	}
+	p.used--
+	metrics.WorkerJobsInFlight.Dec()
+}
+
+// WithDrain returns a context for one task that outlives session by up to
+// grace: a shutdown or rebalance lets the task finish, and aborts it (killing
+// ffmpeg) only when grace runs out.
+func WithDrain(session context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(session))
+	stop := context.AfterFunc(session, func() {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancel()
+		case <-ctx.Done():
+		}
+	})
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
@@ -0,0 +1,207 @@
+// Package queue defines how video processing tasks travel from the API to the
+// workers, independently of the broker. Kafka (internal/kafka) and Redis
+// Streams implement it for deployments; the in-memory implementation serves
+// tests and single-process tools.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/logging"
+	"github.com/Cloud-2025-2/anb-platform/internal/metrics"
+)
+
+// Backends accepted by config.QueueBackend.
+const (
+	BackendKafka  = "kafka"
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+)
+
+// Task asks a worker to process one video.
+type Task struct {
+	TaskID     string    `json:"task_id,omitempty"` // registro en processing_tasks
+	VideoID    string    `json:"video_id"`
+	UserID     string    `json:"user_id"`
+	Title      string    `json:"title"`
+	FilePath   string    `json:"file_path"`
+	Timestamp  time.Time `json:"timestamp"`
+	RetryCount int       `json:"retry_count"`
+	Profile    string    `json:"profile,omitempty"` // perfil de procesamiento; vacío = por defecto
+	// Carril de prioridad; vacío (tareas anteriores a los carriles) = upload
+	Lane domain.TaskLane `json:"lane,omitempty"`
+	// Un reintento no se procesa antes de este momento (su backoff)
+	NotBefore *time.Time `json:"not_before,omitempty"`
+}
+
+// DeadLetter is a task that exhausted its retries, or a message that was
+// rejected, as stored in the DLQ.
+type DeadLetter struct {
+	Task
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+	Raw      string    `json:"raw,omitempty"` // mensaje rechazado, tal como llegó
+}
+
+// TaskPublisher enqueues tasks. Implementations carry the request ID, task ID
+// and trace context of ctx along with the task.
+type TaskPublisher interface {
+	Publish(ctx context.Context, task Task) error
+	// Ping fails when the broker cannot be reached; used by /readyz.
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// TaskConsumer delivers tasks to a Handler until its context is cancelled,
+// then waits for (or aborts, past the drain timeout) the tasks in progress.
+// A task whose handler fails is retried with backoff up to MaxRetries times
+// and then dead-lettered; an aborted task is left for redelivery. A retry is
+// not started before its NotBefore, and waits for it without holding a slot
+// of the worker pool.
+type TaskConsumer interface {
+	Start(ctx context.Context) error
+	// Ready fails while the consumer cannot receive tasks (e.g. it is not
+	// a member of its consumer group); used by /readyz.
+	Ready(ctx context.Context) error
+	Close() error
+}
+
+// Handler processes one task. Returning an error retries it.
+type Handler func(ctx context.Context, task Task) error
+
+// MaxRetries is how many times a failed task is retried before it goes to the
+// dead letter queue.
+const MaxRetries = 3
+
+const baseBackoff = time.Second
+
+// Backoff is the delay before retry number retryCount: exponential from one
+// second, with ±20% jitter.
+func Backoff(retryCount int) time.Duration {
+	backoffMs := int(baseBackoff.Milliseconds()) * int(math.Pow(2, float64(retryCount)))
+
+	// Add some jitter (±20%)
+	jitter := int(float64(backoffMs) * 0.2)
+	backoffMs += (retryCount*137)%(2*jitter) - jitter // Simple pseudo-random jitter
+
+	return time.Duration(backoffMs) * time.Millisecond
+}
+
+// WaitUntil blocks until t, or until ctx is done, in which case it returns
+// false. A zero or past t returns true at once.
+func WaitUntil(ctx context.Context, t time.Time) bool {
+	d := time.Until(t)
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Receive decodes a message and dispatches its task. A message that can't be
+// decoded (malformed, or of a schema version this build doesn't know) never
+// will be: it goes straight to dlq, as received.
+func Receive(ctx context.Context, h Handler, body []byte,
+	retry func(context.Context, Task) error,
+	dlq func(context.Context, DeadLetter) error,
+) error {
+	env, task, err := DecodeTask(body)
+	if err != nil {
+		slog.ErrorContext(ctx, "queue: rejecting message, sending to DLQ", "error", err)
+		metrics.QueueMessagesDecoded.WithLabelValues(schemaVersionLabel(body), "rejected").Inc()
+		return dlq(ctx, DeadLetter{Error: err.Error(), FailedAt: time.Now(), Raw: string(body)})
+	}
+
+	version := strconv.Itoa(env.SchemaVersion)
+	if env.SchemaVersion < SchemaVersion {
+		slog.InfoContext(ctx, "queue: upgraded message", "from_version", env.SchemaVersion, "to_version", SchemaVersion)
+		metrics.QueueMessagesDecoded.WithLabelValues(version, "upgraded").Inc()
+	} else {
+		metrics.QueueMessagesDecoded.WithLabelValues(version, "ok").Inc()
+	}
+	ctx = logging.With(ctx,
+		slog.String("message_id", env.ID),
+		slog.String("producer_id", env.ProducerID),
+		slog.String("idempotency_key", env.IdempotencyKey),
+	)
+	return Dispatch(ctx, h, task, retry, dlq)
+}
+
+// schemaVersionLabel is the schema_version of a message for metrics, "1" if
+// it has none and "unknown" if it can't be read.
+func schemaVersionLabel(body []byte) string {
+	var probe struct {
+		SchemaVersion *int `json:"schema_version"`
+	}
+	switch {
+	case json.Unmarshal(body, &probe) != nil:
+		return "unknown"
+	case probe.SchemaVersion == nil:
+		return "1"
+	default:
+		return strconv.Itoa(*probe.SchemaVersion)
+	}
+}
+
+// Dispatch runs h on task with the retry policy shared by every backend: a
+// failure either sends the task back through retry with RetryCount+1 and a
+// NotBefore one Backoff away, or, after MaxRetries, to dlq. The consumers wait
+// for NotBefore before taking a slot for the retry, so it is not waited here.
+// It only
+// returns an error when the task could not be handed on, or when ctx was
+// cancelled (the task must then be redelivered, not retried).
+func Dispatch(ctx context.Context, h Handler, task Task,
+	retry func(context.Context, Task) error,
+	dlq func(context.Context, DeadLetter) error,
+) error {
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("video.id", task.VideoID),
+		attribute.Int("task.retry_count", task.RetryCount),
+	)
+	ctx = logging.With(ctx,
+		slog.String("video_id", task.VideoID),
+		slog.String("lane", string(task.Lane)),
+		slog.Int("attempt", task.RetryCount+1),
+	)
+	slog.InfoContext(ctx, "queue: processing video task")
+
+	err := h(ctx, task)
+	if err == nil {
+		slog.InfoContext(ctx, "queue: video task processed")
+		return nil
+	}
+	if ctx.Err() != nil {
+		// Aborted, not failed: it must not use up a retry
+		return err
+	}
+
+	slog.WarnContext(ctx, "queue: video processing failed", "error", err)
+	if task.RetryCount >= MaxRetries {
+		slog.ErrorContext(ctx, "queue: max retries exceeded, sending to DLQ", "max_retries", MaxRetries)
+		metrics.TaskDeadLetters.Inc()
+		return dlq(ctx, DeadLetter{Task: task, Error: err.Error(), FailedAt: time.Now()})
+	}
+
+	task.RetryCount++
+	backoff := Backoff(task.RetryCount)
+	notBefore := time.Now().Add(backoff)
+	task.NotBefore = &notBefore
+	slog.InfoContext(ctx, "queue: retrying video", "next_attempt", task.RetryCount+1, "max_retries", MaxRetries, "backoff", backoff.String())
+	metrics.TaskRetries.Inc()
+	return retry(ctx, task)
+}
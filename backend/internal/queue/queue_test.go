@@ -0,0 +1,227 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+)
+
+var errProcessing = errors.New("ffmpeg failed")
+
+func newTask() Task {
+	return Task{
+		VideoID:   "0b7c6f8e-3f0a-4d8e-9c1a-2b3c4d5e6f70",
+		FilePath:  "/storage/original.mp4",
+		Timestamp: time.Now(),
+		Lane:      domain.LaneUpload,
+	}
+}
+
+// dispatchNext pops the next task of m and dispatches it as a consumer would.
+func dispatchNext(t *testing.T, m *Memory, h Handler) Task {
+	t.Helper()
+	task, ok := m.pop()
+	if !ok {
+		t.Fatal("queue is empty")
+	}
+	if err := Dispatch(context.Background(), h, task, m.Publish, m.deadLetter); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	return task
+}
+
+func TestDispatchRetriesThenDeadLetters(t *testing.T) {
+	m := NewMemory(10)
+	if err := m.Publish(context.Background(), newTask()); err != nil {
+		t.Fatal(err)
+	}
+	fail := func(context.Context, Task) error { return errProcessing }
+
+	// The first attempt and MaxRetries retries, each one counted and pushed
+	// back by its backoff
+	for attempt := 0; attempt <= MaxRetries; attempt++ {
+		task := dispatchNext(t, m, fail)
+		if task.RetryCount != attempt {
+			t.Fatalf("attempt %d: RetryCount = %d", attempt, task.RetryCount)
+		}
+		if attempt < MaxRetries && m.Len() != 1 {
+			t.Fatalf("attempt %d: not sent to retry", attempt)
+		}
+		if attempt < MaxRetries && len(m.DeadLetters()) != 0 {
+			t.Fatalf("attempt %d: dead-lettered before using up its retries", attempt)
+		}
+	}
+
+	if m.Len() != 0 {
+		t.Errorf("%d tasks left after the last retry", m.Len())
+	}
+	dead := m.DeadLetters()
+	if len(dead) != 1 {
+		t.Fatalf("%d dead letters, want 1", len(dead))
+	}
+	if dead[0].RetryCount != MaxRetries || dead[0].Error != errProcessing.Error() || dead[0].FailedAt.IsZero() {
+		t.Errorf("dead letter = %+v", dead[0])
+	}
+}
+
+func TestDispatchSchedulesRetryAfterBackoff(t *testing.T) {
+	m := NewMemory(10)
+	fail := func(context.Context, Task) error { return errProcessing }
+
+	for retry := 1; retry <= MaxRetries; retry++ {
+		task := newTask()
+		task.RetryCount = retry - 1
+		before := time.Now()
+		if err := Dispatch(context.Background(), fail, task, m.Publish, m.deadLetter); err != nil {
+			t.Fatal(err)
+		}
+		next, ok := m.pop()
+		if !ok {
+			t.Fatalf("retry %d not published", retry)
+		}
+		if next.NotBefore == nil {
+			t.Fatalf("retry %d has no NotBefore", retry)
+		}
+		wait := next.NotBefore.Sub(before)
+		nominal := time.Second << retry
+		if wait < nominal*8/10 || wait > nominal*12/10+time.Second {
+			t.Errorf("retry %d scheduled %s after the failure, want about %s", retry, wait, nominal)
+		}
+	}
+}
+
+func TestBackoffGrowsExponentially(t *testing.T) {
+	for retry := 1; retry <= MaxRetries; retry++ {
+		nominal := time.Second << retry
+		if got := Backoff(retry); got < nominal*8/10 || got > nominal*12/10 {
+			t.Errorf("Backoff(%d) = %s, want %s ±20%%", retry, got, nominal)
+		}
+	}
+}
+
+func TestDispatchOutcomes(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    func(cancel context.CancelFunc) Handler
+		wantErr    bool
+		wantRetry  bool
+		wantLetter bool
+	}{
+		{
+			name:    "success",
+			handler: func(context.CancelFunc) Handler { return func(context.Context, Task) error { return nil } },
+		},
+		{
+			name:      "failure",
+			handler:   func(context.CancelFunc) Handler { return func(context.Context, Task) error { return errProcessing } },
+			wantRetry: true,
+		},
+		{
+			// Aborted by a shutdown: redelivered, not retried
+			name: "aborted",
+			handler: func(cancel context.CancelFunc) Handler {
+				return func(ctx context.Context, _ Task) error {
+					cancel()
+					return ctx.Err()
+				}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory(10)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			err := Dispatch(ctx, tt.handler(cancel), newTask(), m.Publish, m.deadLetter)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error: %v", err, tt.wantErr)
+			}
+			if got := m.Len() == 1; got != tt.wantRetry {
+				t.Errorf("retried = %v, want %v", got, tt.wantRetry)
+			}
+			if got := len(m.DeadLetters()) == 1; got != tt.wantLetter {
+				t.Errorf("dead-lettered = %v, want %v", got, tt.wantLetter)
+			}
+		})
+	}
+}
+
+func TestReceiveDeadLettersUndecodableMessages(t *testing.T) {
+	m := NewMemory(10)
+	called := false
+	h := func(context.Context, Task) error { called = true; return nil }
+
+	body := []byte(`{"schema_version":2,`)
+	if err := Receive(context.Background(), h, body, m.Publish, m.deadLetter); err != nil {
+		t.Fatal(err)
+	}
+	if called {
+		t.Error("handler ran on a message that could not be decoded")
+	}
+	dead := m.DeadLetters()
+	if len(dead) != 1 || dead[0].Raw != string(body) || dead[0].Error == "" {
+		t.Errorf("dead letters = %+v", dead)
+	}
+}
+
+// A retry waiting for its backoff must not keep the only slot from other
+// tasks.
+func TestMemoryConsumerRetryDoesNotHoldSlot(t *testing.T) {
+	m := NewMemory(10)
+	failing, other := newTask(), newTask()
+	failing.Title, other.Title = "failing", "other"
+
+	var mu sync.Mutex
+	var order []string
+	done := make(chan struct{})
+	h := func(_ context.Context, task Task) error {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, task.Title)
+		if task.Title == "failing" && task.RetryCount == 0 {
+			// Publish the other task only now, so it is queued behind the
+			// retry
+			if err := m.Publish(context.Background(), other); err != nil {
+				t.Error(err)
+			}
+			return errProcessing
+		}
+		if task.Title == "failing" {
+			close(done)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewMemoryConsumer(m, h, 1, time.Second)
+	stopped := make(chan error)
+	go func() { stopped <- c.Start(ctx) }()
+	if err := m.Publish(context.Background(), failing); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("retry never ran")
+	}
+	cancel()
+	<-stopped
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"failing", "other", "failing"}
+	if len(order) != len(want) {
+		t.Fatalf("ran %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("ran %v, want %v", order, want)
+		}
+	}
+}
@@ -0,0 +1,550 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/logging"
+	"github.com/Cloud-2025-2/anb-platform/internal/tracing"
+)
+
+// Streams of the redis backend, named after the Kafka topics. Each lane has
+// its own stream; the upload lane keeps the original one.
+const (
+	StreamVideoProcessing = "queue:video-processing"
+	StreamVideoReprocess  = "queue:video-processing-reprocess"
+	StreamVideoBulk       = "queue:video-processing-bulk"
+	StreamVideoRetry      = "queue:video-processing-retry"
+	StreamVideoDLQ        = "queue:video-processing-dlq"
+)
+
+// consumeOrder is the order in which a worker looks for its next task.
+// Retries come after the lanes that users are waiting on interactively.
+var consumeOrder = []string{StreamVideoProcessing, StreamVideoReprocess, StreamVideoRetry, StreamVideoBulk}
+
+func streamFor(lane domain.TaskLane) string {
+	switch lane {
+	case domain.LaneReprocess:
+		return StreamVideoReprocess
+	case domain.LaneBulk:
+		return StreamVideoBulk
+	default:
+		return StreamVideoProcessing
+	}
+}
+
+// Fields of a stream entry: the task as JSON, the IDs that correlate it with
+// the request that created it, and the trace context (traceparent, ...).
+const (
+	FieldTask      = "task"
+	FieldTaskID    = "task_id"
+	FieldRequestID = "request_id"
+	FieldLane      = "lane"
+	FieldNotBefore = "not_before"
+)
+
+// streamMaxLen caps each stream (approximately); acknowledged entries are
+// only kept for inspection.
+const streamMaxLen = 100000
+
+// maxDeliveries is how many times an entry may be delivered without being
+// acknowledged before it is dead-lettered. Such an entry kills or hangs the
+// workers that take it (e.g. out of memory) and never reaches Dispatch's
+// retry limit.
+const maxDeliveries = MaxRetries + 2
+
+// RedisPublisher publishes tasks to Redis Streams.
+type RedisPublisher struct {
+	client redis.UniversalClient
+}
+
+func NewRedisPublisher(client redis.UniversalClient) *RedisPublisher {
+	return &RedisPublisher{client: client}
+}
+
+// Publish appends task to the stream of its lane.
+func (p *RedisPublisher) Publish(ctx context.Context, task Task) error {
+	taskID := task.TaskID
+	if taskID == "" {
+		taskID = uuid.New().String()
+	}
+	body, err := EncodeTask(ctx, task)
+	if err != nil {
+		return err
+	}
+	_, err = p.add(ctx, streamFor(task.Lane), body, map[string]any{FieldTaskID: taskID, FieldLane: string(task.Lane)})
+	return err
+}
+
+// publishRetry appends a failed task to the retry stream; task already
+// carries the RetryCount and NotBefore of the next attempt.
+func (p *RedisPublisher) publishRetry(ctx context.Context, task Task) error {
+	body, err := EncodeTask(ctx, task)
+	if err != nil {
+		return err
+	}
+	fields := map[string]any{FieldLane: string(task.Lane)}
+	if task.NotBefore != nil {
+		fields[FieldNotBefore] = task.NotBefore.Format(time.RFC3339Nano)
+	}
+	_, err = p.add(ctx, StreamVideoRetry, body, fields)
+	return err
+}
+
+func (p *RedisPublisher) publishDLQ(ctx context.Context, dl DeadLetter) error {
+	body, err := EncodeDeadLetter(ctx, dl)
+	if err != nil {
+		return err
+	}
+	_, err = p.add(ctx, StreamVideoDLQ, body, nil)
+	return err
+}
+
+// add appends the encoded message body to stream under a producer span whose
+// context travels in the entry, together with the request and task IDs
+// attached to ctx.
+func (p *RedisPublisher) add(ctx context.Context, stream string, body []byte, fields map[string]any) (id string, err error) {
+	ctx, span := tracing.Tracer.Start(ctx, "redis.publish "+stream,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "redis"),
+			attribute.String("messaging.destination.name", stream),
+		))
+	defer func() { tracing.End(span, err) }()
+
+	values := map[string]any{FieldTask: body}
+	for k, v := range fields {
+		values[k] = v
+	}
+	for _, key := range []string{FieldRequestID, FieldTaskID} {
+		if _, ok := values[key]; !ok {
+			if v := logging.Value(ctx, key); v != "" {
+				values[key] = v
+			}
+		}
+	}
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	for k, v := range carrier {
+		values[k] = v
+	}
+
+	id, err = p.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: values,
+	}).Result()
+	if err != nil {
+		return "", err
+	}
+	slog.DebugContext(ctx, "queue: task added to stream", "stream", stream, "id", id)
+	return id, nil
+}
+
+// Ping fails when Redis does not answer.
+func (p *RedisPublisher) Ping(ctx context.Context) error {
+	return p.client.Ping(ctx).Err()
+}
+
+// Close is a no-op: the client is shared and closed by its owner.
+func (p *RedisPublisher) Close() error { return nil }
+
+// RedisConsumer reads the lane and retry streams as a member of a consumer
+// group, taking its next task from the first stream of consumeOrder that has
+// one. Entries are acknowledged once handled (or handed to retry or the DLQ);
+// entries of a worker that died stay pending and are claimed by another
+// member after claimIdle.
+type RedisConsumer struct {
+	client    redis.UniversalClient
+	publisher *RedisPublisher
+	group     string
+	name      string
+	handler   Handler
+	pool      *Pool
+	// drainTimeout is how long the tasks in progress may keep running once
+	// ctx is cancelled before they are aborted
+	drainTimeout time.Duration
+	// claimIdle is how long an entry may go without a heartbeat before
+	// another member claims it
+	claimIdle time.Duration
+
+	joined atomic.Bool
+}
+
+// NewRedisConsumer returns a consumer named name (unique per worker, e.g. the
+// hostname) of group that runs handler on up to concurrency tasks at once.
+func NewRedisConsumer(client redis.UniversalClient, group, name string, handler Handler, concurrency int, drainTimeout, claimIdle time.Duration) *RedisConsumer {
+	return &RedisConsumer{
+		client:       client,
+		publisher:    NewRedisPublisher(client),
+		group:        group,
+		name:         name,
+		handler:      handler,
+		pool:         NewPool(concurrency),
+		drainTimeout: drainTimeout,
+		claimIdle:    max(claimIdle, time.Second),
+	}
+}
+
+// Start joins the group and runs tasks until ctx is cancelled, then waits for
+// those in progress.
+func (c *RedisConsumer) Start(ctx context.Context) error {
+	for _, stream := range consumeOrder {
+		err := c.client.XGroupCreateMkStream(ctx, stream, c.group, "0").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("create consumer group %s on %s: %w", c.group, stream, err)
+		}
+	}
+	c.joined.Store(true)
+	defer c.joined.Store(false)
+	slog.Info("queue: joined redis consumer group", "group", c.group, "consumer", c.name)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.recover(ctx, &wg)
+	}()
+
+	// Entries are read only once a slot is free, in priority order
+	for c.pool.Acquire(ctx, domain.LaneUpload) {
+		msgs, err := c.read(ctx)
+		if err != nil {
+			c.pool.Release()
+			if ctx.Err() != nil {
+				break
+			}
+			slog.Error("queue: read from redis failed", "error", err)
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		if len(msgs) == 0 {
+			c.pool.Release()
+			continue
+		}
+		for i, m := range msgs {
+			if due := notBeforeOf(m.msg); time.Now().Before(due) {
+				// A retry still in its backoff waits without a slot
+				if i == 0 {
+					c.pool.Release()
+				}
+				c.runWhenDue(ctx, &wg, m.stream, m.msg, due)
+				continue
+			}
+			// The first entry runs in the slot already taken; an entry read
+			// alongside it from another stream waits for one
+			if i > 0 && !c.pool.Acquire(ctx, laneOf(m.msg)) {
+				// Left pending: claimed after claimIdle
+				break
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer c.pool.Release()
+				c.handle(ctx, m.stream, m.msg)
+			}()
+		}
+	}
+	return ctx.Err()
+}
+
+type streamMessage struct {
+	stream string
+	msg    redis.XMessage
+}
+
+// read returns the next entry of the first stream of consumeOrder that has
+// one. When all are empty it waits up to a couple of seconds for an entry on
+// any of them (one per stream at most).
+func (c *RedisConsumer) read(ctx context.Context) ([]streamMessage, error) {
+	for _, stream := range consumeOrder {
+		msgs, err := c.readGroup(ctx, -1, stream)
+		if err != nil || len(msgs) > 0 {
+			return msgs, err
+		}
+	}
+	return c.readGroup(ctx, 2*time.Second, consumeOrder...)
+}
+
+// readGroup reads one new entry of each stream; a negative block returns at
+// once.
+func (c *RedisConsumer) readGroup(ctx context.Context, block time.Duration, streams ...string) ([]streamMessage, error) {
+	args := append([]string(nil), streams...)
+	for range streams {
+		args = append(args, ">")
+	}
+	res, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    c.group,
+		Consumer: c.name,
+		Streams:  args,
+		Count:    1,
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var msgs []streamMessage
+	for _, s := range res {
+		for _, m := range s.Messages {
+			msgs = append(msgs, streamMessage{stream: s.Stream, msg: m})
+		}
+	}
+	return msgs, nil
+}
+
+func laneOf(msg redis.XMessage) domain.TaskLane {
+	lane, _ := msg.Values[FieldLane].(string)
+	return domain.TaskLane(lane)
+}
+
+// notBeforeOf is the time before which a retry must not run; zero for any
+// other entry.
+func notBeforeOf(msg redis.XMessage) time.Time {
+	v, _ := msg.Values[FieldNotBefore].(string)
+	t, _ := time.Parse(time.RFC3339Nano, v)
+	return t
+}
+
+// runWhenDue handles a retry once its backoff is over. It waits without a
+// slot of the pool, keeping the entry claimed meanwhile; if ctx ends first
+// the entry stays pending for another member.
+func (c *RedisConsumer) runWhenDue(ctx context.Context, wg *sync.WaitGroup, stream string, msg redis.XMessage, due time.Time) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		stopHeartbeat := c.heartbeat(ctx, stream, msg.ID)
+		ok := WaitUntil(ctx, due) && c.pool.Acquire(ctx, laneOf(msg))
+		stopHeartbeat()
+		if !ok {
+			return
+		}
+		defer c.pool.Release()
+		c.handle(ctx, stream, msg)
+	}()
+}
+
+// recover periodically claims the entries other members left pending for
+// longer than claimIdle, as many as there are free slots, and dead-letters
+// those delivered too many times.
+func (c *RedisConsumer) recover(ctx context.Context, wg *sync.WaitGroup) {
+	ticker := time.NewTicker(c.claimIdle / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, stream := range consumeOrder {
+			if err := c.claim(ctx, stream, wg); err != nil && ctx.Err() == nil {
+				slog.Warn("queue: claiming pending entries failed", "stream", stream, "error", err)
+			}
+		}
+	}
+}
+
+func (c *RedisConsumer) claim(ctx context.Context, stream string, wg *sync.WaitGroup) error {
+	free := c.pool.Free()
+	if free == 0 {
+		return nil
+	}
+	pending, err := c.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  c.group,
+		Idle:   c.claimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  int64(free),
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pending {
+		if p.RetryCount >= maxDeliveries {
+			c.deadLetterStuck(ctx, stream, p)
+			continue
+		}
+		if !c.pool.TryAcquire() {
+			return nil
+		}
+		msgs, err := c.client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   stream,
+			Group:    c.group,
+			Consumer: c.name,
+			MinIdle:  c.claimIdle,
+			Messages: []string{p.ID},
+		}).Result()
+		if err != nil || len(msgs) == 0 {
+			// Claimed by another member first, or trimmed from the stream
+			c.pool.Release()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		slog.Info("queue: claimed pending task", "stream", stream, "id", p.ID,
+			"previous_consumer", p.Consumer, "idle", p.Idle.String(), "deliveries", p.RetryCount)
+		if due := notBeforeOf(msgs[0]); time.Now().Before(due) {
+			c.pool.Release()
+			c.runWhenDue(ctx, wg, stream, msgs[0], due)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer c.pool.Release()
+			c.handle(ctx, stream, msgs[0])
+		}()
+	}
+	return nil
+}
+
+// deadLetterStuck moves an entry that was delivered maxDeliveries times
+// without completing to the DLQ.
+func (c *RedisConsumer) deadLetterStuck(ctx context.Context, stream string, p redis.XPendingExt) {
+	msgs, err := c.client.XRangeN(ctx, stream, p.ID, p.ID, 1).Result()
+	if err != nil {
+		slog.Warn("queue: reading stuck entry failed", "stream", stream, "id", p.ID, "error", err)
+		return
+	}
+	if len(msgs) > 0 {
+		dl := DeadLetter{Error: fmt.Sprintf("delivered %d times without completing", p.RetryCount), FailedAt: time.Now()}
+		body, _ := msgs[0].Values[FieldTask].(string)
+		if _, task, err := DecodeTask([]byte(body)); err == nil {
+			dl.Task = task
+		} else {
+			dl.Raw = body
+		}
+		slog.Error("queue: task stuck, sending to DLQ", "stream", stream, "id", p.ID, "video_id", dl.VideoID, "reason", dl.Error)
+		if err := c.publisher.publishDLQ(ctx, dl); err != nil {
+			slog.Warn("queue: sending stuck entry to DLQ failed", "stream", stream, "id", p.ID, "error", err)
+			return
+		}
+	}
+	if err := c.client.XAck(ctx, stream, c.group, p.ID).Err(); err != nil {
+		slog.Warn("queue: acknowledging stuck entry failed", "stream", stream, "id", p.ID, "error", err)
+	}
+}
+
+// handle runs one entry and acknowledges it unless it was aborted by the
+// shutdown, in which case it stays pending for another member to claim.
+func (c *RedisConsumer) handle(session context.Context, stream string, msg redis.XMessage) {
+	ctx, cancel := WithDrain(session, c.drainTimeout)
+	defer cancel()
+
+	stopHeartbeat := c.heartbeat(ctx, stream, msg.ID)
+	err := c.process(ctx, stream, msg)
+	stopHeartbeat()
+
+	if ctx.Err() != nil {
+		slog.Warn("queue: task aborted by shutdown, leaving it for another worker", "stream", stream, "id", msg.ID)
+		return
+	}
+	if err != nil {
+		// Not handed to retry or the DLQ: leave it pending so it is claimed
+		// and tried again
+		return
+	}
+	if err := c.client.XAck(context.WithoutCancel(ctx), stream, c.group, msg.ID).Err(); err != nil {
+		slog.Warn("queue: acknowledging task failed", "stream", stream, "id", msg.ID, "error", err)
+	}
+}
+
+// heartbeat re-claims the entry for this consumer while it runs, which resets
+// its idle time so no other member claims a long task.
+func (c *RedisConsumer) heartbeat(ctx context.Context, stream, id string) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(c.claimIdle / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			err := c.client.XClaimJustID(ctx, &redis.XClaimArgs{
+				Stream:   stream,
+				Group:    c.group,
+				Consumer: c.name,
+				Messages: []string{id},
+			}).Err()
+			if err != nil && ctx.Err() == nil {
+				slog.Warn("queue: task heartbeat failed", "stream", stream, "id", id, "error", err)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func (c *RedisConsumer) process(ctx context.Context, stream string, msg redis.XMessage) (err error) {
+	// Continue the trace started by the publisher of the task, and log with
+	// the request and task that produced it
+	carrier := propagation.MapCarrier{}
+	for k, v := range msg.Values {
+		if s, ok := v.(string); ok {
+			carrier[k] = s
+		}
+	}
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	ctx = logging.With(ctx, slog.String("stream", stream), slog.String("entry_id", msg.ID))
+	for _, key := range []string{FieldRequestID, FieldTaskID} {
+		if v := carrier.Get(key); v != "" {
+			ctx = logging.With(ctx, slog.String(key, v))
+		}
+	}
+	ctx, span := tracing.Tracer.Start(ctx, "redis.consume "+stream,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "redis"),
+			attribute.String("messaging.destination.name", stream),
+			attribute.String("messaging.message.id", msg.ID),
+		))
+	defer func() {
+		if err != nil {
+			slog.ErrorContext(ctx, "queue: entry not processed", "error", err)
+		}
+		tracing.End(span, err)
+	}()
+
+	body, _ := msg.Values[FieldTask].(string)
+	return Receive(ctx, c.handler, []byte(body), c.publisher.publishRetry, c.publisher.publishDLQ)
+}
+
+// Ready fails before the consumer joined its group or when Redis does not
+// answer.
+func (c *RedisConsumer) Ready(ctx context.Context) error {
+	if !c.joined.Load() {
+		return errors.New("not a member of consumer group " + c.group)
+	}
+	return c.client.Ping(ctx).Err()
+}
+
+// Close is a no-op: the client is shared and closed by its owner.
+func (c *RedisConsumer) Close() error { return nil }
@@ -0,0 +1,199 @@
+package ranking
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/cache"
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/events"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+)
+
+// Service serves player rankings from the Redis leaderboard and keeps it in
+// sync with the votes stored in Postgres.
+type Service struct {
+	votes       repo.VoteRepository
+	users       repo.UserRepository
+	snapshots   repo.SnapshotRepository
+	tournaments repo.TournamentRepository
+	board       *cache.Leaderboard
+	cache       *cache.RankingsCache
+	events      *events.Publisher
+}
+
+func NewService(votes repo.VoteRepository, users repo.UserRepository, snapshots repo.SnapshotRepository, tournaments repo.TournamentRepository, board *cache.Leaderboard, cache *cache.RankingsCache, events *events.Publisher) *Service {
+	return &Service{votes: votes, users: users, snapshots: snapshots, tournaments: tournaments, board: board, cache: cache, events: events}
+}
+
+// RecordVote applies delta votes to the owner of the video and notifies live
+// clients. The video must have its User preloaded. Errors only mean the
+// leaderboard is temporarily behind Postgres; the reconciler catches it up.
+func (s *Service) RecordVote(ctx context.Context, video *domain.Video, delta int64) {
+	total, err := s.board.Incr(ctx, entryOf(video), delta)
+	if err != nil {
+		slog.WarnContext(ctx, "leaderboard: failed to record vote", "video_id", video.ID, "error", err)
+		return
+	}
+	s.events.RankingUpdated(ctx, events.RankingUpdate{
+		UserID:  video.UserID,
+		VideoID: video.ID,
+		City:    video.User.City,
+		Votes:   total,
+	})
+}
+
+// RefreshVideo rewrites the title and owner details of a voted video on the
+// leaderboard without changing its votes. The video must have its User preloaded.
+func (s *Service) RefreshVideo(ctx context.Context, video *domain.Video) {
+	if _, err := s.board.Incr(ctx, entryOf(video), 0); err != nil {
+		slog.WarnContext(ctx, "leaderboard: failed to refresh video", "video_id", video.ID, "error", err)
+	}
+	if err := s.cache.InvalidateAll(ctx); err != nil {
+		slog.WarnContext(ctx, "rankings cache: failed to invalidate", "error", err)
+	}
+}
+
+// RemoveUser runs remove, which deletes the user from Postgres, and then drops
+// the user from the leaderboard and takes back the votes they had cast.
+func (s *Service) RemoveUser(ctx context.Context, userID uuid.UUID, remove func() error) error {
+	cast, err := s.votes.TallyCastBy(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := remove(); err != nil {
+		return err
+	}
+
+	if err := s.board.Remove(ctx, userID); err != nil {
+		slog.WarnContext(ctx, "leaderboard: failed to remove user", "user_id", userID, "error", err)
+	}
+	for _, t := range cast {
+		if t.UserID == userID {
+			continue
+		}
+		if _, err := s.board.Incr(ctx, tallyEntry(t), -t.Votes); err != nil {
+			slog.WarnContext(ctx, "leaderboard: failed to take back votes", "user_id", userID, "error", err)
+		}
+	}
+	return nil
+}
+
+// Top returns one page of the ranking, annotated with the positions of the
+// latest snapshot when it covers all time.
+func (s *Service) Top(ctx context.Context, q repo.RankingQuery) ([]repo.RankingRow, error) {
+	rows, err := s.top(ctx, q)
+	if err != nil || q.Windowed() {
+		return rows, err
+	}
+
+	ids := make([]uuid.UUID, len(rows))
+	for i, r := range rows {
+		ids[i] = r.ID()
+	}
+	prev, err := s.snapshots.PreviousPositions(ctx, q, ids)
+	if err != nil {
+		slog.WarnContext(ctx, "rankings: failed to load previous positions", "error", err)
+		return rows, nil
+	}
+	for i := range rows {
+		if p, ok := prev[rows[i].ID()]; ok {
+			rows[i].PreviousPosition = &p
+		}
+	}
+	return rows, nil
+}
+
+// top serves all-time rankings from the leaderboard. Time windows, and
+// all-time rankings while the leaderboard has not been built yet, come from
+// Postgres and are cached for a short TTL.
+func (s *Service) top(ctx context.Context, q repo.RankingQuery) ([]repo.RankingRow, error) {
+	if !q.Windowed() && s.board.Ready(ctx) {
+		rows, err := s.board.Top(ctx, q)
+		if err == nil {
+			return rows, nil
+		}
+		slog.WarnContext(ctx, "leaderboard: read failed, falling back to database", "error", err)
+	}
+
+	if cached, found := s.cache.GetRankings(ctx, q); found {
+		return cached, nil
+	}
+
+	rows, err := s.votes.TopByCity(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.cache.SetRankings(ctx, q, rows)
+	return rows, nil
+}
+
+// Rebuild recomputes every board from Postgres. Votes recorded while it runs
+// are journaled by the leaderboard and replayed on top of the tallies.
+func (s *Service) Rebuild(ctx context.Context) error {
+	if err := s.board.BeginRebuild(ctx); err != nil {
+		return err
+	}
+	tallies, err := s.votes.TallyByVideo(ctx)
+	if err != nil {
+		return err
+	}
+	entries := make([]cache.LeaderboardEntry, 0, len(tallies))
+	for _, t := range tallies {
+		entries = append(entries, cache.LeaderboardEntry{Video: tallyEntry(t), Votes: t.Votes})
+	}
+	if err := s.board.Rebuild(ctx, entries); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "leaderboard: rebuilt", "videos", len(entries))
+	return s.cache.InvalidateAll(ctx)
+}
+
+// StartReconciler rebuilds the leaderboard right away and then every interval
+// until ctx is cancelled, correcting any drift from failed incremental updates.
+// A Redis lock ensures only one API replica reconciles per interval.
+func (s *Service) StartReconciler(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if s.board.TryLock(ctx, "leaderboard:reconcile", interval-interval/10) {
+				if err := s.Rebuild(ctx); err != nil {
+					slog.ErrorContext(ctx, "leaderboard: reconciliation failed", "error", err)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func entryOf(v *domain.Video) cache.LeaderboardVideo {
+	return cache.LeaderboardVideo{
+		VideoID:      v.ID,
+		Title:        v.Title,
+		TournamentID: v.TournamentID,
+		UserID:       v.UserID,
+		Username:     v.User.FirstName + " " + v.User.LastName,
+		City:         v.User.City,
+		Country:      v.User.Country,
+	}
+}
+
+func tallyEntry(t repo.VideoTally) cache.LeaderboardVideo {
+	return cache.LeaderboardVideo{
+		VideoID:      t.VideoID,
+		Title:        t.Title,
+		TournamentID: t.TournamentID,
+		UserID:       t.UserID,
+		Username:     t.Username,
+		City:         t.City,
+		Country:      t.Country,
+	}
+}
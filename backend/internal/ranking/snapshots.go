@@ -0,0 +1,114 @@
+package ranking
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+)
+
+// snapshotMaxRows bounds how many rows of each board are persisted per snapshot.
+const snapshotMaxRows = 1000
+
+// Snapshot persists the current rankings, for both scopes, globally and per
+// city and country, across the platform and for every tournament. A final
+// snapshot freezes the standings when voting closes.
+func (s *Service) Snapshot(ctx context.Context, final bool) (*domain.RankingSnapshot, error) {
+	cities, err := s.users.GetDistinctCities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	countries, err := s.users.GetDistinctCountries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tournaments, err := s.tournaments.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tournamentIDs := []*uuid.UUID{nil}
+	for i := range tournaments {
+		tournamentIDs = append(tournamentIDs, &tournaments[i].ID)
+	}
+
+	var boards []repo.RankingQuery
+	for _, tid := range tournamentIDs {
+		for _, scope := range []repo.RankingScope{repo.RankingByPlayer, repo.RankingByVideo} {
+			boards = append(boards, repo.RankingQuery{Scope: scope, TournamentID: tid})
+			for _, city := range cities {
+				boards = append(boards, repo.RankingQuery{Scope: scope, TournamentID: tid, City: city})
+			}
+			for _, country := range countries {
+				boards = append(boards, repo.RankingQuery{Scope: scope, TournamentID: tid, Country: country})
+			}
+		}
+	}
+
+	snap := &domain.RankingSnapshot{TakenAt: time.Now().UTC(), Final: final}
+	for _, q := range boards {
+		q.Limit = snapshotMaxRows
+		rows, err := s.votes.TopByCity(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			snap.Entries = append(snap.Entries, domain.RankingSnapshotEntry{
+				Scope:             string(q.Scope),
+				BoardTournamentID: q.TournamentID,
+				BoardCity:         q.City,
+				BoardCountry:      q.Country,
+				EntryID:           r.ID(),
+				UserID:            r.UserID,
+				VideoID:           r.VideoID,
+				Username:          r.Username,
+				City:              r.City,
+				Country:           r.Country,
+				Title:             r.Title,
+				Position:          r.Position,
+				Votes:             r.Votes,
+			})
+		}
+	}
+
+	if err := s.snapshots.Create(ctx, snap); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "rankings: snapshot taken", "snapshot_id", snap.ID, "entries", len(snap.Entries), "final", final)
+	return snap, nil
+}
+
+// History returns how the position of one player or video evolved across snapshots.
+func (s *Service) History(ctx context.Context, q repo.RankingQuery, entryID uuid.UUID, from, to *time.Time) ([]repo.HistoryPoint, error) {
+	return s.snapshots.History(ctx, q, entryID, from, to)
+}
+
+// Final returns the standings frozen by the latest final snapshot.
+func (s *Service) Final(ctx context.Context, q repo.RankingQuery) (*domain.RankingSnapshot, []repo.RankingRow, error) {
+	return s.snapshots.LatestFinal(ctx, q)
+}
+
+// StartSnapshots takes a snapshot every interval until ctx is cancelled.
+// A Redis lock ensures only one API replica takes each snapshot.
+func (s *Service) StartSnapshots(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if s.board.TryLock(ctx, "rankings:snapshot", interval-interval/10) {
+				if _, err := s.Snapshot(ctx, false); err != nil {
+					slog.ErrorContext(ctx, "rankings: snapshot failed", "error", err)
+				}
+			}
+		}
+	}()
+}
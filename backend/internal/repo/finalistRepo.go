@@ -0,0 +1,127 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrSelectionFrozen = errors.New("finalist selection is frozen")
+
+type FinalistRepository interface {
+	Create(ctx context.Context, s *domain.FinalistSelection) error
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.FinalistSelection, error)
+	Latest(ctx context.Context, tournamentID *uuid.UUID, status domain.SelectionStatus) (*domain.FinalistSelection, error)
+	ReplaceComputed(ctx context.Context, id uuid.UUID, slots int, decisions []domain.FinalistDecision) error
+	SaveDecision(ctx context.Context, d *domain.FinalistDecision) error
+	Freeze(ctx context.Context, id, by uuid.UUID) error
+}
+
+type finalistRepo struct{ db *gorm.DB }
+
+func NewFinalistRepo(db *gorm.DB) FinalistRepository { return &finalistRepo{db} }
+
+func (r *finalistRepo) Create(ctx context.Context, s *domain.FinalistSelection) error {
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+func (r *finalistRepo) FindByID(ctx context.Context, id uuid.UUID) (*domain.FinalistSelection, error) {
+	var s domain.FinalistSelection
+	if err := r.withDecisions(ctx).First(&s, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Latest returns the most recent selection of the tournament (nil = platform
+// wide) in the given status, or in any status if status is "".
+func (r *finalistRepo) Latest(ctx context.Context, tournamentID *uuid.UUID, status domain.SelectionStatus) (*domain.FinalistSelection, error) {
+	tx := r.withDecisions(ctx)
+	if tournamentID != nil {
+		tx = tx.Where("tournament_id = ?", *tournamentID)
+	} else {
+		tx = tx.Where("tournament_id IS NULL")
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	var s domain.FinalistSelection
+	if err := tx.Order("created_at DESC").First(&s).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ReplaceComputed swaps the decisions computed from the ranking for new ones.
+// Jury decisions are kept and take precedence over computed ones for the same
+// player.
+func (r *finalistRepo) ReplaceComputed(ctx context.Context, id uuid.UUID, slots int, decisions []domain.FinalistDecision) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var s domain.FinalistSelection
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&s, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if s.Status == domain.SelectionFrozen {
+			return ErrSelectionFrozen
+		}
+		if err := tx.Where("selection_id = ? AND source = ?", id, domain.DecisionFromRanking).
+			Delete(&domain.FinalistDecision{}).Error; err != nil {
+			return err
+		}
+		if len(decisions) > 0 {
+			for i := range decisions {
+				decisions[i].SelectionID = id
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&decisions).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&s).Updates(map[string]interface{}{"slots_per_city": slots, "computed_at": time.Now().UTC()}).Error
+	})
+}
+
+// SaveDecision inserts or replaces the decision for d.UserID, unless the
+// selection is frozen.
+func (r *finalistRepo) SaveDecision(ctx context.Context, d *domain.FinalistDecision) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var s domain.FinalistSelection
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&s, "id = ?", d.SelectionID).Error; err != nil {
+			return err
+		}
+		if s.Status == domain.SelectionFrozen {
+			return ErrSelectionFrozen
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "selection_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"decision", "source", "reason", "decided_by", "decided_at"}),
+		}).Create(d).Error
+	})
+}
+
+// Freeze publishes the selection. Frozen selections cannot be frozen again.
+func (r *finalistRepo) Freeze(ctx context.Context, id, by uuid.UUID) error {
+	res := r.db.WithContext(ctx).Model(&domain.FinalistSelection{}).
+		Where("id = ? AND status = ?", id, domain.SelectionDraft).
+		Updates(map[string]interface{}{"status": domain.SelectionFrozen, "frozen_at": time.Now().UTC(), "frozen_by": by})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		if _, err := r.FindByID(ctx, id); err != nil {
+			return err
+		}
+		return ErrSelectionFrozen
+	}
+	return nil
+}
+
+func (r *finalistRepo) withDecisions(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Preload("Decisions", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("city ASC, CASE WHEN position = 0 THEN 1 ELSE 0 END, position ASC, votes DESC, user_id DESC")
+	})
+}
@@ -0,0 +1,116 @@
+package repo
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RankingScope selects what is ranked: players (sum of the votes of all
+// their videos) or individual videos.
+type RankingScope string
+
+const (
+	RankingByPlayer RankingScope = "player"
+	RankingByVideo  RankingScope = "video"
+)
+
+// RankingQuery filters and paginates a ranking. Rows are ordered by votes
+// descending and, for ties, by ID descending so the order is stable across
+// requests. Tied rows share the same position (RANK semantics).
+type RankingQuery struct {
+	Scope        RankingScope
+	TournamentID *uuid.UUID // only videos submitted to this tournament
+	City         string
+	Country      string
+	From         *time.Time // only votes cast at or after From
+	To           *time.Time // only votes cast before To
+	Limit        int
+	After        *RankingCursor // keyset: only rows strictly after this one
+}
+
+// Windowed reports whether q only counts votes from a time window, which the
+// all-time Redis leaderboard cannot answer.
+func (q RankingQuery) Windowed() bool {
+	return q.From != nil || q.To != nil
+}
+
+type RankingRow struct {
+	Position int         `json:"position"`
+	UserID   uuid.UUID   `json:"user_id"`
+	Username string      `json:"username"`
+	City     string      `json:"city"`
+	Country  string      `json:"country"`
+	VideoID  *uuid.UUID  `json:"video_id,omitempty"` // only with scope=video
+	Title    string      `json:"title,omitempty"`    // only with scope=video
+	VideoIDs []uuid.UUID `json:"video_ids"`
+	Votes    int64       `json:"votes"`
+
+	// Position in the latest ranking snapshot, to show movement arrows
+	PreviousPosition *int `json:"previous_position,omitempty"`
+}
+
+// ID is the identifier the row is ranked by: the video with scope=video,
+// the player otherwise.
+func (r RankingRow) ID() uuid.UUID {
+	if r.VideoID != nil {
+		return *r.VideoID
+	}
+	return r.UserID
+}
+
+// RankingCursor marks the last row of a page.
+type RankingCursor struct {
+	Votes int64
+	ID    uuid.UUID
+}
+
+var ErrInvalidCursor = errors.New("invalid ranking cursor")
+
+// CursorAfter returns the cursor pointing right after row.
+func CursorAfter(row RankingRow) *RankingCursor {
+	return &RankingCursor{Votes: row.Votes, ID: row.ID()}
+}
+
+// Encode returns the opaque string handed to clients.
+func (c RankingCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", c.Votes, c.ID)))
+}
+
+func DecodeRankingCursor(s string) (*RankingCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	votes, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(votes, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &RankingCursor{Votes: n, ID: uid}, nil
+}
+
+// VideoTally is the number of votes received by one video together with its
+// owner, used to (re)build the Redis leaderboard.
+type VideoTally struct {
+	VideoID      uuid.UUID
+	Title        string
+	TournamentID *uuid.UUID
+	UserID       uuid.UUID
+	Username     string
+	City         string
+	Country      string
+	Votes        int64
+}
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrDuplicateReport = errors.New("user has already reported this video")
+
+type ReportRepository interface {
+	Create(ctx context.Context, r *domain.VideoReport) error
+	CountOpen(ctx context.Context, videoID uuid.UUID) (int64, error)
+	CountOpenByVideo(ctx context.Context, videoIDs []uuid.UUID) (map[uuid.UUID]int64, error)
+	ListOpen(ctx context.Context, limit, offset int) ([]domain.VideoReport, error)
+	Resolve(ctx context.Context, videoID uuid.UUID, status domain.ReportStatus, by uuid.UUID) error
+}
+
+type reportRepo struct{ db *gorm.DB }
+
+func NewReportRepo(db *gorm.DB) ReportRepository { return &reportRepo{db} }
+
+func (r *reportRepo) Create(ctx context.Context, rep *domain.VideoReport) error {
+	err := r.db.WithContext(ctx).Create(rep).Error
+	if err != nil && (strings.Contains(err.Error(), "duplicate key") ||
+		strings.Contains(err.Error(), "idx_report_video_reporter")) {
+		return ErrDuplicateReport
+	}
+	return err
+}
+
+func (r *reportRepo) CountOpen(ctx context.Context, videoID uuid.UUID) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&domain.VideoReport{}).
+		Where("video_id = ? AND status = ?", videoID, domain.ReportOpen).
+		Count(&n).Error
+	return n, err
+}
+
+// CountOpenByVideo returns the number of open reports of each of videoIDs.
+func (r *reportRepo) CountOpenByVideo(ctx context.Context, videoIDs []uuid.UUID) (map[uuid.UUID]int64, error) {
+	out := make(map[uuid.UUID]int64, len(videoIDs))
+	if len(videoIDs) == 0 {
+		return out, nil
+	}
+	var rows []struct {
+		VideoID uuid.UUID
+		N       int64
+	}
+	err := r.db.WithContext(ctx).Model(&domain.VideoReport{}).
+		Select("video_id, COUNT(*) as n").
+		Where("video_id IN ? AND status = ?", videoIDs, domain.ReportOpen).
+		Group("video_id").
+		Scan(&rows).Error
+	for _, row := range rows {
+		out[row.VideoID] = row.N
+	}
+	return out, err
+}
+
+// ListOpen returns open reports, oldest first.
+func (r *reportRepo) ListOpen(ctx context.Context, limit, offset int) ([]domain.VideoReport, error) {
+	var out []domain.VideoReport
+	err := r.db.WithContext(ctx).Preload("Video").
+		Where("status = ?", domain.ReportOpen).
+		Order("created_at ASC").
+		Limit(limit).Offset(offset).
+		Find(&out).Error
+	return out, err
+}
+
+// Resolve closes every open report of the video.
+func (r *reportRepo) Resolve(ctx context.Context, videoID uuid.UUID, status domain.ReportStatus, by uuid.UUID) error {
+	return r.db.WithContext(ctx).Model(&domain.VideoReport{}).
+		Where("video_id = ? AND status = ?", videoID, domain.ReportOpen).
+		Updates(map[string]interface{}{"status": status, "resolved_by": by, "resolved_at": time.Now().UTC()}).Error
+}
@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// HistoryPoint is the position of one player or video in one snapshot.
+type HistoryPoint struct {
+	TakenAt  time.Time `json:"taken_at"`
+	Position int       `json:"position"`
+	Votes    int64     `json:"votes"`
+}
+
+type SnapshotRepository interface {
+	Create(ctx context.Context, s *domain.RankingSnapshot) error
+	PreviousPositions(ctx context.Context, q RankingQuery, ids []uuid.UUID) (map[uuid.UUID]int, error)
+	History(ctx context.Context, q RankingQuery, entryID uuid.UUID, from, to *time.Time) ([]HistoryPoint, error)
+	LatestFinal(ctx context.Context, q RankingQuery) (*domain.RankingSnapshot, []RankingRow, error)
+}
+
+type snapshotRepo struct{ db *gorm.DB }
+
+func NewSnapshotRepo(db *gorm.DB) SnapshotRepository { return &snapshotRepo{db} }
+
+func (r *snapshotRepo) Create(ctx context.Context, s *domain.RankingSnapshot) error {
+	return r.db.WithContext(ctx).Session(&gorm.Session{CreateBatchSize: 500}).Create(s).Error
+}
+
+// PreviousPositions returns the positions the given entries had in the most
+// recent snapshot of the board described by q.
+func (r *snapshotRepo) PreviousPositions(ctx context.Context, q RankingQuery, ids []uuid.UUID) (map[uuid.UUID]int, error) {
+	out := make(map[uuid.UUID]int, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+	var entries []domain.RankingSnapshotEntry
+	err := r.boardEntries(ctx, q).
+		Where("e.snapshot_id = (?)", r.db.WithContext(ctx).Model(&domain.RankingSnapshot{}).Select("id").Order("taken_at DESC").Limit(1)).
+		Where("e.entry_id IN ?", ids).
+		Find(&entries).Error
+	for _, e := range entries {
+		out[e.EntryID] = e.Position
+	}
+	return out, err
+}
+
+// History returns the positions of one entry across snapshots, oldest first.
+func (r *snapshotRepo) History(ctx context.Context, q RankingQuery, entryID uuid.UUID, from, to *time.Time) ([]HistoryPoint, error) {
+	var out []HistoryPoint
+	tx := r.boardEntries(ctx, q).
+		Select("s.taken_at, e.position, e.votes").
+		Joins("JOIN ranking_snapshots s ON s.id = e.snapshot_id").
+		Where("e.entry_id = ?", entryID).
+		Order("s.taken_at ASC")
+	if from != nil {
+		tx = tx.Where("s.taken_at >= ?", *from)
+	}
+	if to != nil {
+		tx = tx.Where("s.taken_at < ?", *to)
+	}
+	return out, tx.Scan(&out).Error
+}
+
+// LatestFinal returns the frozen standings of the board described by q, or
+// gorm.ErrRecordNotFound if no final snapshot has been taken yet.
+func (r *snapshotRepo) LatestFinal(ctx context.Context, q RankingQuery) (*domain.RankingSnapshot, []RankingRow, error) {
+	var s domain.RankingSnapshot
+	if err := r.db.WithContext(ctx).Where("final = ?", true).Order("taken_at DESC").First(&s).Error; err != nil {
+		return nil, nil, err
+	}
+	var entries []domain.RankingSnapshotEntry
+	if err := r.boardEntries(ctx, q).Where("e.snapshot_id = ?", s.ID).Order("e.position ASC, e.entry_id DESC").Find(&entries).Error; err != nil {
+		return nil, nil, err
+	}
+	rows := make([]RankingRow, 0, len(entries))
+	for _, e := range entries {
+		row := RankingRow{
+			Position: e.Position,
+			UserID:   e.UserID,
+			Username: e.Username,
+			City:     e.City,
+			Country:  e.Country,
+			VideoID:  e.VideoID,
+			Title:    e.Title,
+			VideoIDs: []uuid.UUID{},
+			Votes:    e.Votes,
+		}
+		if e.VideoID != nil {
+			row.VideoIDs = []uuid.UUID{*e.VideoID}
+		}
+		rows = append(rows, row)
+	}
+	return &s, rows, nil
+}
+
+func (r *snapshotRepo) boardEntries(ctx context.Context, q RankingQuery) *gorm.DB {
+	scope := q.Scope
+	if scope == "" {
+		scope = RankingByPlayer
+	}
+	tx := r.db.WithContext(ctx).Table("ranking_snapshot_entries e").
+		Where("e.scope = ? AND e.board_city = ? AND e.board_country = ?", scope, q.City, q.Country)
+	if q.TournamentID != nil {
+		return tx.Where("e.board_tournament_id = ?", *q.TournamentID)
+	}
+	return tx.Where("e.board_tournament_id IS NULL")
+}
@@ -0,0 +1,131 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TaskRepository interface {
+	Create(ctx context.Context, t *domain.ProcessingTask) error
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.ProcessingTask, error)
+	FindByIDForUser(ctx context.Context, id, userID uuid.UUID) (*domain.ProcessingTask, error)
+	LatestByVideo(ctx context.Context, videoID uuid.UUID) (*domain.ProcessingTask, error)
+	Start(ctx context.Context, id uuid.UUID, workerID string) error
+	UpdateProgress(ctx context.Context, id uuid.UUID, step string, percent int) error
+	Finish(ctx context.Context, id uuid.UUID, status domain.TaskStatus, lastError *string) error
+	CountPendingByUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	EstimateQueuePosition(ctx context.Context, t *domain.ProcessingTask) (int64, error)
+}
+
+type taskRepo struct{ db *gorm.DB }
+
+func NewTaskRepo(db *gorm.DB) TaskRepository { return &taskRepo{db} }
+
+func (r *taskRepo) Create(ctx context.Context, t *domain.ProcessingTask) error {
+	return r.db.WithContext(ctx).Omit("Video").Create(t).Error
+}
+
+func (r *taskRepo) FindByID(ctx context.Context, id uuid.UUID) (*domain.ProcessingTask, error) {
+	var t domain.ProcessingTask
+	if err := r.db.WithContext(ctx).First(&t, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// FindByIDForUser returns the task only if its video belongs to userID.
+func (r *taskRepo) FindByIDForUser(ctx context.Context, id, userID uuid.UUID) (*domain.ProcessingTask, error) {
+	var t domain.ProcessingTask
+	err := r.db.WithContext(ctx).
+		Joins("JOIN videos ON videos.id = processing_tasks.video_id").
+		Where("processing_tasks.id = ? AND videos.user_id = ?", id, userID).
+		First(&t).Error
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// LatestByVideo returns the most recently enqueued task of the video.
+func (r *taskRepo) LatestByVideo(ctx context.Context, videoID uuid.UUID) (*domain.ProcessingTask, error) {
+	var t domain.ProcessingTask
+	err := r.db.WithContext(ctx).
+		Where("video_id = ?", videoID).
+		Order("enqueued_at DESC").
+		First(&t).Error
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Start records a new attempt of the task by workerID, resetting its progress.
+func (r *taskRepo) Start(ctx context.Context, id uuid.UUID, workerID string) error {
+	return r.db.WithContext(ctx).Model(&domain.ProcessingTask{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status":      domain.TaskRunning,
+			"attempts":    gorm.Expr("attempts + 1"),
+			"started_at":  time.Now(),
+			"finished_at": nil,
+			"worker_id":   workerID,
+			"step":        "",
+			"progress":    0,
+		}).Error
+}
+
+func (r *taskRepo) UpdateProgress(ctx context.Context, id uuid.UUID, step string, percent int) error {
+	return r.db.WithContext(ctx).Model(&domain.ProcessingTask{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"step": step, "progress": percent}).Error
+}
+
+// Finish records the outcome of the current attempt.
+func (r *taskRepo) Finish(ctx context.Context, id uuid.UUID, status domain.TaskStatus, lastError *string) error {
+	updates := map[string]any{
+		"status":      status,
+		"finished_at": time.Now(),
+		"last_error":  lastError,
+	}
+	if status == domain.TaskSucceeded {
+		updates["progress"] = 100
+	}
+	return r.db.WithContext(ctx).Model(&domain.ProcessingTask{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+// CountPendingByUser counts the tasks of the user's videos that are queued,
+// running or waiting for a retry.
+func (r *taskRepo) CountPendingByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&domain.ProcessingTask{}).
+		Joins("JOIN videos ON videos.id = processing_tasks.video_id").
+		Where("videos.user_id = ? AND processing_tasks.status IN ?", userID,
+			[]domain.TaskStatus{domain.TaskQueued, domain.TaskRunning, domain.TaskRetrying}).
+		Count(&n).Error
+	return n, err
+}
+
+// EstimateQueuePosition returns an estimate of the 1-based position of a
+// queued task: one plus the queued tasks of the lanes ahead of its own and
+// those enqueued before it in its lane. It assumes each lane runs in FIFO
+// order, which the backends only approximate: Kafka partitions and Redis
+// consumers run in parallel and retries wait out their backoff, so the
+// actual order may differ.
+func (r *taskRepo) EstimateQueuePosition(ctx context.Context, t *domain.ProcessingTask) (int64, error) {
+	ahead := r.db.Where("lane = ? AND enqueued_at < ?", t.Lane, t.EnqueuedAt)
+	if lanes := t.Lane.Ahead(); len(lanes) > 0 {
+		ahead = ahead.Or("lane IN ?", lanes)
+	}
+	var n int64
+	err := r.db.WithContext(ctx).Model(&domain.ProcessingTask{}).
+		Where("status = ? AND id <> ?", domain.TaskQueued, t.ID).
+		Where(ahead).
+		Count(&n).Error
+	return n + 1, err
+}
@@ -0,0 +1,58 @@
+package repo
+
+import (
+	"context"
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TournamentRepository interface {
+	Create(ctx context.Context, t *domain.Tournament) error
+	Update(ctx context.Context, t *domain.Tournament) error
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.Tournament, error)
+	List(ctx context.Context) ([]domain.Tournament, error)
+	CountEntries(ctx context.Context, tournamentID, userID uuid.UUID) (int64, error)
+}
+
+type tournamentRepo struct{ db *gorm.DB }
+
+func NewTournamentRepo(db *gorm.DB) TournamentRepository { return &tournamentRepo{db} }
+
+func (r *tournamentRepo) Create(ctx context.Context, t *domain.Tournament) error {
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+func (r *tournamentRepo) Update(ctx context.Context, t *domain.Tournament) error {
+	return r.db.WithContext(ctx).Save(t).Error
+}
+
+func (r *tournamentRepo) FindByID(ctx context.Context, id uuid.UUID) (*domain.Tournament, error) {
+	var t domain.Tournament
+	if err := r.db.WithContext(ctx).First(&t, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// List returns every tournament, the ones voting closes latest first.
+func (r *tournamentRepo) List(ctx context.Context) ([]domain.Tournament, error) {
+	var out []domain.Tournament
+	err := r.db.WithContext(ctx).Order("voting_closes_at DESC").Find(&out).Error
+	return out, err
+}
+
+// CountEntries counts the videos userID has submitted to the tournament.
+// Rejected videos and those sent back for re-upload free up their slot.
+func (r *tournamentRepo) CountEntries(ctx context.Context, tournamentID, userID uuid.UUID) (int64, error) {
+	return countEntries(r.db.WithContext(ctx), tournamentID, userID)
+}
+
+func countEntries(tx *gorm.DB, tournamentID, userID uuid.UUID) (int64, error) {
+	var n int64
+	err := tx.Model(&domain.Video{}).
+		Where("tournament_id = ? AND user_id = ?", tournamentID, userID).
+		Where("status NOT IN ?", []domain.VideoStatus{domain.VideoRejected, domain.VideoReuploadRequested}).
+		Count(&n).Error
+	return n, err
+}
@@ -1,39 +1,49 @@
 package repo
 
 import (
+	"context"
 	"github.com/Cloud-2025-2/anb-platform/internal/domain"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
-	Create(u *domain.User) error
-	FindByEmail(email string) (*domain.User, error)
-	FindByID(id uuid.UUID) (*domain.User, error)
-	DeleteByID(id uuid.UUID) error
-	GetDistinctCities() ([]string, error)
+	Create(ctx context.Context, u *domain.User) error
+	FindByEmail(ctx context.Context, email string) (*domain.User, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.User, error)
+	DeleteByID(ctx context.Context, id uuid.UUID) error
+	GetDistinctCities(ctx context.Context) ([]string, error)
+	GetDistinctCountries(ctx context.Context) ([]string, error)
 }
 
-type userRepo struct{ db *gorm.DB }
+// userRepo sends the read-heavy public queries to read, which may be a
+// replica; everything else goes to the primary.
+type userRepo struct{ db, read *gorm.DB }
 
-func NewUserRepo(db *gorm.DB) UserRepository { return &userRepo{db} }
+// NewUserRepo returns a repository on db. A nil replica routes reads to db.
+func NewUserRepo(db, replica *gorm.DB) UserRepository {
+	if replica == nil {
+		replica = db
+	}
+	return &userRepo{db: db, read: replica}
+}
 
-func (r *userRepo) Create(u *domain.User) error { return r.db.Create(u).Error }
+func (r *userRepo) Create(ctx context.Context, u *domain.User) error { return r.db.WithContext(ctx).Create(u).Error }
 
-func (r *userRepo) FindByEmail(email string) (*domain.User, error) {
+func (r *userRepo) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
 	var u domain.User
-	if err := r.db.Where("email = ?", email).First(&u).Error; err != nil { return nil, err }
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&u).Error; err != nil { return nil, err }
 	return &u, nil
 }
 
-func (r *userRepo) FindByID(id uuid.UUID) (*domain.User, error) {
+func (r *userRepo) FindByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
 	var u domain.User
-	if err := r.db.First(&u, "id = ?", id).Error; err != nil { return nil, err }
+	if err := r.db.WithContext(ctx).First(&u, "id = ?", id).Error; err != nil { return nil, err }
 	return &u, nil
 }
 
-func (r *userRepo) DeleteByID(id uuid.UUID) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+func (r *userRepo) DeleteByID(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// First verify user exists
 		var user domain.User
 		if err := tx.Where("id = ?", id).First(&user).Error; err != nil {
@@ -68,11 +78,20 @@ func (r *userRepo) DeleteByID(id uuid.UUID) error {
 	})
 }
 
-func (r *userRepo) GetDistinctCities() ([]string, error) {
+func (r *userRepo) GetDistinctCities(ctx context.Context) ([]string, error) {
 	var cities []string
-	err := r.db.Model(&domain.User{}).
+	err := r.read.WithContext(ctx).Model(&domain.User{}).
 		Distinct("city").
 		Where("city != ''").
 		Pluck("city", &cities).Error
 	return cities, err
 }
+
+func (r *userRepo) GetDistinctCountries(ctx context.Context) ([]string, error) {
+	var countries []string
+	err := r.db.WithContext(ctx).Model(&domain.User{}).
+		Distinct("country").
+		Where("country != ''").
+		Pluck("country", &countries).Error
+	return countries, err
+}
@@ -1,59 +1,133 @@
 package repo
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/Cloud-2025-2/anb-platform/internal/domain"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type VideoRepository interface {
-	Create(v *domain.Video) error
-	FindByUser(userID uuid.UUID) ([]domain.Video, error)
-	FindByIDForUser(id, userID uuid.UUID) (*domain.Video, error)
-	FindByID(id uuid.UUID) (*domain.Video, error)                   // útil para el worker
-	Update(v *domain.Video) error
-	DeleteByIDForUser(id, userID uuid.UUID) error                   // usado por handler Delete
-	ListPublic(limit, offset int) ([]domain.Video, error)           // usado por público
+	Create(ctx context.Context, v *domain.Video) error
+	CreateEntry(ctx context.Context, v *domain.Video, maxEntries int) error // inscripción en torneo
+	FindByUser(ctx context.Context, userID uuid.UUID) ([]domain.Video, error)
+	FindByIDForUser(ctx context.Context, id, userID uuid.UUID) (*domain.Video, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.Video, error)                   // útil para el worker
+	Update(ctx context.Context, v *domain.Video) error
+	DeleteByIDForUser(ctx context.Context, id, userID uuid.UUID) error                   // usado por handler Delete
+	ListPublic(ctx context.Context, q VideoSearch) ([]domain.Video, *VideoCursor, error) // usado por público
+	ListByStatus(ctx context.Context, status domain.VideoStatus, limit, offset int) ([]domain.Video, error) // cola de moderación
+	UpdateStatus(ctx context.Context, v *domain.Video, ev *domain.VideoStatusEvent) error                  // usado por la máquina de estados
+	StatusHistory(ctx context.Context, videoID uuid.UUID) ([]domain.VideoStatusEvent, error)
+	UpdateDetails(ctx context.Context, id uuid.UUID, title, description string) error
 }
 
-type videoRepo struct{ db *gorm.DB }
+var (
+	// ErrStaleStatus means the video changed status since it was loaded.
+	ErrStaleStatus = errors.New("video status changed concurrently")
+	// ErrEntryLimit means the player already has the most entries the
+	// tournament allows.
+	ErrEntryLimit = errors.New("tournament entry limit reached")
+)
+
+// videoRepo sends the read-heavy public queries to read, which may be a
+// replica; everything else goes to the primary.
+type videoRepo struct{ db, read *gorm.DB }
+
+// NewVideoRepo returns a repository on db. A nil replica routes reads to db.
+func NewVideoRepo(db, replica *gorm.DB) VideoRepository {
+	if replica == nil {
+		replica = db
+	}
+	return &videoRepo{db: db, read: replica}
+}
 
-func NewVideoRepo(db *gorm.DB) VideoRepository { return &videoRepo{db} }
+func (r *videoRepo) Create(ctx context.Context, v *domain.Video) error {
+	return r.db.WithContext(ctx).Create(v).Error
+}
 
-func (r *videoRepo) Create(v *domain.Video) error {
-	return r.db.Create(v).Error
+// CreateEntry stores v, a tournament entry, unless its owner already has
+// maxEntries entries in the tournament. The entries of a player in a
+// tournament are serialized with an advisory lock so concurrent uploads
+// cannot overshoot the limit.
+func (r *videoRepo) CreateEntry(ctx context.Context, v *domain.Video, maxEntries int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		key := "entry:" + v.TournamentID.String() + ":" + v.UserID.String()
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", key).Error; err != nil {
+			return err
+		}
+		n, err := countEntries(tx, *v.TournamentID, v.UserID)
+		if err != nil {
+			return err
+		}
+		if n >= int64(maxEntries) {
+			return ErrEntryLimit
+		}
+		return tx.Create(v).Error
+	})
 }
 
-func (r *videoRepo) FindByUser(userID uuid.UUID) ([]domain.Video, error) {
+func (r *videoRepo) FindByUser(ctx context.Context, userID uuid.UUID) ([]domain.Video, error) {
 	var out []domain.Video
-	err := r.db.Preload("User").Where("user_id = ?", userID).
+	err := r.db.WithContext(ctx).Preload("User").Where("user_id = ?", userID).
 		Order("uploaded_at DESC").
 		Find(&out).Error
 	return out, err
 }
 
-func (r *videoRepo) FindByIDForUser(id, userID uuid.UUID) (*domain.Video, error) {
+func (r *videoRepo) FindByIDForUser(ctx context.Context, id, userID uuid.UUID) (*domain.Video, error) {
 	var v domain.Video
-	if err := r.db.Preload("User").Where("id = ? AND user_id = ?", id, userID).First(&v).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("User").Where("id = ? AND user_id = ?", id, userID).First(&v).Error; err != nil {
 		return nil, err
 	}
 	return &v, nil
 }
 
-func (r *videoRepo) FindByID(id uuid.UUID) (*domain.Video, error) {
+func (r *videoRepo) FindByID(ctx context.Context, id uuid.UUID) (*domain.Video, error) {
 	var v domain.Video
-	if err := r.db.Preload("User").First(&v, "id = ?", id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("User").Preload("Tournament").First(&v, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &v, nil
 }
 
-func (r *videoRepo) Update(v *domain.Video) error {
-	return r.db.Save(v).Error
+func (r *videoRepo) Update(ctx context.Context, v *domain.Video) error {
+	return r.db.WithContext(ctx).Save(v).Error
+}
+
+// UpdateStatus saves v and records ev in one transaction. The update only
+// applies if the video is still in ev.From, so two concurrent transitions
+// cannot both succeed.
+func (r *videoRepo) UpdateStatus(ctx context.Context, v *domain.Video, ev *domain.VideoStatusEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if ev.From != "" {
+			res := tx.Model(&domain.Video{}).Where("id = ? AND status = ?", v.ID, ev.From).Update("status", ev.To)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return ErrStaleStatus
+			}
+		}
+		if err := tx.Omit("User", "Tournament", "Votes").Save(v).Error; err != nil {
+			return err
+		}
+		return tx.Create(ev).Error
+	})
+}
+
+// StatusHistory returns the status changes of a video, oldest first.
+func (r *videoRepo) StatusHistory(ctx context.Context, videoID uuid.UUID) ([]domain.VideoStatusEvent, error) {
+	var out []domain.VideoStatusEvent
+	err := r.db.WithContext(ctx).Where("video_id = ?", videoID).Order("created_at ASC").Find(&out).Error
+	return out, err
 }
 
-func (r *videoRepo) DeleteByIDForUser(id, userID uuid.UUID) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
+func (r *videoRepo) DeleteByIDForUser(ctx context.Context, id, userID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// First verify the video exists and belongs to the user
 		var video domain.Video
 		if err := tx.Where("id = ? AND user_id = ?", id, userID).First(&video).Error; err != nil {
@@ -76,18 +150,98 @@ func (r *videoRepo) DeleteByIDForUser(id, userID uuid.UUID) error {
 	})
 }
 
-func (r *videoRepo) ListPublic(limit, offset int) ([]domain.Video, error) {
-	if limit <= 0 {
-		limit = 20
+// ListByStatus returns the videos in status, oldest first.
+func (r *videoRepo) ListByStatus(ctx context.Context, status domain.VideoStatus, limit, offset int) ([]domain.Video, error) {
+	var out []domain.Video
+	err := r.db.WithContext(ctx).Preload("User").Preload("Tournament").
+		Where("status = ?", status).
+		Order("processed_at ASC NULLS FIRST, uploaded_at ASC").
+		Limit(limit).Offset(offset).
+		Find(&out).Error
+	return out, err
+}
+
+// ListPublic returns published videos with their counted votes, computed in
+// SQL, and the cursor of the next page (nil on the last one).
+func (r *videoRepo) ListPublic(ctx context.Context, q VideoSearch) ([]domain.Video, *VideoCursor, error) {
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+	if q.Sort == "" {
+		q.Sort = VideoSortNewest
+	}
+
+	counts := r.read.WithContext(ctx).Table("votes").
+		Select("video_id, COUNT(*) AS total, COUNT(*) FILTER (WHERE created_at >= ?) AS recent", time.Now().Add(-TrendingWindow)).
+		Where("status = ?", domain.VoteCounted).
+		Group("video_id")
+	db := r.read.WithContext(ctx).Table("videos vd").
+		Select("vd.*, COALESCE(vc.total, 0) AS vote_count, COALESCE(vc.recent, 0) AS trending_votes").
+		Joins("JOIN users u ON u.id = vd.user_id").
+		Joins("LEFT JOIN (?) vc ON vc.video_id = vd.id", counts).
+		Where("vd.status = ? AND vd.is_public_for_vote = true", domain.VideoPublished)
+
+	if q.Query != "" {
+		db = db.Where(videoTitleVector+" @@ "+searchQueryParser+" OR "+playerNameVector+" @@ "+searchQueryParser, q.Query, q.Query)
+	}
+	if q.TournamentID != nil {
+		db = db.Where("vd.tournament_id = ?", *q.TournamentID)
+	}
+	if q.City != "" {
+		db = db.Where("u.city = ?", q.City)
 	}
-	if offset < 0 {
-		offset = 0
+	if q.Country != "" {
+		db = db.Where("u.country = ?", q.Country)
 	}
+
+	var key string
+	switch q.Sort {
+	case VideoSortVotes:
+		key = "COALESCE(vc.total, 0)"
+	case VideoSortTrending:
+		key = "COALESCE(vc.recent, 0)"
+	default:
+		key = publishedKey
+	}
+	if a := q.After; a != nil {
+		if a.Sort != q.Sort {
+			return nil, nil, ErrInvalidVideoCursor
+		}
+		if q.Sort == VideoSortNewest {
+			db = db.Where("("+key+", vd.id) < (?, ?)", a.PublishedAt, a.ID)
+		} else {
+			db = db.Where("("+key+", vd.id) < (?, ?)", a.Votes, a.ID)
+		}
+	}
+
 	var out []domain.Video
-	err := r.db.Preload("User").Preload("Votes").Where("status = ? AND is_public_for_vote = ?", "published", true).
-		Order("uploaded_at DESC").
-		Limit(limit).
-		Offset(offset).
+	err := db.Preload("User").
+		Order(key + " DESC, vd.id DESC").
+		Limit(q.Limit).
 		Find(&out).Error
-	return out, err
+	if err != nil || len(out) < q.Limit {
+		return out, nil, err
+	}
+
+	last := out[len(out)-1]
+	next := &VideoCursor{Sort: q.Sort, ID: last.ID}
+	switch q.Sort {
+	case VideoSortVotes:
+		next.Votes = last.VoteCount
+	case VideoSortTrending:
+		next.Votes = last.TrendingVotes
+	default:
+		next.PublishedAt = last.UploadedAt
+		if last.PublishedAt != nil {
+			next.PublishedAt = *last.PublishedAt
+		}
+	}
+	return out, next, nil
+}
+
+// UpdateDetails changes only the owner-editable fields, leaving the status
+// to the state machine.
+func (r *videoRepo) UpdateDetails(ctx context.Context, id uuid.UUID, title, description string) error {
+	return r.db.WithContext(ctx).Model(&domain.Video{}).Where("id = ?", id).
+		Updates(map[string]any{"title": title, "description": description}).Error
 }
@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VideoSort orders the public video list.
+type VideoSort string
+
+const (
+	VideoSortNewest   VideoSort = "newest"   // most recently published first
+	VideoSortVotes    VideoSort = "votes"    // most counted votes first
+	VideoSortTrending VideoSort = "trending" // most counted votes in the last TrendingWindow first
+)
+
+// TrendingWindow is how far back votes count for VideoSortTrending.
+const TrendingWindow = 24 * time.Hour
+
+// VideoSearch filters, sorts and paginates the public video list. Ties are
+// broken by video ID descending so pages never overlap.
+type VideoSearch struct {
+	Query        string // full-text search on the title and the player's name
+	City         string
+	Country      string
+	TournamentID *uuid.UUID
+	Sort         VideoSort
+	Limit        int
+	After        *VideoCursor // keyset: only videos strictly after this one
+}
+
+// VideoCursor marks the last video of a page. Only the key of the sort it was
+// issued for is set.
+type VideoCursor struct {
+	Sort        VideoSort `json:"s"`
+	Votes       int64     `json:"v,omitempty"`
+	PublishedAt time.Time `json:"t,omitempty"`
+	ID          uuid.UUID `json:"id"`
+}
+
+var ErrInvalidVideoCursor = errors.New("invalid video cursor")
+
+// Encode returns the opaque string handed to clients.
+func (c VideoCursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeVideoCursor(s string) (*VideoCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidVideoCursor
+	}
+	var c VideoCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID == uuid.Nil {
+		return nil, ErrInvalidVideoCursor
+	}
+	return &c, nil
+}
+
+// Full-text search runs on these expressions; migration 0002 indexes them.
+const (
+	videoTitleVector  = "to_tsvector('simple', coalesce(vd.title, ''))"
+	playerNameVector  = "to_tsvector('simple', u.first_name || ' ' || u.last_name)"
+	searchQueryParser = "websearch_to_tsquery('simple', ?)"
+
+	// Videos published before published_at was tracked fall back to their upload time
+	publishedKey = "COALESCE(vd.published_at, vd.uploaded_at)"
+)
@@ -1,37 +1,113 @@
 package repo
 
 import (
+	"context"
 	"errors"
 	"strings"
+	"time"
 	
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"github.com/Cloud-2025-2/anb-platform/internal/domain"
 )
 
-type RankingRow struct {
-	Position int    `json:"position"`
-	Username string `json:"username"`
-	City     string `json:"city"`
-	Votes    int64  `json:"votes"`
+type VoteRepository interface {
+	CastOnce(ctx context.Context, v *domain.Vote, quota VoteQuota) error
+	Retract(ctx context.Context, userID, videoID uuid.UUID) (*domain.Vote, error)
+	CountByVideo(ctx context.Context, videoID uuid.UUID) (int64, error)
+	ListByVoter(ctx context.Context, userID uuid.UUID) ([]domain.Vote, error)
+	VotedVideoIDs(ctx context.Context, userID uuid.UUID, videoIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+	CountByVoterInCity(ctx context.Context, userID uuid.UUID, city string) (int64, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*domain.Vote, error)
+	ListByStatus(ctx context.Context, status domain.VoteStatus, limit, offset int) ([]domain.Vote, error)
+	Review(ctx context.Context, id uuid.UUID, status domain.VoteStatus, reviewerID uuid.UUID) (*domain.Vote, error)
+
+	// Señales para detección de fraude
+	CountRecentFromIP(ctx context.Context, ip string, since time.Time) (int64, error)
+	CountRecentFromSubnet(ctx context.Context, subnet string, since time.Time) (int64, error)
+	CountOtherVotersWithFingerprint(ctx context.Context, fingerprint string, videoID, userID uuid.UUID) (int64, error)
+	VotesPerPlayer(ctx context.Context, voterID uuid.UUID) (map[uuid.UUID]int64, error)
+
+	TopByCity(ctx context.Context, q RankingQuery) ([]RankingRow, error)
+	TallyByVideo(ctx context.Context) ([]VideoTally, error)
+	TallyCastBy(ctx context.Context, voterID uuid.UUID) ([]VideoTally, error)
 }
 
-type VoteRepository interface {
-	CastOnce(userID, videoID uuid.UUID) error
-	CountByVideo(videoID uuid.UUID) (int64, error)
-	TopByCity(limit int, city string) ([]RankingRow, error)
+// voteRepo sends the read-heavy public queries to read, which may be a
+// replica; everything else goes to the primary.
+type voteRepo struct{ db, read *gorm.DB }
+
+// NewVoteRepo returns a repository on db. A nil replica routes reads to db.
+func NewVoteRepo(db, replica *gorm.DB) VoteRepository {
+	if replica == nil {
+		replica = db
+	}
+	return &voteRepo{db: db, read: replica}
 }
 
-type voteRepo struct{ db *gorm.DB }
+var (
+	ErrDuplicateVote      = errors.New("user has already voted for this video")
+	ErrVoteNotQuarantined = errors.New("vote is not pending review")
+	ErrVoteQuotaExceeded  = errors.New("vote quota exceeded")
+)
 
-func NewVoteRepo(db *gorm.DB) VoteRepository { return &voteRepo{db} }
+// VoteQuota limits how many votes a user may cast. Zero means unlimited.
+type VoteQuota struct {
+	PerCity       int // votos por ciudad del jugador votado
+	PerTournament int // votos por torneo del video votado
+}
 
-var ErrDuplicateVote = errors.New("user has already voted for this video")
+// CastOnce stores v unless the voter already voted for the video or the vote
+// would exceed quota. Rejected votes do not use up the quota. The voter's votes
+// are serialized with an advisory lock so concurrent requests cannot overshoot it.
+func (r *voteRepo) CastOnce(ctx context.Context, v *domain.Vote, quota VoteQuota) error {
+	if v.Status == "" {
+		v.Status = domain.VoteCounted
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if quota.PerCity <= 0 && quota.PerTournament <= 0 {
+			return tx.Create(v).Error
+		}
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "vote:"+v.UserID.String()).Error; err != nil {
+			return err
+		}
+		var target struct {
+			City         string
+			TournamentID *uuid.UUID
+		}
+		if err := tx.Table("videos vd").Select("u.city, vd.tournament_id").
+			Joins("JOIN users u ON u.id = vd.user_id").
+			Where("vd.id = ?", v.VideoID).
+			Scan(&target).Error; err != nil {
+			return err
+		}
+		if quota.PerCity > 0 {
+			n, err := countByVoterInCity(tx, v.UserID, target.City)
+			if err != nil {
+				return err
+			}
+			if n >= int64(quota.PerCity) {
+				return ErrVoteQuotaExceeded
+			}
+		}
+		if quota.PerTournament > 0 && target.TournamentID != nil {
+			var n int64
+			if err := tx.Table("votes v").
+				Joins("JOIN videos vd ON vd.id = v.video_id").
+				Where("v.user_id = ? AND vd.tournament_id = ? AND v.status <> ?", v.UserID, *target.TournamentID, domain.VoteRejected).
+				Count(&n).Error; err != nil {
+				return err
+			}
+			if n >= int64(quota.PerTournament) {
+				return ErrVoteQuotaExceeded
+			}
+		}
+		return tx.Create(v).Error
+	})
+	if errors.Is(err, ErrVoteQuotaExceeded) {
+		return err
+	}
 
-func (r *voteRepo) CastOnce(userID, videoID uuid.UUID) error {
-	v := domain.Vote{UserID: userID, VideoID: videoID}
-	err := r.db.Create(&v).Error
-	
 	// Check if error is due to unique constraint violation
 	if err != nil && (strings.Contains(err.Error(), "duplicate key") || 
 		strings.Contains(err.Error(), "UNIQUE constraint") || 
@@ -42,23 +118,257 @@ func (r *voteRepo) CastOnce(userID, videoID uuid.UUID) error {
 	return err
 }
 
-func (r *voteRepo) CountByVideo(videoID uuid.UUID) (int64, error) {
+// Retract deletes the vote userID cast for videoID and returns it, or
+// gorm.ErrRecordNotFound if there was none.
+func (r *voteRepo) Retract(ctx context.Context, userID, videoID uuid.UUID) (*domain.Vote, error) {
+	var v domain.Vote
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND video_id = ?", userID, videoID).First(&v).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&v).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ListByVoter returns the votes cast by userID, newest first, with their videos.
+func (r *voteRepo) ListByVoter(ctx context.Context, userID uuid.UUID) ([]domain.Vote, error) {
+	var out []domain.Vote
+	err := r.db.WithContext(ctx).Preload("Video").Preload("Video.User").
+		Where("user_id = ? AND status <> ?", userID, domain.VoteRejected).
+		Order("created_at DESC").
+		Find(&out).Error
+	return out, err
+}
+
+// VotedVideoIDs reports which of videoIDs userID has voted for.
+func (r *voteRepo) VotedVideoIDs(ctx context.Context, userID uuid.UUID, videoIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	out := make(map[uuid.UUID]bool, len(videoIDs))
+	if len(videoIDs) == 0 {
+		return out, nil
+	}
+	var ids []uuid.UUID
+	err := r.db.WithContext(ctx).Model(&domain.Vote{}).
+		Where("user_id = ? AND video_id IN ? AND status <> ?", userID, videoIDs, domain.VoteRejected).
+		Pluck("video_id", &ids).Error
+	for _, id := range ids {
+		out[id] = true
+	}
+	return out, err
+}
+
+// CountByVoterInCity counts the votes userID has cast for players of city.
+func (r *voteRepo) CountByVoterInCity(ctx context.Context, userID uuid.UUID, city string) (int64, error) {
+	return countByVoterInCity(r.db.WithContext(ctx), userID, city)
+}
+
+func countByVoterInCity(tx *gorm.DB, userID uuid.UUID, city string) (int64, error) {
+	var n int64
+	err := tx.Table("votes v").
+		Joins("JOIN videos vd ON vd.id = v.video_id").
+		Joins("JOIN users u ON u.id = vd.user_id").
+		Where("v.user_id = ? AND u.city = ? AND v.status <> ?", userID, city, domain.VoteRejected).
+		Count(&n).Error
+	return n, err
+}
+
+func (r *voteRepo) CountByVideo(ctx context.Context, videoID uuid.UUID) (int64, error) {
 	var n int64
-	err := r.db.Model(&domain.Vote{}).Where("video_id = ?", videoID).Count(&n).Error
+	err := r.db.WithContext(ctx).Model(&domain.Vote{}).Where("video_id = ? AND status = ?", videoID, domain.VoteCounted).Count(&n).Error
 	return n, err
 }
 
-func (r *voteRepo) TopByCity(limit int, city string) ([]RankingRow, error) {
-	var rows []RankingRow
-	q := r.db.Table("votes v").
-		Select("ROW_NUMBER() OVER (ORDER BY COUNT(*) DESC) as position, CONCAT(u.first_name, ' ', u.last_name) as username, u.city, COUNT(*) as votes").
+func (r *voteRepo) FindByID(ctx context.Context, id uuid.UUID) (*domain.Vote, error) {
+	var v domain.Vote
+	if err := r.db.WithContext(ctx).Preload("User").Preload("Video").First(&v, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ListByStatus returns votes in the given status, most suspicious first.
+func (r *voteRepo) ListByStatus(ctx context.Context, status domain.VoteStatus, limit, offset int) ([]domain.Vote, error) {
+	var out []domain.Vote
+	err := r.db.WithContext(ctx).Preload("User").Preload("Video").
+		Where("status = ?", status).
+		Order("fraud_score DESC, created_at ASC").
+		Limit(limit).Offset(offset).
+		Find(&out).Error
+	return out, err
+}
+
+// Review moves a quarantined vote to status. Votes already reviewed are left
+// untouched and ErrVoteNotQuarantined is returned, so concurrent reviews cannot
+// count a vote twice.
+func (r *voteRepo) Review(ctx context.Context, id uuid.UUID, status domain.VoteStatus, reviewerID uuid.UUID) (*domain.Vote, error) {
+	now := time.Now().UTC()
+	res := r.db.WithContext(ctx).Model(&domain.Vote{}).
+		Where("id = ? AND status = ?", id, domain.VoteQuarantined).
+		Updates(map[string]interface{}{"status": status, "reviewed_by": reviewerID, "reviewed_at": now})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		if _, err := r.FindByID(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrVoteNotQuarantined
+	}
+	return r.FindByID(ctx, id)
+}
+
+func (r *voteRepo) CountRecentFromIP(ctx context.Context, ip string, since time.Time) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&domain.Vote{}).Where("ip = ? AND created_at >= ?", ip, since).Count(&n).Error
+	return n, err
+}
+
+func (r *voteRepo) CountRecentFromSubnet(ctx context.Context, subnet string, since time.Time) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&domain.Vote{}).Where("subnet = ? AND created_at >= ?", subnet, since).Count(&n).Error
+	return n, err
+}
+
+// CountOtherVotersWithFingerprint counts votes for videoID cast by other
+// accounts from the same device.
+func (r *voteRepo) CountOtherVotersWithFingerprint(ctx context.Context, fingerprint string, videoID, userID uuid.UUID) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&domain.Vote{}).
+		Where("fingerprint = ? AND video_id = ? AND user_id <> ?", fingerprint, videoID, userID).
+		Count(&n).Error
+	return n, err
+}
+
+// VotesPerPlayer returns how many votes voterID has cast for each player.
+func (r *voteRepo) VotesPerPlayer(ctx context.Context, voterID uuid.UUID) (map[uuid.UUID]int64, error) {
+	var rows []struct {
+		PlayerID uuid.UUID
+		Votes    int64
+	}
+	err := r.db.WithContext(ctx).Table("votes v").
+		Select("vd.user_id as player_id, COUNT(*) as votes").
+		Joins("JOIN videos vd ON vd.id = v.video_id").
+		Where("v.user_id = ?", voterID).
+		Group("vd.user_id").
+		Scan(&rows).Error
+	out := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		out[row.PlayerID] = row.Votes
+	}
+	return out, err
+}
+
+// TopByCity computes the ranking in Postgres. It is the source of truth the
+// Redis leaderboard is rebuilt from, and the fallback while it is not built.
+func (r *voteRepo) TopByCity(ctx context.Context, q RankingQuery) ([]RankingRow, error) {
+	if q.Limit <= 0 {
+		q.Limit = 50
+	}
+
+	idCol := "user_id"
+	inner := r.read.WithContext(ctx).Table("votes v").
+		Where("v.status = ?", domain.VoteCounted).
+		Joins("JOIN videos vd ON vd.id = v.video_id AND vd.is_public_for_vote = true").
+		Joins("JOIN users u ON u.id = vd.user_id")
+	if q.Scope == RankingByVideo {
+		idCol = "video_id"
+		inner = inner.
+			Select("RANK() OVER (ORDER BY COUNT(*) DESC) as position, vd.id as video_id, vd.title, vd.id::text as video_ids, " +
+				"u.id as user_id, CONCAT(u.first_name, ' ', u.last_name) as username, u.city, u.country, COUNT(*) as votes").
+			Group("vd.id, vd.title, u.id, u.first_name, u.last_name, u.city, u.country")
+	} else {
+		inner = inner.
+			Select("RANK() OVER (ORDER BY COUNT(*) DESC) as position, STRING_AGG(DISTINCT vd.id::text, ',') as video_ids, " +
+				"u.id as user_id, CONCAT(u.first_name, ' ', u.last_name) as username, u.city, u.country, COUNT(*) as votes").
+			Group("u.id, u.first_name, u.last_name, u.city, u.country")
+	}
+	if q.TournamentID != nil {
+		inner = inner.Where("vd.tournament_id = ?", *q.TournamentID)
+	}
+	if q.City != "" {
+		inner = inner.Where("u.city = ?", q.City)
+	}
+	if q.Country != "" {
+		inner = inner.Where("u.country = ?", q.Country)
+	}
+	if q.From != nil {
+		inner = inner.Where("v.created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		inner = inner.Where("v.created_at < ?", *q.To)
+	}
+
+	// Positions are computed over the whole ranking before the keyset filter applies
+	outer := r.read.WithContext(ctx).Table("(?) as r", inner).
+		Order("r.votes DESC, r." + idCol + " DESC").
+		Limit(q.Limit)
+	if q.After != nil {
+		outer = outer.Where("r.votes < ? OR (r.votes = ? AND r."+idCol+" < ?)", q.After.Votes, q.After.Votes, q.After.ID)
+	}
+
+	var scanned []struct {
+		Position int
+		UserID   uuid.UUID
+		Username string
+		City     string
+		Country  string
+		VideoID  *uuid.UUID
+		Title    string
+		VideoIDs string
+		Votes    int64
+	}
+	if err := outer.Scan(&scanned).Error; err != nil {
+		return nil, err
+	}
+	rows := make([]RankingRow, 0, len(scanned))
+	for _, s := range scanned {
+		rows = append(rows, RankingRow{
+			Position: s.Position,
+			UserID:   s.UserID,
+			Username: s.Username,
+			City:     s.City,
+			Country:  s.Country,
+			VideoID:  s.VideoID,
+			Title:    s.Title,
+			VideoIDs: parseUUIDList(s.VideoIDs),
+			Votes:    s.Votes,
+		})
+	}
+	return rows, nil
+}
+
+// TallyByVideo returns the vote count of every video with at least one vote.
+func (r *voteRepo) TallyByVideo(ctx context.Context) ([]VideoTally, error) {
+	var rows []VideoTally
+	err := r.tallyQuery(ctx).Scan(&rows).Error
+	return rows, err
+}
+
+// TallyCastBy returns, per video, how many votes the given user has cast for it.
+func (r *voteRepo) TallyCastBy(ctx context.Context, voterID uuid.UUID) ([]VideoTally, error) {
+	var rows []VideoTally
+	err := r.tallyQuery(ctx).Where("v.user_id = ?", voterID).Scan(&rows).Error
+	return rows, err
+}
+
+func (r *voteRepo) tallyQuery(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Table("votes v").
+		Select("vd.id as video_id, vd.title, vd.tournament_id, u.id as user_id, CONCAT(u.first_name, ' ', u.last_name) as username, u.city, u.country, COUNT(*) as votes").
 		Joins("JOIN videos vd ON vd.id = v.video_id AND vd.is_public_for_vote = true").
 		Joins("JOIN users u ON u.id = vd.user_id").
-		Group("u.id, u.first_name, u.last_name, u.city").
-		Order("votes DESC").
-		Limit(limit)
-	if city != "" { 
-		q = q.Where("u.city = ?", city) 
+		Where("v.status = ?", domain.VoteCounted).
+		Group("vd.id, vd.title, vd.tournament_id, u.id, u.first_name, u.last_name, u.city, u.country")
+}
+
+func parseUUIDList(s string) []uuid.UUID {
+	out := []uuid.UUID{}
+	for _, part := range strings.Split(s, ",") {
+		if id, err := uuid.Parse(part); err == nil {
+			out = append(out, id)
+		}
 	}
-	return rows, q.Scan(&rows).Error
+	return out
 }
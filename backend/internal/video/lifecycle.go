@@ -0,0 +1,132 @@
+package video
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Cloud-2025-2/anb-platform/internal/domain"
+	"github.com/Cloud-2025-2/anb-platform/internal/events"
+	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+)
+
+// VoteTally adjusts the live rankings when a video enters or leaves voting.
+// It is implemented by ranking.Service; the worker runs without one.
+type VoteTally interface {
+	RecordVote(ctx context.Context, video *domain.Video, delta int64)
+	RefreshVideo(ctx context.Context, video *domain.Video)
+}
+
+// Lifecycle is the only component allowed to change a video's status. It
+// rejects transitions the lifecycle forbids, applies their side effects,
+// records them in video_status_events and notifies the owner.
+type Lifecycle struct {
+	videos repo.VideoRepository
+	votes  repo.VoteRepository
+	tally  VoteTally
+	events *events.Publisher
+}
+
+// NewLifecycle creates the state machine. votes and tally may be nil when
+// videos never enter or leave voting, as in the worker.
+func NewLifecycle(videos repo.VideoRepository, votes repo.VoteRepository, tally VoteTally, events *events.Publisher) *Lifecycle {
+	return &Lifecycle{videos: videos, votes: votes, tally: tally, events: events}
+}
+
+// Transition moves v to status to. actor is nil for system changes. On
+// success v reflects the new status.
+func (l *Lifecycle) Transition(ctx context.Context, v *domain.Video, to domain.VideoStatus, actor *uuid.UUID, reason string) error {
+	from := v.Status
+	if !domain.CanTransition(from, to) {
+		return domain.ErrIllegalTransition{From: from, To: to}
+	}
+
+	prev := *v
+	now := time.Now()
+	v.Status = to
+	switch to {
+	case domain.VideoPublished:
+		v.IsPublicForVote = true
+		if v.PublishedAt == nil {
+			v.PublishedAt = &now
+		}
+	case domain.VideoProcessed:
+		v.ProcessedAt = &now
+	default:
+		v.IsPublicForVote = false
+	}
+	if actor != nil && isModeration(to) {
+		v.ModeratedBy = actor
+		v.ModeratedAt = &now
+		v.ModerationReason = nil
+		if reason != "" {
+			v.ModerationReason = &reason
+		}
+	}
+
+	ev := &domain.VideoStatusEvent{VideoID: v.ID, From: from, To: to, Reason: reason, ActorID: actor}
+	if err := l.videos.UpdateStatus(ctx, v, ev); err != nil {
+		*v = prev
+		return err
+	}
+
+	// Votes of videos leaving or re-entering voting leave or re-enter the rankings
+	if prev.IsPublicForVote != v.IsPublicForVote {
+		l.adjustRankings(ctx, v, v.IsPublicForVote)
+	}
+	l.events.VideoStatusChanged(ctx, v.UserID, v.ID, v.Status)
+	return nil
+}
+
+// Created records the initial status of a freshly uploaded video.
+func (l *Lifecycle) Created(ctx context.Context, v *domain.Video) {
+	ev := &domain.VideoStatusEvent{VideoID: v.ID, To: v.Status}
+	if err := l.videos.UpdateStatus(ctx, v, ev); err != nil {
+		slog.ErrorContext(ctx, "lifecycle: failed to record initial status", "video_id", v.ID, "error", err)
+	}
+	l.events.VideoStatusChanged(ctx, v.UserID, v.ID, v.Status)
+}
+
+// History returns the recorded status changes of a video, oldest first.
+func (l *Lifecycle) History(ctx context.Context, videoID uuid.UUID) ([]domain.VideoStatusEvent, error) {
+	return l.videos.StatusHistory(ctx, videoID)
+}
+
+func (l *Lifecycle) adjustRankings(ctx context.Context, v *domain.Video, entering bool) {
+	if l.votes == nil || l.tally == nil {
+		return
+	}
+	n, err := l.votes.CountByVideo(ctx, v.ID)
+	if err != nil {
+		slog.WarnContext(ctx, "lifecycle: failed to count votes", "video_id", v.ID, "error", err)
+		return
+	}
+	if n == 0 {
+		return
+	}
+	if !entering {
+		n = -n
+	}
+	l.tally.RecordVote(ctx, v, n)
+}
+
+// refreshRankings rewrites the title and owner of a voted video in the rankings.
+func (l *Lifecycle) refreshRankings(ctx context.Context, v *domain.Video) {
+	if l.votes == nil || l.tally == nil {
+		return
+	}
+	if n, err := l.votes.CountByVideo(ctx, v.ID); err != nil || n == 0 {
+		return
+	}
+	l.tally.RefreshVideo(ctx, v)
+}
+
+func isModeration(s domain.VideoStatus) bool {
+	switch s {
+	case domain.VideoPublished, domain.VideoRejected, domain.VideoReuploadRequested, domain.VideoUnpublished:
+		return true
+	}
+	return false
+}
@@ -1,14 +1,20 @@
 package video
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"path/filepath"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/Cloud-2025-2/anb-platform/internal/domain"
-	"github.com/Cloud-2025-2/anb-platform/internal/kafka"
+	"github.com/Cloud-2025-2/anb-platform/internal/processing"
+	"github.com/Cloud-2025-2/anb-platform/internal/queue"
 	"github.com/Cloud-2025-2/anb-platform/internal/repo"
+	"github.com/Cloud-2025-2/anb-platform/internal/tracing"
 )
 
 type Storage interface {
@@ -17,17 +23,42 @@ type Storage interface {
 }
 
 type Service struct {
-	videos   repo.VideoRepository
-	store    Storage
-	producer *kafka.Producer
+	videos      repo.VideoRepository
+	tasks       repo.TaskRepository
+	tournaments repo.TournamentRepository
+	store       Storage
+	producer    queue.TaskPublisher
+	lifecycle   *Lifecycle
+	// fairShare es cuántas tareas pendientes puede tener un usuario en el
+	// carril de subidas; las siguientes van al carril bulk
+	fairShare int
 }
 
-func NewService(videos repo.VideoRepository, store Storage, producer *kafka.Producer) *Service {
-	return &Service{videos: videos, store: store, producer: producer}
+func NewService(videos repo.VideoRepository, tasks repo.TaskRepository, tournaments repo.TournamentRepository, store Storage, producer queue.TaskPublisher, lifecycle *Lifecycle, fairShare int) *Service {
+	return &Service{videos: videos, tasks: tasks, tournaments: tournaments, store: store, producer: producer, lifecycle: lifecycle, fairShare: max(fairShare, 1)}
 }
 
-// UploadAndEnqueue guarda metadata del video y crea una tarea asíncrona
-func (s *Service) UploadAndEnqueue(user domain.User, tmpPath, title string) (taskID string, videoID uuid.UUID, err error) {
+var (
+	ErrRegistrationClosed = errors.New("tournament is not accepting entries")
+	ErrNotEligible        = errors.New("player is not eligible for this tournament")
+	ErrTooManyEntries     = errors.New("maximum entries for this tournament reached")
+	ErrTitleRequired      = errors.New("title is required")
+)
+
+// UploadAndEnqueue guarda metadata del video y crea una tarea asíncrona.
+// Si tournamentID no es nil, el video se inscribe en ese torneo.
+func (s *Service) UploadAndEnqueue(ctx context.Context, user domain.User, tmpPath, title string, tournamentID *uuid.UUID) (taskID string, videoID uuid.UUID, err error) {
+	ctx, span := tracing.Tracer.Start(ctx, "video.UploadAndEnqueue")
+	defer func() { tracing.End(span, err) }()
+
+	// 0. Validar la inscripción al torneo antes de guardar nada
+	var tournament *domain.Tournament
+	if tournamentID != nil {
+		if tournament, err = s.checkEntry(ctx, user, *tournamentID); err != nil {
+			return "", uuid.Nil, err
+		}
+	}
+
 	// 1. Guardar archivo en storage
 	destName := uuid.New().String() + filepath.Ext(tmpPath)
 	url, err := s.store.Save(tmpPath, destName)
@@ -42,24 +73,193 @@ func (s *Service) UploadAndEnqueue(user domain.User, tmpPath, title string) (tas
 		OriginalURL:  url,
 		Status:       domain.VideoUploaded,
 		CitySnapshot: user.City,
+		TournamentID: tournamentID,
+	}
+	// Una inscripción vuelve a contar las del jugador al guardarse, por si
+	// otra subida suya terminó entre tanto
+	if tournament != nil {
+		err = s.videos.CreateEntry(ctx, &v, tournament.MaxEntriesPerPlayer)
+	} else {
+		err = s.videos.Create(ctx, &v)
 	}
-	if err := s.videos.Create(&v); err != nil {
+	if errors.Is(err, repo.ErrEntryLimit) {
+		return "", uuid.Nil, ErrTooManyEntries
+	}
+	if err != nil {
 		return "", uuid.Nil, err
 	}
+	span.SetAttributes(attribute.String("video.id", v.ID.String()))
+	s.lifecycle.Created(ctx, &v)
 
 	// 3. Encolar tarea para el worker usando Kafka
-	task := kafka.VideoProcessingTask{
+	lane, err := s.uploadLane(ctx, user.ID)
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+	task, err := s.enqueue(ctx, &v, processing.DefaultProfile, lane)
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+
+	return task.ID.String(), v.ID, nil
+}
+
+// Retry vuelve a encolar un video cuyo procesamiento falló, con su mismo perfil
+func (s *Service) Retry(ctx context.Context, v *domain.Video, actor uuid.UUID) error {
+	if v.Status != domain.VideoFailed {
+		return domain.ErrIllegalTransition{From: v.Status, To: domain.VideoUploaded}
+	}
+	return s.Reprocess(ctx, v, actor, processing.Profile(v.Profile))
+}
+
+// Reprocess vuelve a procesar el archivo original con profile. Un video
+// publicado sale de la votación hasta que la moderación apruebe el nuevo
+// resultado; sus votos se conservan y vuelven a contar entonces.
+func (s *Service) Reprocess(ctx context.Context, v *domain.Video, actor uuid.UUID, profile processing.Profile) error {
+	if err := s.lifecycle.Transition(ctx, v, domain.VideoUploaded, &actor, "reprocess requested ("+string(profile)+")"); err != nil {
+		return err
+	}
+	_, err := s.enqueue(ctx, v, profile, domain.LaneReprocess)
+	return err
+}
+
+// Withdraw retira un video publicado de la votación. Sus votos se conservan
+// para auditoría y vuelven a contar si el video se publica de nuevo tras revisión.
+func (s *Service) Withdraw(ctx context.Context, v *domain.Video, actor uuid.UUID, reason string) error {
+	return s.lifecycle.Transition(ctx, v, domain.VideoUnpublished, &actor, reason)
+}
+
+// Archive deja el video en su estado final
+func (s *Service) Archive(ctx context.Context, v *domain.Video, actor uuid.UUID, reason string) error {
+	return s.lifecycle.Transition(ctx, v, domain.VideoArchived, &actor, reason)
+}
+
+// UpdateDetails cambia el título y la descripción del video
+func (s *Service) UpdateDetails(ctx context.Context, v *domain.Video, title, description string) error {
+	if title == "" {
+		return ErrTitleRequired
+	}
+	if err := s.videos.UpdateDetails(ctx, v.ID, title, description); err != nil {
+		return err
+	}
+	v.Title = title
+	v.Description = description
+	// El ranking guarda el título de los videos votados
+	if v.IsPublicForVote {
+		s.lifecycle.refreshRankings(ctx, v)
+	}
+	return nil
+}
+
+// History devuelve los cambios de estado del video
+func (s *Service) History(ctx context.Context, videoID uuid.UUID) ([]domain.VideoStatusEvent, error) {
+	return s.lifecycle.History(ctx, videoID)
+}
+
+// uploadLane elige el carril de una subida: el de subidas mientras el usuario
+// tenga menos de fairShare tareas pendientes, y bulk a partir de ahí. Así
+// quien sube 50 videos de golpe no deja esperando a los demás. Solo baja de
+// carril: dentro de cada carril no hay turnos por usuario, las tareas salen
+// en orden de llegada.
+func (s *Service) uploadLane(ctx context.Context, userID uuid.UUID) (domain.TaskLane, error) {
+	pending, err := s.tasks.CountPendingByUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if pending >= int64(s.fairShare) {
+		return domain.LaneBulk, nil
+	}
+	return domain.LaneUpload, nil
+}
+
+// TaskStatus es una tarea con una estimación de su posición en la cola, si
+// sigue encolada.
+type TaskStatus struct {
+	*domain.ProcessingTask
+	EstimatedQueuePosition *int64
+}
+
+// TaskStatus devuelve la tarea taskID si su video pertenece a userID.
+func (s *Service) TaskStatus(ctx context.Context, taskID, userID uuid.UUID) (*TaskStatus, error) {
+	t, err := s.tasks.FindByIDForUser(ctx, taskID, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &TaskStatus{ProcessingTask: t}
+	if t.Status == domain.TaskQueued {
+		pos, err := s.tasks.EstimateQueuePosition(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		status.EstimatedQueuePosition = &pos
+	}
+	return status, nil
+}
+
+// enqueue crea el registro de la tarea, donde el worker reporta su avance, y
+// la publica en la cola en el carril lane. Si no lo logra, el video (que
+// está en uploaded) queda fallido, para que su dueño pueda reintentarlo en
+// vez de quedar sin tarea.
+func (s *Service) enqueue(ctx context.Context, v *domain.Video, profile processing.Profile, lane domain.TaskLane) (*domain.ProcessingTask, error) {
+	record := domain.ProcessingTask{VideoID: v.ID, TaskType: domain.TaskTypeVideoProcess, Status: domain.TaskQueued, Lane: lane}
+	if err := s.tasks.Create(ctx, &record); err != nil {
+		s.failEnqueue(ctx, v, nil, err)
+		return nil, err
+	}
+
+	task := queue.Task{
+		TaskID:     record.ID.String(),
 		VideoID:    v.ID.String(),
-		UserID:     user.ID.String(),
-		Title:      title,
-		FilePath:   url,
+		UserID:     v.UserID.String(),
+		Title:      v.Title,
+		FilePath:   v.OriginalURL,
 		Timestamp:  time.Now(),
 		RetryCount: 0,
+		Profile:    string(profile),
+		Lane:       lane,
+	}
+	if err := s.producer.Publish(ctx, task); err != nil {
+		s.failEnqueue(ctx, v, &record, err)
+		return nil, err
 	}
+	return &record, nil
+}
 
-	if err := s.producer.PublishVideoProcessingTask(task); err != nil {
-		return "", uuid.Nil, err
+// failEnqueue marca como fallidos el video y, si llegó a crearse, el
+// registro de la tarea que no se pudo encolar.
+func (s *Service) failEnqueue(ctx context.Context, v *domain.Video, record *domain.ProcessingTask, cause error) {
+	ctx = context.WithoutCancel(ctx)
+	reason := "enqueue failed: " + cause.Error()
+	if record != nil {
+		if err := s.tasks.Finish(ctx, record.ID, domain.TaskFailed, &reason); err != nil {
+			slog.WarnContext(ctx, "video: failed to record task failure", "task_id", record.ID, "error", err)
+		}
 	}
+	if err := s.lifecycle.Transition(ctx, v, domain.VideoFailed, nil, reason); err != nil {
+		slog.ErrorContext(ctx, "video: failed to mark unqueued video as failed", "video_id", v.ID, "error", err)
+	}
+}
 
-	return uuid.New().String(), v.ID, nil
+// checkEntry verifica que el usuario pueda inscribir un video más en el
+// torneo y lo devuelve. Devuelve gorm.ErrRecordNotFound si el torneo no
+// existe. El límite de inscripciones se vuelve a comprobar al crear el video.
+func (s *Service) checkEntry(ctx context.Context, user domain.User, tournamentID uuid.UUID) (*domain.Tournament, error) {
+	t, err := s.tournaments.FindByID(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	if !t.AcceptsEntries(time.Now()) {
+		return nil, ErrRegistrationClosed
+	}
+	if !t.Eligible(user.City, user.Country) {
+		return nil, ErrNotEligible
+	}
+	n, err := s.tournaments.CountEntries(ctx, tournamentID, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if n >= int64(t.MaxEntriesPerPlayer) {
+		return nil, ErrTooManyEntries
+	}
+	return t, nil
 }
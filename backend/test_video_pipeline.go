@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -11,6 +12,7 @@ import (
 	"github.com/Cloud-2025-2/anb-platform/internal/domain"
 	"github.com/Cloud-2025-2/anb-platform/internal/kafka"
 	"github.com/Cloud-2025-2/anb-platform/internal/processing"
+	"github.com/Cloud-2025-2/anb-platform/internal/queue"
 	"github.com/Cloud-2025-2/anb-platform/internal/repo"
 	"github.com/Cloud-2025-2/anb-platform/internal/storage"
 	"github.com/google/uuid"
@@ -27,21 +29,22 @@ func main() {
 	cfg := config.Load()
 
 	// Initialize database connection
-	db.Connect()
+	db.Connect(cfg)
 
-	// Auto-migrate
-	if err := db.DB.AutoMigrate(&domain.User{}, &domain.Video{}, &domain.Vote{}); err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+	// Schema is applied by `go run ./cmd/migrate up`
+	if err := db.RequireSchema(); err != nil {
+		log.Fatalf("Database not migrated: %v", err)
 	}
 
 	// Initialize repositories
-	videoRepo := repo.NewVideoRepo(db.DB)
+	videoRepo := repo.NewVideoRepo(db.DB, db.Replica)
+	ctx := context.Background()
 
 	// Initialize storage (for completeness)
 	_ = storage.NewLocal("./storage")
 
 	// Initialize video processor
-	processor := processing.NewVideoProcessor("./storage", "./assets", "./assets")
+	processor := processing.NewVideoProcessor("./storage", "./assets", "./assets", 5*time.Minute)
 
 	// Initialize Kafka producer
 	producer, err := kafka.NewProducer(cfg.KafkaBrokers)
@@ -63,21 +66,21 @@ func main() {
 	}
 
 	// Save to database
-	if err := videoRepo.Create(testVideo); err != nil {
+	if err := videoRepo.Create(ctx, testVideo); err != nil {
 		log.Fatalf("Failed to create test video: %v", err)
 	}
 
 	fmt.Printf("✅ Created test video: %s\n", testVideo.ID)
 
 	// Test Kafka message publishing
-	task := kafka.VideoProcessingTask{
+	task := queue.Task{
 		VideoID:   testVideo.ID.String(),
 		UserID:    testVideo.UserID.String(),
 		Timestamp: time.Now(),
 	}
 
 	// Publish to main topic
-	if err := producer.PublishVideoProcessingTask(task); err != nil {
+	if err := producer.Publish(ctx, task); err != nil {
 		log.Fatalf("Failed to publish video processing task: %v", err)
 	}
 
@@ -88,7 +91,7 @@ func main() {
 	fmt.Println("==================================")
 
 	// Test batch processing capability
-	batchTasks := []kafka.VideoProcessingTask{
+	batchTasks := []queue.Task{
 		{VideoID: uuid.New().String(), UserID: uuid.New().String()},
 		{VideoID: uuid.New().String(), UserID: uuid.New().String()},
 		{VideoID: uuid.New().String(), UserID: uuid.New().String()},
@@ -115,13 +118,13 @@ func main() {
 
 	// Update video status
 	testVideo.Status = domain.VideoProcessing
-	if err := videoRepo.Update(testVideo); err != nil {
+	if err := videoRepo.Update(ctx, testVideo); err != nil {
 		log.Fatalf("Failed to update video status: %v", err)
 	}
 	fmt.Printf("✅ Updated video status to: %s\n", testVideo.Status)
 
 	// Test finding video
-	foundVideo, err := videoRepo.FindByID(testVideo.ID)
+	foundVideo, err := videoRepo.FindByID(ctx, testVideo.ID)
 	if err != nil {
 		log.Fatalf("Failed to find video: %v", err)
 	}
@@ -138,7 +141,7 @@ func main() {
 	fmt.Printf("✅ VideoProcessingTask JSON:\n%s\n", taskJSON)
 
 	// Clean up test video
-	if err := videoRepo.DeleteByIDForUser(testVideo.ID, testVideo.UserID); err != nil {
+	if err := videoRepo.DeleteByIDForUser(ctx, testVideo.ID, testVideo.UserID); err != nil {
 		log.Printf("Warning: Failed to clean up test video: %v", err)
 	} else {
 		fmt.Printf("✅ Cleaned up test video\n")
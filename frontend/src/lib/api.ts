@@ -4,9 +4,21 @@ const api = axios.create({
   baseURL: import.meta.env.VITE_API_BASE_URL || "http://localhost:8000/api",
 });
 
+// Stable per-browser identifier, used by the backend to spot several
+// accounts voting from the same device.
+function deviceId(): string {
+  let id = localStorage.getItem("anb_device_id");
+  if (!id) {
+    id = crypto.randomUUID();
+    localStorage.setItem("anb_device_id", id);
+  }
+  return id;
+}
+
 api.interceptors.request.use((config) => {
   const tok = localStorage.getItem("anb_access_token");
   if (tok) config.headers.Authorization = `Bearer ${tok}`;
+  config.headers["X-Device-Fingerprint"] = deviceId();
   return config;
 });
 
@@ -0,0 +1,51 @@
+import api from "./api";
+import { getToken } from "./auth";
+
+const baseURL = import.meta.env.VITE_API_BASE_URL || "http://localhost:8000/api";
+
+// eslint-disable-next-line @typescript-eslint/no-explicit-any
+type Handlers = Record<string, (data: any) => void>;
+
+// Delay before opening a new stream once the server closed the previous one
+// (e.g. its ticket expired).
+const reconnectDelay = 3000;
+
+// Subscribes to the live updates stream. EventSource cannot send headers, so
+// logged-in users open it with a short-lived stream ticket instead of their
+// token. Returns a function that closes it.
+export function subscribe(handlers: Handlers): () => void {
+  let es: EventSource | null = null;
+  let closed = false;
+  let timer: ReturnType<typeof setTimeout> | undefined;
+
+  const open = async () => {
+    let url = `${baseURL}/events`;
+    if (getToken()) {
+      try {
+        const { data } = await api.post("/events/ticket");
+        url += `?ticket=${encodeURIComponent(data.ticket)}`;
+      } catch {
+        // Fall back to the anonymous stream
+      }
+    }
+    if (closed) return;
+    es = new EventSource(url);
+    for (const [type, fn] of Object.entries(handlers)) {
+      es.addEventListener(type, (e) => fn(JSON.parse((e as MessageEvent).data)));
+    }
+    es.onerror = () => {
+      // EventSource retries by itself with the same URL unless the server
+      // rejected it; then the ticket is stale and a new one is needed
+      if (es?.readyState === EventSource.CLOSED && !closed) {
+        timer = setTimeout(open, reconnectDelay);
+      }
+    };
+  };
+  open();
+
+  return () => {
+    closed = true;
+    clearTimeout(timer);
+    es?.close();
+  };
+}
@@ -1,37 +1,67 @@
 import { useEffect, useState } from "react";
 import { Link } from "react-router-dom";
 import api from "../lib/api";
+import { subscribe } from "../lib/events";
 
 type Item = {
-  ID: string;
-  Title: string;
-  Status: "uploaded" | "processed" | "processing" | "failed" | "published";
-  ProcessedURL?: string;
+  id: string;
+  title: string;
+  status: "uploaded" | "processed" | "processing" | "failed" | "published"
+    | "pending_review" | "rejected" | "reupload_requested" | "unpublished" | "archived";
+  processed_url?: string;
+  moderation_reason?: string;
 };
 
 export default function MyVideos() {
   const [items, setItems] = useState<Item[]>([]);
   useEffect(() => { api.get<Item[]>("/videos").then(r=>setItems(r.data)); }, []);
 
+  const act = (id: string, action: "retry" | "reprocess" | "withdraw" | "archive", body?: object) =>
+    api.post<Item>(`/videos/${id}/${action}`, body)
+      .then(r => setItems(list => list.map(v => v.id === id ? r.data : v)))
+      .catch(e => alert(e?.response?.data?.error ?? "Action failed"));
+
+  // Live status transitions (uploaded → processing → pending_review → published/rejected)
+  useEffect(() => subscribe({
+    "video.status": (ev: { video_id: string; status: Item["status"] }) => {
+      if (ev.status === "published" || ev.status === "pending_review" || ev.status === "rejected" || ev.status === "reupload_requested") {
+        api.get<Item[]>("/videos").then(r=>setItems(r.data));
+        return;
+      }
+      setItems(list => list.map(v => v.id === ev.video_id ? { ...v, status: ev.status } : v));
+    },
+  }), []);
+
   return (
     <div>
       <h1>My Videos</h1>
       <div className="list">
         {items.map(v => (
-          <div className="item" key={v.ID}>
+          <div className="item" key={v.id}>
             <div className="thumb">
               <div style={{background: '#f0f0f0', width: '100%', height: '100%', display: 'flex', alignItems: 'center', justifyContent: 'center', color: '#666', fontSize: '24px'}}>
                 🎥
               </div>
             </div>
             <div>
-              <div className="title">{v.Title}</div>
-              <div className="meta">{v.ProcessedURL ? "Ready to watch" : "Waiting / processing"}</div>
+              <div className="title">{v.title}</div>
+              <div className="meta">{v.processed_url ? "Ready to watch" : "Waiting / processing"}</div>
+              {v.moderation_reason && <div className="helper">Moderator: {v.moderation_reason}</div>}
             </div>
             <div style={{display:"grid", gap:8, justifyItems:"end"}}>
-              <span className={`badge ${v.Status}`}>{v.Status}</span>
+              <span className={`badge ${v.status}`}>{v.status}</span>
               <div style={{display:"flex", gap:8}}>
-                <Link to={`/videos/${v.ID}`} className="btn">Details</Link>
+                <Link to={`/videos/${v.id}`} className="btn">Details</Link>
+                {v.status === "failed" && <button className="btn" onClick={()=>act(v.id, "retry")}>Retry</button>}
+                {v.status === "published" && <button className="btn" onClick={()=>act(v.id, "withdraw")}>Withdraw</button>}
+                {["published", "unpublished", "pending_review", "reupload_requested"].includes(v.status) && (
+                  <button className="btn" onClick={()=>{
+                    const profile = prompt("Processing profile (720p, 1080p, 480p)", "720p");
+                    if (profile) act(v.id, "reprocess", { profile });
+                  }}>Reprocess</button>
+                )}
+                {v.status !== "archived" && v.status !== "processing" && v.status !== "processed"
+                  && <button className="btn" onClick={()=>act(v.id, "archive")}>Archive</button>}
               </div>
             </div>
           </div>
@@ -4,59 +4,105 @@ import { isLoggedIn } from "../lib/auth";
 import type { AxiosError } from "axios";
 
 type Pub = { 
-  ID: string; 
-  Title: string; 
-  ProcessedURL?: string; 
-  User: { FirstName: string; LastName: string; };
-  Votes?: Array<any>; 
+  id: string;
+  title: string;
+  processed_url?: string;
+  player: { first_name: string; last_name: string; city: string; };
+  vote_count: number;
+  voted_by_me?: boolean;
 };
 
+type Sort = "newest" | "votes" | "trending";
+
 export default function PublicVideos() {
   const [items, setItems] = useState<Pub[]>([]);
   const [msg, setMsg] = useState<string | null>(null);
+  const [q, setQ] = useState("");
+  const [sort, setSort] = useState<Sort>("newest");
+  const [next, setNext] = useState<string | null>(null);
 
-  useEffect(() => {
-    api.get<Pub[]>("/public/videos")
-      .then(r => setItems(r.data))
+  // Loads the first page, or appends the page after cursor
+  const load = (cursor?: string) =>
+    api.get<Pub[]>("/public/videos", { params: { q: q || undefined, sort, cursor } })
+      .then(r => {
+        setItems(list => cursor ? [...list, ...r.data] : r.data);
+        setNext(r.headers["x-next-cursor"] ?? null);
+      })
       .catch(() => setMsg("Could not load public videos"));
-  }, []);
+
+  useEffect(() => { load(); }, [sort]); // eslint-disable-line react-hooks/exhaustive-deps
 
   const vote = async (id: string) => {
     try {
       await api.post(`/public/videos/${id}/vote`);
       setMsg("Vote registered successfully.");
       // Reload the videos to get updated vote counts
-      const { data } = await api.get<Pub[]>("/public/videos");
-      setItems(data);
+      await load();
     } catch (err: unknown) {
       const ax = err as AxiosError<{message?:string}>;
       setMsg(ax.response?.data?.message ?? "Could not vote"); // si ya votó, backend devuelve 400 con mensaje
     }
   };
 
+  const unvote = async (id: string) => {
+    try {
+      await api.delete(`/public/videos/${id}/vote`);
+      setMsg("Vote removed.");
+      await load();
+    } catch (err: unknown) {
+      const ax = err as AxiosError<{error?:string}>;
+      setMsg(ax.response?.data?.error ?? "Could not remove vote");
+    }
+  };
+
+  const report = async (id: string) => {
+    const reason = window.prompt("Why is this video inappropriate?");
+    if (!reason) return;
+    try {
+      await api.post(`/public/videos/${id}/report`, { reason });
+      setMsg("Thanks, a moderator will review this video.");
+    } catch (err: unknown) {
+      const ax = err as AxiosError<{error?:string}>;
+      setMsg(ax.response?.data?.error ?? "Could not report video");
+    }
+  };
+
   return (
     <div>
       <h1>Explore</h1>
+      <form style={{display:"flex", gap:8, marginBottom:10}} onSubmit={e => { e.preventDefault(); load(); }}>
+        <input placeholder="Search title or player" value={q} onChange={e => setQ(e.target.value)} />
+        <select value={sort} onChange={e => setSort(e.target.value as Sort)}>
+          <option value="newest">Newest</option>
+          <option value="votes">Most voted</option>
+          <option value="trending">Trending</option>
+        </select>
+        <button className="btn" type="submit">Search</button>
+      </form>
       {msg && <div className="helper" style={{marginBottom:10}}>{msg}</div>}
       <div className="list">
         {items.map(v => (
-          <div className="item" key={v.ID}>
+          <div className="item" key={v.id}>
             <div className="thumb">
               <div style={{background: '#f0f0f0', width: '100%', height: '100%', display: 'flex', alignItems: 'center', justifyContent: 'center', color: '#666', fontSize: '24px'}}>
                 🎥
               </div>
             </div>
             <div>
-              <div className="title">{v.Title}</div>
-              <div className="meta">by {v.User.FirstName} {v.User.LastName} • {(v.Votes?.length ?? 0)} votes</div>
+              <div className="title">{v.title}</div>
+              <div className="meta">by {v.player.first_name} {v.player.last_name} • {v.vote_count} votes</div>
             </div>
             <div style={{display:"flex", gap:8}}>
-              {v.ProcessedURL && <a className="btn" href={v.ProcessedURL} target="_blank">Watch</a>}
-              {isLoggedIn() && <button className="btn btn-primary" onClick={() => vote(v.ID)}>Vote</button>}
+              {v.processed_url && <a className="btn" href={v.processed_url} target="_blank">Watch</a>}
+              {isLoggedIn() && (v.voted_by_me
+                ? <button className="btn" onClick={() => unvote(v.id)}>Unvote</button>
+                : <button className="btn btn-primary" onClick={() => vote(v.id)}>Vote</button>)}
+              {isLoggedIn() && <button className="btn" onClick={() => report(v.id)}>Report</button>}
             </div>
           </div>
         ))}
         {items.length===0 && <div className="helper">No public videos yet.</div>}
+        {next && <button className="btn" onClick={() => load(next)}>Load more</button>}
       </div>
     </div>
   );
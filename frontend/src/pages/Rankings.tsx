@@ -1,22 +1,36 @@
 import { useEffect, useState } from "react";
 import api from "../lib/api";
+import { subscribe } from "../lib/events";
 
-type Row = { position?: number; username?: string; city?: string; votes: number };
+type Row = {
+  position: number;
+  user_id: string;
+  username: string;
+  city: string;
+  country: string;
+  video_ids: string[];
+  votes: number;
+};
+
+const PAGE_SIZE = 50;
 
 export default function Rankings() {
   const [rows, setRows] = useState<Row[]>([]);
   const [cities, setCities] = useState<string[]>([]);
   const [city, setCity] = useState<string>("");
   const [page, setPage] = useState(1);
-  const [totalPages, setTotalPages] = useState(1);
+  // cursors[i] loads page i+1; the first page has no cursor
+  const [cursors, setCursors] = useState<(string | undefined)[]>([undefined]);
 
   const load = async () => {
-    const { data } = await api.get<Row[]>("/public/rankings", {
-      params: { city: city || undefined, limit: 50 }
+    const res = await api.get<Row[]>("/public/rankings", {
+      params: { city: city || undefined, limit: PAGE_SIZE, cursor: cursors[page - 1] }
     });
-    setRows(data);
-    setTotalPages(1); // No pagination needed since we get all results
+    setRows(res.data);
+    const next = res.headers["x-next-cursor"] as string | undefined;
+    setCursors(cs => [...cs.slice(0, page), next]);
   };
+  const hasNext = cursors[page] !== undefined;
 
   useEffect(() => { 
     load(); 
@@ -26,12 +40,23 @@ export default function Rankings() {
       .catch(() => {}); // Ignore errors, fallback to hardcoded cities
   }, [city, page]);
 
+  // Reload on live vote updates, at most once per second
+  useEffect(() => {
+    let timer: number | undefined;
+    const close = subscribe({
+      "ranking.updated": () => {
+        if (timer === undefined) timer = window.setTimeout(() => { timer = undefined; load(); }, 1000);
+      },
+    });
+    return () => { window.clearTimeout(timer); close(); };
+  }, [city, page]);
+
   return (
     <div>
       <h1>Leaderboard</h1>
 
       <div style={{display:"flex", gap:12, marginBottom:10}}>
-        <select className="select" value={city} onChange={e=>{setPage(1); setCity(e.target.value);}}>
+        <select className="select" value={city} onChange={e=>{setPage(1); setCursors([undefined]); setCity(e.target.value);}}>
           <option value="">All cities</option>
           {cities.map(c => <option key={c} value={c}>{c}</option>)}
         </select>
@@ -42,10 +67,10 @@ export default function Rankings() {
           <tr><th style={{width:80}}>Rank</th><th>User</th><th style={{width:160}}>Votes</th></tr>
         </thead>
         <tbody>
-          {rows.map((r, i) => (
-            <tr key={i}>
-              <td>#{r.position ?? (i + 1 + (page-1)*10)}</td>
-              <td>{r.username ?? "User"}{r.city ? ` (${r.city})` : ""}</td>
+          {rows.map(r => (
+            <tr key={r.user_id}>
+              <td>#{r.position}</td>
+              <td>{r.username}{r.city ? ` (${r.city})` : ""}</td>
               <td>{r.votes}</td>
             </tr>
           ))}
@@ -57,8 +82,8 @@ export default function Rankings() {
 
       <div className="pager">
         <button className="btn" onClick={()=>setPage(p=>Math.max(1,p-1))} disabled={page<=1}>‹</button>
-        <span className="helper">Page {page} / {totalPages}</span>
-        <button className="btn" onClick={()=>setPage(p=>Math.min(totalPages,p+1))} disabled={page>=totalPages}>›</button>
+        <span className="helper">Page {page}</span>
+        <button className="btn" onClick={()=>setPage(p=>p+1)} disabled={!hasNext}>›</button>
       </div>
     </div>
   );
@@ -1,13 +1,27 @@
-import { useState } from "react";
+import { useEffect, useState } from "react";
 import api from "../lib/api";
+import type { AxiosError } from "axios";
 
 const MAX_MB = 100;
 
+type Tournament = { ID: string; Name: string; RegistrationOpensAt: string; RegistrationClosesAt: string };
+
 export default function Upload() {
   const [title, setTitle] = useState("");
   const [file, setFile] = useState<File | null>(null);
   const [msg, setMsg] = useState<string | null>(null);
   const [loading, setLoading] = useState(false);
+  const [tournaments, setTournaments] = useState<Tournament[]>([]);
+  const [tournament, setTournament] = useState("");
+
+  useEffect(() => {
+    api.get<Tournament[]>("/public/tournaments")
+      .then(r => {
+        const now = new Date();
+        setTournaments(r.data.filter(t => new Date(t.RegistrationOpensAt) <= now && now < new Date(t.RegistrationClosesAt)));
+      })
+      .catch(() => setTournaments([]));
+  }, []);
 
   const onPick = (f: File | null) => {
     if (!f) return setFile(null);
@@ -21,6 +35,7 @@ export default function Upload() {
     const fd = new FormData();
     fd.append("title", title);
     fd.append("video_file", file);
+    if (tournament) fd.append("tournament_id", tournament);
     try {
       setLoading(true);
       const { data } = await api.post<{message?:string}>("/videos/upload", fd, {
@@ -28,7 +43,10 @@ export default function Upload() {
       });
       setMsg(data?.message ?? "Uploaded. Processing in progress.");
       setTitle(""); setFile(null);
-    } catch { setMsg("Upload error"); }
+    } catch (err: unknown) {
+      const ax = err as AxiosError<{error?:string}>;
+      setMsg(ax.response?.data?.error ?? "Upload error");
+    }
     finally { setLoading(false); }
   };
 
@@ -41,6 +59,15 @@ export default function Upload() {
           <input className="input" value={title}
             onChange={e=>setTitle(e.target.value)} required />
         </div>
+        {tournaments.length > 0 && (
+          <div className="field">
+            <label>Tournament</label>
+            <select className="input" value={tournament} onChange={e=>setTournament(e.target.value)}>
+              <option value="">None</option>
+              {tournaments.map(t => <option key={t.ID} value={t.ID}>{t.Name}</option>)}
+            </select>
+          </div>
+        )}
         <div className="field">
           <label>MP4 file (≤ 100 MB)</label>
           <input type="file" className="input" accept="video/mp4"
@@ -4,9 +4,9 @@ import api from "../lib/api";
 import type { AxiosError } from "axios";
 
 type Detail = {
-  ID: string; Title: string; Status: "uploaded"|"processed"|"processing"|"failed"|"published";
-  OriginalURL?: string; ProcessedURL?: string;
-  is_public?: boolean;
+  id: string; title: string; status: string;
+  processed_url?: string; published_at?: string;
+  status_history: Array<{ from?: string; to: string; reason?: string; at: string }>;
 };
 
 export default function VideoDetail() {
@@ -36,19 +36,19 @@ export default function VideoDetail() {
 
   return (
     <div>
-      <h1>{d.Title}</h1>
+      <h1>{d.title}</h1>
       <div className="card" style={{padding:16}}>
-        {d.ProcessedURL ? (
-          <video src={d.ProcessedURL} controls style={{width:"100%", borderRadius:"12px"}} />
+        {d.processed_url ? (
+          <video src={d.processed_url} controls style={{width:"100%", borderRadius:"12px"}} />
         ) : (
           <div className="thumb" style={{width:"100%", height:320, borderRadius:"12px", background:"#f0f0f0", display:"flex", alignItems:"center", justifyContent:"center", color:"#666", fontSize:"48px"}}>
-            {d.Status === "processing" ? "⏳" : "🎥"}
+            {d.status === "processing" ? "⏳" : "🎥"}
           </div>
         )}
         <div style={{display:"flex", gap:10, marginTop:12, flexWrap:"wrap"}}>
-          <span className={`badge ${d.Status}`}>{d.Status}</span>
-          {/* Borra solo si no está publicado para votación */}
-          {d.is_public !== true && (
+          <span className={`badge ${d.status}`}>{d.status}</span>
+          {/* Borra solo si nunca se publicó para votación */}
+          {!d.published_at && (
             <button
               className="btn btn-danger"
               onClick={remove}
@@ -58,6 +58,11 @@ export default function VideoDetail() {
           )}
         </div>
         {msg && <div className="error" style={{marginTop:8}}>{msg}</div>}
+        <ul className="helper" style={{marginTop:12}}>
+          {d.status_history.map((e, i) => (
+            <li key={i}>{new Date(e.at).toLocaleString()}: {e.from ? `${e.from} → ` : ""}{e.to}{e.reason ? ` (${e.reason})` : ""}</li>
+          ))}
+        </ul>
       </div>
     </div>
   );